@@ -2,11 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"host-vault/internal/audit"
+	"host-vault/internal/database"
+	"host-vault/internal/guestkey"
+	"host-vault/internal/keychain"
+	"host-vault/internal/sshkeys"
+	"host-vault/internal/storage"
+	"host-vault/internal/sysevents"
 	"host-vault/internal/terminal"
+	"host-vault/internal/vault"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -15,6 +28,32 @@ import (
 type App struct {
 	ctx             context.Context
 	terminalManager *terminal.TerminalManager
+	vault           *vault.Vault
+	keychain        keychain.Keychain
+	guestKey        *guestkey.Store
+	stores          *storage.Scope
+	auditLog        *audit.Log
+	db              *database.DB
+}
+
+const (
+	// vaultMasterKeyName is the keychain entry caching the vault master key
+	vaultMasterKeyName = "vault-master-key"
+	// frontendKeyPrefix keeps keys set through SaveToKeychain apart from
+	// the app's own entries, so the frontend can't read the master key
+	frontendKeyPrefix = "app:"
+	// maxImportSize bounds files read through ImportFileWithDialog
+	maxImportSize = 10 << 20
+	// maxAuditDetailSize bounds the detail the frontend attaches to an
+	// audit event
+	maxAuditDetailSize = 512
+)
+
+// frontendAuditEvents are the audit events only the frontend sees happen
+var frontendAuditEvents = map[audit.Event]bool{
+	audit.EventCredentialViewed:   true,
+	audit.EventCredentialExported: true,
+	audit.EventBackupRestored:     true,
 }
 
 // NewApp creates a new App application struct
@@ -27,190 +66,224 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.terminalManager = terminal.NewTerminalManager(ctx)
-}
 
-// Greet returns a greeting for the given name
-func (a *App) Greet(name string) string {
-	return fmt.Sprintf("Hello %s, It's show time!", name)
-}
+	// Every other subsystem keeps its files in the app data directory
+	appPath, err := a.GetAppDataPath()
+	if err != nil {
+		log.Printf("[APP] App data directory unavailable, running without keychain, audit log, stored data or vault: %v", err)
+		return
+	}
 
-// SaveToKeychain saves a value to Windows Credential Manager
-// This is a placeholder - actual implementation will use golang.org/x/sys/windows
-func (a *App) SaveToKeychain(key string, value string) error {
-	// TODO: Implement Windows Credential Manager integration
-	// For now, return an error indicating it's not implemented
-	return errors.New("Windows Credential Manager integration not yet implemented")
-}
+	// Backends are probed at runtime; the file fallback lives next to the app data
+	if a.keychain, err = keychain.Open(appPath); err != nil {
+		log.Printf("[KEYCHAIN] Keychain unavailable: %v", err)
+	}
 
-// GetFromKeychain retrieves a value from Windows Credential Manager
-func (a *App) GetFromKeychain(key string) (string, error) {
-	// TODO: Implement Windows Credential Manager integration
-	return "", errors.New("Windows Credential Manager integration not yet implemented")
-}
+	if a.auditLog, err = audit.Open(filepath.Join(appPath, "audit.jsonl")); err != nil {
+		log.Printf("[AUDIT] Audit log unavailable: %v", err)
+	}
+	a.terminalManager.SetAuditLog(a.auditLog)
 
-// DeleteFromKeychain removes a value from Windows Credential Manager
-func (a *App) DeleteFromKeychain(key string) error {
-	// TODO: Implement Windows Credential Manager integration
-	return errors.New("Windows Credential Manager integration not yet implemented")
-}
+	a.stores = storage.NewScope(appPath)
 
-// GetAppDataPath returns the application data directory path
-// On Windows: %APPDATA%\host-vault
-func (a *App) GetAppDataPath() (string, error) {
-	appDataDir := os.Getenv("APPDATA")
-	if appDataDir == "" {
-		return "", errors.New("APPDATA environment variable not set")
+	// Files and database are only migrated with a backup to fall back on
+	if backupPath, err := a.GetBackupPath(); err != nil {
+		log.Printf("[APP] Backup directory unavailable, not migrating store files or opening the database: %v", err)
+	} else {
+		a.openStoredData(appPath, backupPath)
 	}
 
-	appPath := filepath.Join(appDataDir, "host-vault")
-
-	// Create directory if it doesn't exist
-	if err := os.MkdirAll(appPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create app data directory: %w", err)
+	var guestFiles []string
+	for _, store := range []storage.Store{storage.StoreConnections, storage.StoreCommands} {
+		path, err := a.stores.Path(store, "", "")
+		if err != nil {
+			log.Printf("[GUEST] Guest %s not re-encryptable: %v", store, err)
+			continue
+		}
+		guestFiles = append(guestFiles, path)
+	}
+	if a.guestKey, err = guestkey.Open(a.keychain, appPath, guestFiles); err != nil {
+		log.Printf("[GUEST] Guest key unavailable: %v", err)
 	}
 
-	return appPath, nil
+	a.openVault(ctx, appPath)
 }
 
-// GetDatabasePath returns the path to the SQLite database file
-func (a *App) GetDatabasePath() (string, error) {
-	appPath, err := a.GetAppDataPath()
+// openVault opens the vault and, whenever there is one, starts locking it
+// when idle, on suspend and on screen lock
+func (a *App) openVault(ctx context.Context, appPath string) {
+	v, err := vault.Open(filepath.Join(appPath, "vault.json"))
 	if err != nil {
-		return "", err
+		log.Printf("[VAULT] Vault unavailable: %v", err)
+		return
 	}
+	a.vault = v
+	a.terminalManager.SetConnectionResolver(a.resolveVaultConnection)
 
-	dbPath := filepath.Join(appPath, "db", "main.db")
-
-	// Create db directory if it doesn't exist
-	dbDir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dbDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create db directory: %w", err)
+	a.vault.SetLockHandler(a.onVaultLocked)
+	go a.vault.RunAutoLock(ctx)
+	if err := sysevents.Watch(ctx, a.onSystemEvent); err != nil {
+		log.Printf("[SYS] Not locking the vault on suspend or screen lock: %v", err)
 	}
-
-	return dbPath, nil
 }
 
-// GetBackupPath returns the path to the backups directory
-func (a *App) GetBackupPath() (string, error) {
-	appPath, err := a.GetAppDataPath()
-	if err != nil {
-		return "", err
+// openStoredData migrates the store files and opens the database, backing
+// both up into backupPath before changing their format
+func (a *App) openStoredData(appPath, backupPath string) {
+	if result, err := a.stores.MigrateFiles(backupPath); err != nil {
+		log.Printf("[APP] Store files not migrated: %v", err)
+	} else if result.Failed > 0 {
+		log.Printf("[APP] %d store files failed to migrate and were left as they were", result.Failed)
 	}
 
-	backupPath := filepath.Join(appPath, "backups")
-
-	// Create backups directory if it doesn't exist
-	if err := os.MkdirAll(backupPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create backups directory: %w", err)
+	if dbPath, err := a.GetDatabasePath(); err != nil {
+		log.Printf("[DB] Database unavailable: %v", err)
+	} else if a.db, err = database.Open(dbPath, backupPath); err != nil {
+		log.Printf("[DB] Database unavailable: %v", err)
+	} else if result, err := a.db.ImportJSON(appPath); err != nil {
+		log.Printf("[DB] %v", err)
+	} else if result.Connections+result.Commands > 0 {
+		log.Printf("[DB] Imported %d connections and %d commands from %d profiles", result.Connections, result.Commands, result.Profiles)
 	}
-
-	return backupPath, nil
 }
 
-// GetGuestConfigPath returns the path to the guest config file
-// Path: %APPDATA%\host-vault\guest\config.json
-func (a *App) GetGuestConfigPath() (string, error) {
-	appPath, err := a.GetAppDataPath()
-	if err != nil {
-		return "", err
+// onVaultLocked applies the session lock action and emits vault:locked
+func (a *App) onVaultLocked(reason vault.LockReason) {
+	event := vault.LockedEvent{
+		Reason:        reason,
+		SessionAction: a.vault.AutoLockSettings().SessionAction,
+		SessionIDs:    []string{},
 	}
+	switch event.SessionAction {
+	case vault.SessionLockFreeze:
+		event.SessionIDs = a.terminalManager.FreezeConnectionSessions()
+	case vault.SessionLockDisconnect:
+		event.SessionIDs = a.terminalManager.CloseConnectionSessions()
+	}
+	runtime.EventsEmit(a.ctx, "vault:locked", event)
+}
 
-	guestPath := filepath.Join(appPath, "guest")
-
-	// Create guest directory if it doesn't exist
-	if err := os.MkdirAll(guestPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create guest directory: %w", err)
+// onSystemEvent locks the vault on suspend or screen lock, as configured
+func (a *App) onSystemEvent(event sysevents.Event) {
+	switch event {
+	case sysevents.Suspend:
+		a.vault.LockFor(vault.LockReasonSuspend)
+	case sysevents.ScreenLock:
+		a.vault.LockFor(vault.LockReasonScreenLock)
 	}
+}
 
-	configPath := filepath.Join(guestPath, "config.json")
-	return configPath, nil
+// vaultUnlocked lets input through to sessions frozen by the last lock
+func (a *App) vaultUnlocked() {
+	if ids := a.terminalManager.ThawSessions(); len(ids) > 0 {
+		runtime.EventsEmit(a.ctx, "vault:unlocked", ids)
+	}
 }
 
-// GetUserConfigPath returns the path to a user's config file
-// Path: %APPDATA%\host-vault\users\{userId}\config.json
-func (a *App) GetUserConfigPath(userId string) (string, error) {
-	appPath, err := a.GetAppDataPath()
+// resolveVaultConnection gives the terminal layer a saved connection with
+// its credentials, straight from the vault
+func (a *App) resolveVaultConnection(connectionID string) (terminal.ConnectionConfig, error) {
+	conn, cred, err := a.vault.ResolveConnection(connectionID)
 	if err != nil {
-		return "", err
+		return terminal.ConnectionConfig{}, err
 	}
+	return terminal.ConnectionConfig{
+		Host:          conn.Host,
+		Port:          conn.Port,
+		Username:      conn.Username,
+		Password:      cred.Password,
+		PrivateKey:    cred.PrivateKey,
+		Passphrase:    cred.Passphrase,
+		HostKeyPolicy: terminal.HostKeyPolicy(conn.HostKeyPolicy),
+	}, nil
+}
 
-	userPath := filepath.Join(appPath, "users", userId)
+// Greet returns a greeting for the given name
+func (a *App) Greet(name string) string {
+	return fmt.Sprintf("Hello %s, It's show time!", name)
+}
 
-	// Create user directory if it doesn't exist
-	if err := os.MkdirAll(userPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create user directory: %w", err)
+// SaveToKeychain saves a value to the OS keychain (Secret Service on Linux,
+// Credential Manager on Windows) or the encrypted file fallback
+func (a *App) SaveToKeychain(key string, value string) error {
+	if a.keychain == nil {
+		return errors.New("keychain not initialized")
 	}
-
-	configPath := filepath.Join(userPath, "config.json")
-	return configPath, nil
+	return a.keychain.Set(frontendKeyPrefix+key, value)
 }
 
-// GetGuestSnippetsPath returns the path to the guest snippets file
-// Path: %APPDATA%\host-vault\guest\commands.json
-func (a *App) GetGuestSnippetsPath() (string, error) {
-	appPath, err := a.GetAppDataPath()
-	if err != nil {
-		return "", err
+// GetFromKeychain retrieves a value saved with SaveToKeychain
+func (a *App) GetFromKeychain(key string) (string, error) {
+	if a.keychain == nil {
+		return "", errors.New("keychain not initialized")
 	}
+	return a.keychain.Get(frontendKeyPrefix + key)
+}
 
-	guestPath := filepath.Join(appPath, "guest")
-
-	if err := os.MkdirAll(guestPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create guest directory: %w", err)
+// DeleteFromKeychain removes a value saved with SaveToKeychain
+func (a *App) DeleteFromKeychain(key string) error {
+	if a.keychain == nil {
+		return errors.New("keychain not initialized")
 	}
+	return a.keychain.Delete(frontendKeyPrefix + key)
+}
 
-	return filepath.Join(guestPath, "commands.json"), nil
+// GetKeychainBackend names the keychain backend in use: secret-service,
+// credential-manager or file
+func (a *App) GetKeychainBackend() (string, error) {
+	if a.keychain == nil {
+		return "", errors.New("keychain not initialized")
+	}
+	return a.keychain.Name(), nil
 }
 
-// GetUserSnippetsPath returns the path to a user's snippets file
-// Path: %APPDATA%\host-vault\users\{userId}\commands.json
-func (a *App) GetUserSnippetsPath(userId string) (string, error) {
-	appPath, err := a.GetAppDataPath()
+// GetAppDataPath returns the application data directory path
+// On Windows: %APPDATA%\host-vault; elsewhere under the user config directory
+func (a *App) GetAppDataPath() (string, error) {
+	appPath, err := storage.AppDataDir()
 	if err != nil {
 		return "", err
 	}
 
-	userPath := filepath.Join(appPath, "users", userId)
-
-	if err := os.MkdirAll(userPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create user directory: %w", err)
+	// Create directory if it doesn't exist
+	if err := storage.EnsureDir(appPath); err != nil {
+		return "", fmt.Errorf("failed to create app data directory: %w", err)
 	}
 
-	return filepath.Join(userPath, "commands.json"), nil
+	return appPath, nil
 }
 
-// GetGuestConnectionsPath returns the path to the guest connections file
-// Path: %APPDATA%\host-vault\guest\connections.json
-func (a *App) GetGuestConnectionsPath() (string, error) {
+// GetDatabasePath returns the path to the SQLite database file
+func (a *App) GetDatabasePath() (string, error) {
 	appPath, err := a.GetAppDataPath()
 	if err != nil {
 		return "", err
 	}
 
-	guestPath := filepath.Join(appPath, "guest")
+	dbPath := filepath.Join(appPath, "db", "main.db")
 
-	if err := os.MkdirAll(guestPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create guest directory: %w", err)
+	// Create db directory if it doesn't exist
+	dbDir := filepath.Dir(dbPath)
+	if err := storage.EnsureDir(dbDir); err != nil {
+		return "", fmt.Errorf("failed to create db directory: %w", err)
 	}
 
-	return filepath.Join(guestPath, "connections.json"), nil
+	return dbPath, nil
 }
 
-// GetUserConnectionsPath returns the path to a user's connections file
-// Path: %APPDATA%\host-vault\users\{userId}\connections.json
-func (a *App) GetUserConnectionsPath(userId string) (string, error) {
+// GetBackupPath returns the directory database backups are written to
+func (a *App) GetBackupPath() (string, error) {
 	appPath, err := a.GetAppDataPath()
 	if err != nil {
 		return "", err
 	}
 
-	userPath := filepath.Join(appPath, "users", userId)
-
-	if err := os.MkdirAll(userPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to create user directory: %w", err)
+	backupPath := filepath.Join(appPath, "backups")
+	if err := storage.EnsureDir(backupPath); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	return filepath.Join(userPath, "connections.json"), nil
+	return backupPath, nil
 }
 
 // ShowMessageDialog shows a native Windows message dialog
@@ -222,110 +295,413 @@ func (a *App) ShowMessageDialog(title string, message string, dialogType string)
 	return "", errors.New("Native message dialog not yet implemented")
 }
 
-// ShowOpenFileDialog shows a native Windows file open dialog
-func (a *App) ShowOpenFileDialog(title string, fileType string) (string, error) {
-	var filters []runtime.FileFilter
-
+// dialogFilters returns the file filters for a dialog's fileType
+func dialogFilters(fileType string) []runtime.FileFilter {
 	switch fileType {
 	case "json":
-		filters = []runtime.FileFilter{
+		return []runtime.FileFilter{
 			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
 			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
 		}
 	case "ssh-key":
-		filters = []runtime.FileFilter{
-			{DisplayName: "SSH Keys", Pattern: "id_rsa;id_dsa;id_ecdsa;id_ed25519;*.pem;*.key;*.pub"},
+		return []runtime.FileFilter{
+			{DisplayName: "SSH Keys", Pattern: "id_rsa;id_dsa;id_ecdsa;id_ed25519;*.pem;*.key;*.ppk;*.pub"},
 			{DisplayName: "Private Keys", Pattern: "id_rsa;id_dsa;id_ecdsa;id_ed25519"},
 			{DisplayName: "PEM Files (*.pem)", Pattern: "*.pem"},
 			{DisplayName: "Key Files (*.key)", Pattern: "*.key"},
+			{DisplayName: "PuTTY Keys (*.ppk)", Pattern: "*.ppk"},
 			{DisplayName: "Public Keys (*.pub)", Pattern: "*.pub"},
 			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
 		}
 	case "ssh-cert":
-		filters = []runtime.FileFilter{
+		return []runtime.FileFilter{
 			{DisplayName: "SSH Certificates", Pattern: "*.pub;*-cert.pub;*.crt;*.cer;*.pem"},
 			{DisplayName: "Certificate Files (*.crt, *.cer)", Pattern: "*.crt;*.cer"},
 			{DisplayName: "PEM Files (*.pem)", Pattern: "*.pem"},
 			{DisplayName: "Public Key Files (*.pub)", Pattern: "*.pub"},
 			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
 		}
+	case "known-hosts":
+		return []runtime.FileFilter{
+			{DisplayName: "Known Hosts", Pattern: "known_hosts*"},
+			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
+		}
+	case "jsonl":
+		return []runtime.FileFilter{
+			{DisplayName: "JSON Lines (*.jsonl)", Pattern: "*.jsonl"},
+			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
+		}
 	default:
-		filters = []runtime.FileFilter{
+		return []runtime.FileFilter{
 			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
 		}
 	}
+}
 
+// showOpenDialog asks the user for a file to read; "" means cancelled
+func (a *App) showOpenDialog(title, fileType string) (string, error) {
 	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
 		Title:   title,
-		Filters: filters,
+		Filters: dialogFilters(fileType),
 	})
 }
 
-// ShowSaveFileDialog shows a native Windows file save dialog
-func (a *App) ShowSaveFileDialog(title string, defaultFilename string, fileType string) (string, error) {
+// showSaveDialog asks the user for a file to write; "" means cancelled
+func (a *App) showSaveDialog(title, defaultFilename, fileType string) (string, error) {
 	return runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
 		Title:           title,
 		DefaultFilename: defaultFilename,
-		Filters: []runtime.FileFilter{
-			{DisplayName: "JSON Files (*.json)", Pattern: "*.json"},
-			{DisplayName: "All Files (*.*)", Pattern: "*.*"},
-		},
+		Filters:         dialogFilters(fileType),
 	})
 }
 
-// FileExists checks if a file exists
-func (a *App) FileExists(filePath string) bool {
-	_, err := os.Stat(filePath)
-	return !os.IsNotExist(err)
-}
+// ImportFileWithDialog lets the user pick a file and returns its contents.
+// This and ExportFileWithDialog are the only ways the frontend can reach
+// files outside the app's stores. Cancelling gives "".
+func (a *App) ImportFileWithDialog(title string, fileType string) (string, error) {
+	path, err := a.showOpenDialog(title, fileType)
+	if err != nil || path == "" {
+		return "", err
+	}
 
-// ReadFile reads the contents of a file
-func (a *App) ReadFile(filePath string) (string, error) {
-	data, err := os.ReadFile(filePath)
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportSize+1))
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	if len(data) > maxImportSize {
+		return "", fmt.Errorf("file is larger than %d MiB", maxImportSize>>20)
+	}
 	return string(data), nil
 }
 
-// WriteFile writes data to a file
-func (a *App) WriteFile(filePath string, data string) error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// ExportFileWithDialog writes data to a file the user picks, readable by
+// the owner only. Returns false if the user cancelled.
+func (a *App) ExportFileWithDialog(title string, defaultFilename string, fileType string, data string) (bool, error) {
+	path, err := a.showSaveDialog(title, defaultFilename, fileType)
+	if err != nil || path == "" {
+		return false, err
+	}
+	if err := storage.WriteExport(path, []byte(data)); err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
 	}
+	return true, nil
+}
 
-	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+// storePath resolves a file in one of the app's stores. profile is a user
+// ID, or "" for guest mode; name picks a file in the workspaces and
+// backups stores and is empty for the others.
+func (a *App) storePath(store, profile, name string) (string, error) {
+	if a.stores == nil {
+		return "", errors.New("storage not initialized")
 	}
+	return a.stores.Path(storage.Store(store), profile, name)
+}
+
+// ReadStoreFile reads a file from a store; a missing file reads as empty
+func (a *App) ReadStoreFile(store string, profile string, name string) (string, error) {
+	path, err := a.storePath(store, profile, name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(data), nil
+}
 
+// WriteStoreFile writes a file in a store atomically, readable by the owner only
+func (a *App) WriteStoreFile(store string, profile string, name string, data string) error {
+	path, err := a.storePath(store, profile, name)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFile(path, []byte(data)); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
 	return nil
 }
 
-// DeleteFile deletes a file
-func (a *App) DeleteFile(filePath string) error {
-	if err := os.Remove(filePath); err != nil {
+// GetStoreFileVersion returns a token identifying a store file's current
+// contents, empty if it doesn't exist. Pass it to WriteStoreFileIfUnchanged
+// to detect writes made meanwhile by another instance.
+func (a *App) GetStoreFileVersion(store string, profile string, name string) (string, error) {
+	path, err := a.storePath(store, profile, name)
+	if err != nil {
+		return "", err
+	}
+	version, err := storage.Version(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+	return version, nil
+}
+
+// WriteStoreFileIfUnchanged writes data only if the file is still at
+// version and returns the new version. If another writer got there first it
+// fails with "file changed on disk"; reload and retry.
+func (a *App) WriteStoreFileIfUnchanged(store string, profile string, name string, data string, version string) (string, error) {
+	path, err := a.storePath(store, profile, name)
+	if err != nil {
+		return "", err
+	}
+	newVersion, err := storage.WriteFileIfUnchanged(path, []byte(data), version)
+	if errors.Is(err, storage.ErrFileChanged) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	return newVersion, nil
+}
+
+// DeleteStoreFile deletes a file from a store; a missing file is not an error
+func (a *App) DeleteStoreFile(store string, profile string, name string) error {
+	path, err := a.storePath(store, profile, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 	return nil
 }
 
-// ListFiles lists files in a directory
-func (a *App) ListFiles(dirPath string) ([]string, error) {
-	entries, err := os.ReadDir(dirPath)
+// ListStoreFiles lists the files in the workspaces or backups store
+func (a *App) ListStoreFiles(store string, profile string) ([]string, error) {
+	if a.stores == nil {
+		return nil, errors.New("storage not initialized")
+	}
+	return a.stores.List(storage.Store(store), profile)
+}
+
+// ListDatabaseConnections returns a profile's connections from the
+// database. profile is a user ID, or "" for guest mode, as for the stores.
+func (a *App) ListDatabaseConnections(profile string) ([]database.Connection, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.ListConnections(profile)
+}
+
+// GetDatabaseConnection returns one of a profile's connections
+func (a *App) GetDatabaseConnection(profile string, id string) (database.Connection, error) {
+	if a.db == nil {
+		return database.Connection{}, errors.New("database not initialized")
+	}
+	return a.db.GetConnection(profile, id)
+}
+
+// CreateDatabaseConnection adds a connection to a profile
+func (a *App) CreateDatabaseConnection(profile string, conn database.Connection) (database.Connection, error) {
+	if a.db == nil {
+		return database.Connection{}, errors.New("database not initialized")
+	}
+	conn.UserID = profile
+	return a.db.CreateConnection(conn)
+}
+
+// UpdateDatabaseConnection saves a connection. Its version must be the one
+// read, so changes made meanwhile elsewhere aren't overwritten.
+func (a *App) UpdateDatabaseConnection(profile string, conn database.Connection) (database.Connection, error) {
+	if a.db == nil {
+		return database.Connection{}, errors.New("database not initialized")
+	}
+	conn.UserID = profile
+	return a.db.UpdateConnection(conn)
+}
+
+// DeleteDatabaseConnection removes a connection with its scoped commands
+func (a *App) DeleteDatabaseConnection(profile string, id string) error {
+	if a.db == nil {
+		return errors.New("database not initialized")
+	}
+	return a.db.DeleteConnection(profile, id)
+}
+
+// ListDatabaseCommands returns a profile's commands
+func (a *App) ListDatabaseCommands(profile string) ([]database.Command, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.ListCommands(profile)
+}
+
+// GetDatabaseCommand returns one of a profile's commands
+func (a *App) GetDatabaseCommand(profile string, id string) (database.Command, error) {
+	if a.db == nil {
+		return database.Command{}, errors.New("database not initialized")
+	}
+	return a.db.GetCommand(profile, id)
+}
+
+// CreateDatabaseCommand adds a command to a profile
+func (a *App) CreateDatabaseCommand(profile string, command database.Command) (database.Command, error) {
+	if a.db == nil {
+		return database.Command{}, errors.New("database not initialized")
+	}
+	command.UserID = profile
+	return a.db.CreateCommand(command)
+}
+
+// UpdateDatabaseCommand saves a command, checking its version like
+// UpdateDatabaseConnection
+func (a *App) UpdateDatabaseCommand(profile string, command database.Command) (database.Command, error) {
+	if a.db == nil {
+		return database.Command{}, errors.New("database not initialized")
+	}
+	command.UserID = profile
+	return a.db.UpdateCommand(command)
+}
+
+// DeleteDatabaseCommand removes a command
+func (a *App) DeleteDatabaseCommand(profile string, id string) error {
+	if a.db == nil {
+		return errors.New("database not initialized")
+	}
+	return a.db.DeleteCommand(profile, id)
+}
+
+// ListDatabaseKeychains returns a profile's keychains
+func (a *App) ListDatabaseKeychains(profile string) ([]database.Keychain, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.ListKeychains(profile)
+}
+
+// GetDatabaseKeychain returns one of a profile's keychains
+func (a *App) GetDatabaseKeychain(profile string, id string) (database.Keychain, error) {
+	if a.db == nil {
+		return database.Keychain{}, errors.New("database not initialized")
+	}
+	return a.db.GetKeychain(profile, id)
+}
+
+// CreateDatabaseKeychain adds a keychain to a profile
+func (a *App) CreateDatabaseKeychain(profile string, keychain database.Keychain) (database.Keychain, error) {
+	if a.db == nil {
+		return database.Keychain{}, errors.New("database not initialized")
+	}
+	keychain.UserID = profile
+	return a.db.CreateKeychain(keychain)
+}
+
+// UpdateDatabaseKeychain saves a keychain, checking its version like
+// UpdateDatabaseConnection
+func (a *App) UpdateDatabaseKeychain(profile string, keychain database.Keychain) (database.Keychain, error) {
+	if a.db == nil {
+		return database.Keychain{}, errors.New("database not initialized")
+	}
+	keychain.UserID = profile
+	return a.db.UpdateKeychain(keychain)
+}
+
+// DeleteDatabaseKeychain removes a keychain
+func (a *App) DeleteDatabaseKeychain(profile string, id string) error {
+	if a.db == nil {
+		return errors.New("database not initialized")
+	}
+	return a.db.DeleteKeychain(profile, id)
+}
+
+// ValidateDatabase checks the database's integrity and foreign keys and
+// returns the problems found
+func (a *App) ValidateDatabase() ([]string, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.Validate()
+}
+
+// RepairDatabase rebuilds indexes, drops dangling references and compacts
+// the database, returning the problems that remain
+func (a *App) RepairDatabase() ([]string, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.Repair()
+}
+
+// ListDatabaseMigrations returns the schema migrations applied to the
+// database, oldest first
+func (a *App) ListDatabaseMigrations() ([]database.AppliedMigration, error) {
+	if a.db == nil {
+		return nil, errors.New("database not initialized")
+	}
+	return a.db.Migrations()
+}
+
+// CreateDatabaseBackup copies the database into the backup directory and
+// returns the backup's file name
+func (a *App) CreateDatabaseBackup() (string, error) {
+	if a.db == nil {
+		return "", errors.New("database not initialized")
+	}
+	backupPath, err := a.GetBackupPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
+		return "", err
 	}
+	name := database.BackupName("")
+	if err := a.db.Backup(filepath.Join(backupPath, name)); err != nil {
+		return "", err
+	}
+	log.Printf("[DB] Backed up database to %s", name)
+	return name, nil
+}
 
-	var files []string
+// ListDatabaseBackups returns the file names of the database backups,
+// newest first
+func (a *App) ListDatabaseBackups() ([]string, error) {
+	backupPath, err := a.GetBackupPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+	names := []string{}
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			files = append(files, entry.Name())
+		if entry.Type().IsRegular() && isDatabaseBackup(entry.Name()) {
+			names = append(names, entry.Name())
 		}
 	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	return names, nil
+}
+
+// RestoreDatabaseBackup replaces the database's records with those of a
+// backup listed by ListDatabaseBackups
+func (a *App) RestoreDatabaseBackup(name string) error {
+	if a.db == nil {
+		return errors.New("database not initialized")
+	}
+	if !isDatabaseBackup(name) || filepath.Base(name) != name {
+		return fmt.Errorf("%q: %w", name, storage.ErrInvalidPath)
+	}
+	backupPath, err := a.GetBackupPath()
+	if err != nil {
+		return err
+	}
+	if err := a.db.Restore(filepath.Join(backupPath, name)); err != nil {
+		return err
+	}
+	log.Printf("[DB] Restored database from %s", name)
+	a.auditLog.Record(audit.Entry{Event: audit.EventBackupRestored, Detail: name})
+	return nil
+}
 
-	return files, nil
+func isDatabaseBackup(name string) bool {
+	return strings.HasPrefix(name, database.BackupPrefix) && strings.HasSuffix(name, ".db")
 }
 
 // WindowMinimize minimizes the window
@@ -364,18 +740,23 @@ func (a *App) CreateLocalTerminal(shell, cwd string, env map[string]string) (str
 }
 
 // CreateSSHTerminal creates a new SSH terminal session
-func (a *App) CreateSSHTerminal(host string, port int, username, password, privateKey string) (string, error) {
+// hostKeyPolicy is "strict" (default when empty), "accept-new", "ask" or "off". With "ask"
+// the backend emits terminal:host-key-prompt during the handshake and waits for
+// RespondSSHHostKeyPrompt. isGuest keeps keys accepted along the way in memory only.
+func (a *App) CreateSSHTerminal(host string, port int, username, password, privateKey, hostKeyPolicy string, isGuest bool) (string, error) {
 	if a.terminalManager == nil {
 		return "", errors.New("terminal manager not initialized")
 	}
 
 	fmt.Println("Creating SSH terminal for host:", host, "port:", port, "username:", username)
 	config := terminal.ConnectionConfig{
-		Host:       host,
-		Port:       port,
-		Username:   username,
-		Password:   password,
-		PrivateKey: privateKey,
+		Host:          host,
+		Port:          port,
+		Username:      username,
+		Password:      password,
+		PrivateKey:    privateKey,
+		HostKeyPolicy: terminal.HostKeyPolicy(hostKeyPolicy),
+		GuestMode:     isGuest,
 	}
 
 	return a.terminalManager.CreateSSHSession("", config)
@@ -397,6 +778,15 @@ func (a *App) WriteToTerminal(sessionID string, data string) error {
 	return a.terminalManager.WriteToSession(sessionID, []byte(data))
 }
 
+// AckTerminalOutput acknowledges terminal:output events up to seq so the
+// backend keeps streaming; output pauses while too much is unacknowledged
+func (a *App) AckTerminalOutput(sessionID string, seq uint64) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.AckOutput(sessionID, seq)
+}
+
 // ResizeTerminal resizes terminal dimensions
 func (a *App) ResizeTerminal(sessionID string, cols, rows int) error {
 	if a.terminalManager == nil {
@@ -414,17 +804,20 @@ func (a *App) CloseTerminal(sessionID string) error {
 }
 
 // ReconnectTerminal attempts to reconnect a disconnected SSH terminal session
-func (a *App) ReconnectTerminal(sessionID, host string, port int, username, password, privateKey string) error {
+// hostKeyPolicy and isGuest work as for CreateSSHTerminal
+func (a *App) ReconnectTerminal(sessionID, host string, port int, username, password, privateKey, hostKeyPolicy string, isGuest bool) error {
 	if a.terminalManager == nil {
 		return errors.New("terminal manager not initialized")
 	}
 
 	config := terminal.ConnectionConfig{
-		Host:       host,
-		Port:       port,
-		Username:   username,
-		Password:   password,
-		PrivateKey: privateKey,
+		Host:          host,
+		Port:          port,
+		Username:      username,
+		Password:      password,
+		PrivateKey:    privateKey,
+		HostKeyPolicy: terminal.HostKeyPolicy(hostKeyPolicy),
+		GuestMode:     isGuest,
 	}
 
 	return a.terminalManager.ReconnectSession(sessionID, config)
@@ -438,17 +831,60 @@ func (a *App) GetTerminalMetadata(sessionID string) (terminal.SessionMetadata, e
 	return a.terminalManager.GetSessionMetadata(sessionID)
 }
 
-// GetGuestEncryptionKeyphrase returns the encryption keyphrase for guest mode from environment/config
-func (a *App) GetGuestEncryptionKeyphrase() string {
-	// Try to get from environment variable first
-	keyphrase := os.Getenv("HOST_VAULT_GUEST_KEYPHRASE")
-	if keyphrase != "" {
-		return keyphrase
+// GetTerminalCommandHistory returns the commands detected via shell integration (OSC 133) for a session
+func (a *App) GetTerminalCommandHistory(sessionID string) ([]terminal.CommandRecord, error) {
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.GetCommandHistory(sessionID)
+}
+
+// GetTerminalCommandOutput returns the plain-text output of a command from the session's history
+func (a *App) GetTerminalCommandOutput(sessionID string, commandID int) (string, error) {
+	if a.terminalManager == nil {
+		return "", errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.GetCommandOutput(sessionID, commandID)
+}
+
+// SetTerminalOutputFraming selects how terminal output is encoded: "text"
+// (UTF-8 strings) or "base64" (the exact byte stream)
+func (a *App) SetTerminalOutputFraming(framing string) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	parsed, err := terminal.ParseOutputFraming(framing)
+	if err != nil {
+		return err
+	}
+	a.terminalManager.SetOutputFraming(parsed)
+	return nil
+}
+
+// GetTerminalOutputStats returns output batching metrics (events, bytes per event) for a session
+func (a *App) GetTerminalOutputStats(sessionID string) (terminal.OutputStats, error) {
+	if a.terminalManager == nil {
+		return terminal.OutputStats{}, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.GetOutputStats(sessionID)
+}
+
+// GetGuestEncryptionKeyphrase returns this install's random keyphrase for
+// encrypting guest mode credentials
+func (a *App) GetGuestEncryptionKeyphrase() (string, error) {
+	if a.guestKey == nil {
+		return "", errors.New("guest key not initialized")
 	}
+	return a.guestKey.Keyphrase()
+}
 
-	// Default keyphrase (should be changed in production via .env)
-	// In production, this should be set via environment variable
-	return "host-vault-guest-default-keyphrase-change-in-production"
+// RotateGuestEncryptionKey replaces the guest keyphrase and re-encrypts the
+// guest connections and commands under the new one
+func (a *App) RotateGuestEncryptionKey() error {
+	if a.guestKey == nil {
+		return errors.New("guest key not initialized")
+	}
+	return a.guestKey.Rotate()
 }
 
 // GetSSHHostKeyInfo gets the host key fingerprint for an SSH host
@@ -469,7 +905,12 @@ func (a *App) GetSSHHostKeyInfo(host string, port int) (map[string]interface{},
 		"keyBase64":           info.KeyBase64,
 		"isKnown":             info.IsKnown,
 		"isMismatch":          info.IsMismatch,
+		"isRevoked":           info.IsRevoked,
+		"isCertified":         info.IsCertified,
 		"expectedFingerprint": info.ExpectedFingerprint,
+		"knownKeyTypes":       info.KnownKeyTypes,
+		"sshfp":               info.SSHFP,
+		"sshfpSecure":         info.SSHFPSecure,
 	}, nil
 }
 
@@ -482,3 +923,674 @@ func (a *App) AcceptSSHHostKey(host string, port int, keyBase64 string, isGuest
 
 	return a.terminalManager.AcceptHostKey(host, port, keyBase64, isGuest)
 }
+
+// ScanSSHHostKeys collects the host keys of many hosts concurrently, one handshake per key type,
+// and reports which are new, match, mismatch or are unreachable. Targets may name saved connections
+// by connectionId instead of giving a host. Nothing is stored.
+func (a *App) ScanSSHHostKeys(targets []terminal.HostKeyScanTarget) (*terminal.HostKeyScanReport, error) {
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.ScanHostKeys(targets)
+}
+
+// AcceptSSHHostKeys stores several host keys at once, typically the new keys of a scan report
+// Returns the number of keys stored
+func (a *App) AcceptSSHHostKeys(keys []terminal.HostKeyAcceptance, isGuest bool) (int, error) {
+	if a.terminalManager == nil {
+		return 0, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.AcceptHostKeys(keys, isGuest)
+}
+
+// RespondSSHHostKeyPrompt answers a terminal:host-key-prompt event; accepted keys are stored
+func (a *App) RespondSSHHostKeyPrompt(promptID string, accept bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.RespondHostKeyPrompt(promptID, accept)
+}
+
+// ImportSSHKnownHosts imports the user's ~/.ssh/known_hosts into the app's
+// trusted hosts. Returns the number of new entries.
+func (a *App) ImportSSHKnownHosts() (int, error) {
+	if a.terminalManager == nil {
+		return 0, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.ImportKnownHosts("")
+}
+
+// ImportSSHKnownHostsFromFile imports an OpenSSH known_hosts file the user
+// picks. Returns the number of new entries, 0 if the user cancelled.
+func (a *App) ImportSSHKnownHostsFromFile() (int, error) {
+	if a.terminalManager == nil {
+		return 0, errors.New("terminal manager not initialized")
+	}
+	path, err := a.showOpenDialog("Import Known Hosts", "known-hosts")
+	if err != nil || path == "" {
+		return 0, err
+	}
+	return a.terminalManager.ImportKnownHosts(path)
+}
+
+// ExportSSHKnownHosts exports the app's trusted hosts in OpenSSH
+// known_hosts format to a file the user picks. Returns false if cancelled.
+func (a *App) ExportSSHKnownHosts() (bool, error) {
+	if a.terminalManager == nil {
+		return false, errors.New("terminal manager not initialized")
+	}
+	path, err := a.showSaveDialog("Export Known Hosts", "known_hosts", "known-hosts")
+	if err != nil || path == "" {
+		return false, err
+	}
+	if err := a.terminalManager.ExportKnownHosts(path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExportSSHKnownHostEntries exports only the selected known hosts (IDs from
+// ListSSHKnownHosts), like ExportSSHKnownHosts
+func (a *App) ExportSSHKnownHostEntries(ids []string) (bool, error) {
+	if a.terminalManager == nil {
+		return false, errors.New("terminal manager not initialized")
+	}
+	path, err := a.showSaveDialog("Export Known Hosts", "known_hosts", "known-hosts")
+	if err != nil || path == "" {
+		return false, err
+	}
+	if err := a.terminalManager.ExportKnownHostEntries(path, ids); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListSSHKnownHosts lists all known host keys, including read-only ones from ~/.ssh/known_hosts
+func (a *App) ListSSHKnownHosts() ([]terminal.KnownHostRecord, error) {
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.ListKnownHosts()
+}
+
+// RemoveSSHKnownHost removes a single known host key by its ID
+func (a *App) RemoveSSHKnownHost(id string) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.RemoveKnownHost(id)
+}
+
+// RemoveSSHHostKey removes every key stored for a host
+func (a *App) RemoveSSHHostKey(host string, port int) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.RemoveHostKey(host, port)
+}
+
+// RekeySSHHostKey replaces every key stored for a host, e.g. after the server was rebuilt
+// keyBase64 is the key reported by GetSSHHostKeyInfo
+func (a *App) RekeySSHHostKey(host string, port int, keyBase64 string, isGuest bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.RekeyHostKey(host, port, keyBase64, isGuest)
+}
+
+// ForgetGuestSSHHostKeys drops the in-memory host keys accepted in guest mode
+// Returns the number of keys removed
+func (a *App) ForgetGuestSSHHostKeys() (int, error) {
+	if a.terminalManager == nil {
+		return 0, errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.ForgetGuestHostKeys()
+}
+
+// SetSSHFPConfig configures host key verification against SSHFP DNS records
+// Matching records show up in GetSSHHostKeyInfo and, with autoTrust, accept unknown keys
+// when the answer is DNSSEC validated
+func (a *App) SetSSHFPConfig(config terminal.SSHFPConfig) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.SetSSHFPConfig(config)
+}
+
+// SetUseSystemKnownHosts enables or disables ~/.ssh/known_hosts as a read-only source of trusted host keys
+// It is off at startup
+func (a *App) SetUseSystemKnownHosts(enabled bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.SetUseSystemKnownHosts(enabled)
+}
+
+// CreateSSHTerminalForConnection opens an SSH terminal for a connection saved
+// in the vault; its credentials are resolved in Go and never sent to the frontend
+func (a *App) CreateSSHTerminalForConnection(connectionID string, isGuest bool) (string, error) {
+	if a.terminalManager == nil {
+		return "", errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.CreateSSHSessionForConnection(connectionID, isGuest)
+}
+
+// ReconnectTerminalForConnection reconnects a terminal opened with
+// CreateSSHTerminalForConnection using the connection's current vault entry
+func (a *App) ReconnectTerminalForConnection(sessionID string, isGuest bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.ReconnectSessionForConnection(sessionID, isGuest)
+}
+
+// GetVaultStatus reports whether the vault is set up and unlocked
+func (a *App) GetVaultStatus() (vault.Status, error) {
+	if a.vault == nil {
+		return vault.Status{}, errors.New("vault not initialized")
+	}
+	return a.vault.Status(), nil
+}
+
+// SetupVault creates the vault with a master password and unlocks it
+func (a *App) SetupVault(masterPassword string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.Initialize(masterPassword)
+}
+
+// UnlockVault unlocks the vault with the master password
+func (a *App) UnlockVault(masterPassword string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	if err := a.vault.Unlock(masterPassword); err != nil {
+		if errors.Is(err, vault.ErrInvalidPassword) {
+			a.auditLog.Record(audit.Entry{Event: audit.EventVaultUnlockFailed, Detail: "master password"})
+		}
+		return err
+	}
+	a.auditLog.Record(audit.Entry{Event: audit.EventVaultUnlock, Detail: "master password"})
+	a.vaultUnlocked()
+	return nil
+}
+
+// RememberVaultMasterKey caches the vault master key in the OS keychain
+// (GNOME Keyring, KWallet, Credential Manager) so UnlockVaultFromKeychain
+// works without the password. Refused with only the file fallback, which
+// would leave the key on the same disk as the vault.
+func (a *App) RememberVaultMasterKey(masterPassword string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	if !keychain.IsOSBackend(a.keychain) {
+		return errors.New("no OS keychain available to remember the master key")
+	}
+
+	masterKey, err := a.vault.MasterKey(masterPassword)
+	if err != nil {
+		return err
+	}
+	return a.keychain.Set(vaultMasterKeyName, base64.StdEncoding.EncodeToString(masterKey))
+}
+
+// ForgetVaultMasterKey removes the cached master key from the keychain
+func (a *App) ForgetVaultMasterKey() error {
+	if a.keychain == nil {
+		return errors.New("keychain not initialized")
+	}
+	return a.keychain.Delete(vaultMasterKeyName)
+}
+
+// UnlockVaultFromKeychain unlocks the vault with the cached master key.
+// Returns false if none is cached, or if the vault locked itself since the
+// master password was last entered; a stale key is removed.
+func (a *App) UnlockVaultFromKeychain() (bool, error) {
+	if a.vault == nil {
+		return false, errors.New("vault not initialized")
+	}
+	if !keychain.IsOSBackend(a.keychain) || a.vault.Status().PasswordRequired {
+		return false, nil
+	}
+
+	encoded, err := a.keychain.Get(vaultMasterKeyName)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	masterKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		a.keychain.Delete(vaultMasterKeyName)
+		return false, nil
+	}
+
+	if err := a.vault.UnlockWithMasterKey(masterKey); errors.Is(err, vault.ErrInvalidPassword) {
+		// Cached before the master password changed
+		a.auditLog.Record(audit.Entry{Event: audit.EventVaultUnlockFailed, Detail: "keychain"})
+		a.keychain.Delete(vaultMasterKeyName)
+		return false, nil
+	} else if errors.Is(err, vault.ErrPasswordRequired) {
+		// Locked automatically since the check above
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	a.auditLog.Record(audit.Entry{Event: audit.EventVaultUnlock, Detail: "keychain"})
+	a.vaultUnlocked()
+	return true, nil
+}
+
+// LockVault wipes the vault key from memory
+func (a *App) LockVault() error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	a.vault.Lock()
+	return nil
+}
+
+// TouchVault reports user activity, postponing the idle lock
+func (a *App) TouchVault() {
+	if a.vault != nil {
+		a.vault.Touch()
+	}
+}
+
+// GetVaultAutoLockSettings returns when the vault locks itself and what
+// happens to sessions using its credentials
+func (a *App) GetVaultAutoLockSettings() (vault.AutoLockSettings, error) {
+	if a.vault == nil {
+		return vault.AutoLockSettings{}, errors.New("vault not initialized")
+	}
+	return a.vault.AutoLockSettings(), nil
+}
+
+// SetVaultAutoLockSettings changes the auto-lock settings; the vault must
+// be unlocked
+func (a *App) SetVaultAutoLockSettings(settings vault.AutoLockSettings) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.SetAutoLockSettings(settings)
+}
+
+// ChangeVaultPassword replaces the master password
+func (a *App) ChangeVaultPassword(currentPassword, newPassword string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	if err := a.vault.ChangePassword(currentPassword, newPassword); err != nil {
+		return err
+	}
+
+	// Keep a remembered master key in step with the new password
+	if !keychain.IsOSBackend(a.keychain) {
+		return nil
+	}
+	if _, err := a.keychain.Get(vaultMasterKeyName); err != nil {
+		return nil
+	}
+	return a.RememberVaultMasterKey(newPassword)
+}
+
+// ListVaultConnections returns the saved connections without their secrets
+func (a *App) ListVaultConnections() ([]vault.Connection, error) {
+	if a.vault == nil {
+		return nil, errors.New("vault not initialized")
+	}
+	return a.vault.ListConnections()
+}
+
+// GetVaultConnection returns one saved connection without its secrets
+func (a *App) GetVaultConnection(connectionID string) (vault.Connection, error) {
+	if a.vault == nil {
+		return vault.Connection{}, errors.New("vault not initialized")
+	}
+	return a.vault.GetConnection(connectionID)
+}
+
+// SaveVaultConnection creates (empty id) or updates a saved connection
+func (a *App) SaveVaultConnection(conn vault.Connection) (vault.Connection, error) {
+	if a.vault == nil {
+		return vault.Connection{}, errors.New("vault not initialized")
+	}
+	return a.vault.SaveConnection(conn)
+}
+
+// DeleteVaultConnection removes a saved connection and its credentials
+func (a *App) DeleteVaultConnection(connectionID string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.DeleteConnection(connectionID)
+}
+
+// SetVaultCredential stores a connection's password and/or private key.
+// This is write-only: secrets are never returned to the frontend.
+func (a *App) SetVaultCredential(connectionID string, credential vault.Credential) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.SetCredential(connectionID, credential)
+}
+
+// GenerateSSHKey creates a key pair and stores it in the vault under name
+func (a *App) GenerateSSHKey(name string, options sshkeys.GenerateOptions) (vault.SSHKey, error) {
+	if a.vault == nil {
+		return vault.SSHKey{}, errors.New("vault not initialized")
+	}
+	if !a.vault.IsUnlocked() {
+		return vault.SSHKey{}, vault.ErrLocked
+	}
+
+	pair, err := sshkeys.Generate(options)
+	if err != nil {
+		return vault.SSHKey{}, err
+	}
+	return a.vault.AddKey(vault.SSHKey{
+		Name:        name,
+		Type:        pair.Info.Type,
+		Bits:        pair.Info.Bits,
+		Comment:     pair.Info.Comment,
+		PublicKey:   pair.PublicKey,
+		Fingerprint: pair.Info.Fingerprint,
+		RandomArt:   pair.Info.RandomArt,
+		Encrypted:   options.Passphrase != "",
+	}, pair.PrivateKey)
+}
+
+// InspectPrivateKey detects a private key's format (OpenSSH, PEM or PuTTY)
+// and describes it. Without the passphrase an encrypted key is described as
+// far as its format allows.
+func (a *App) InspectPrivateKey(privateKey string, passphrase string) (*sshkeys.PrivateKeyInfo, error) {
+	return sshkeys.Inspect([]byte(privateKey), passphrase)
+}
+
+// ConvertPrivateKey rewrites a private key as openssh, pkcs8 or ppk3,
+// encrypted with newPassphrase when it is set
+func (a *App) ConvertPrivateKey(privateKey string, passphrase string, format string, newPassphrase string) (string, error) {
+	converted, err := sshkeys.Convert([]byte(privateKey), passphrase, sshkeys.Format(format), newPassphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(converted), nil
+}
+
+// ImportSSHKey stores an existing private key of any supported format in
+// the vault. It is kept in OpenSSH format, encrypted with the same
+// passphrase if it was encrypted.
+func (a *App) ImportSSHKey(name string, privateKey string, passphrase string) (vault.SSHKey, error) {
+	if a.vault == nil {
+		return vault.SSHKey{}, errors.New("vault not initialized")
+	}
+	if !a.vault.IsUnlocked() {
+		return vault.SSHKey{}, vault.ErrLocked
+	}
+
+	info, err := sshkeys.Inspect([]byte(privateKey), passphrase)
+	if err != nil {
+		return vault.SSHKey{}, err
+	}
+	if !info.Decrypted {
+		return vault.SSHKey{}, sshkeys.ErrPassphraseRequired
+	}
+	if !info.Encrypted {
+		passphrase = ""
+	}
+	converted, err := sshkeys.Convert([]byte(privateKey), passphrase, sshkeys.FormatOpenSSH, passphrase)
+	if err != nil {
+		return vault.SSHKey{}, err
+	}
+	return a.vault.AddKey(vault.SSHKey{
+		Name:        name,
+		Type:        info.Key.Type,
+		Bits:        info.Key.Bits,
+		Comment:     info.Key.Comment,
+		PublicKey:   info.PublicKey,
+		Fingerprint: info.Key.Fingerprint,
+		RandomArt:   info.Key.RandomArt,
+		Encrypted:   info.Encrypted,
+	}, string(converted))
+}
+
+// ListSSHKeys returns the keys stored in the vault without their private
+// halves; this works while the vault is locked
+func (a *App) ListSSHKeys() ([]vault.SSHKey, error) {
+	if a.vault == nil {
+		return nil, errors.New("vault not initialized")
+	}
+	return a.vault.ListKeys()
+}
+
+// RenameSSHKey changes the display name of a stored key
+func (a *App) RenameSSHKey(keyID string, name string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.RenameKey(keyID, name)
+}
+
+// DeleteSSHKey removes a key pair from the vault
+func (a *App) DeleteSSHKey(keyID string) error {
+	if a.vault == nil {
+		return errors.New("vault not initialized")
+	}
+	return a.vault.DeleteKey(keyID)
+}
+
+// InstallSSHKeyForConnection installs a vault key on a saved connection's
+// host like ssh-copy-id, logging in with the connection's credentials, and
+// checks that the key then works. passphrase opens an encrypted key. With
+// switchToKey, a verified key replaces the connection's credential.
+func (a *App) InstallSSHKeyForConnection(connectionID string, keyID string, passphrase string, switchToKey bool, isGuest bool) (*terminal.KeyInstallResult, error) {
+	if a.vault == nil {
+		return nil, errors.New("vault not initialized")
+	}
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	key, err := a.vault.GetKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.Encrypted && passphrase == "" {
+		return nil, errors.New("passphrase required for this key")
+	}
+	privateKey, err := a.vault.PrivateKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.terminalManager.InstallPublicKeyForConnection(connectionID, isGuest, key.PublicKey, privateKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if switchToKey && result.Verified {
+		credential := vault.Credential{PrivateKey: privateKey, Passphrase: passphrase}
+		if err := a.vault.SetCredential(connectionID, credential); err != nil {
+			return nil, fmt.Errorf("key installed, but the connection still uses its old credentials: %w", err)
+		}
+		result.SwitchedToKey = true
+	}
+	return result, nil
+}
+
+// ListRemoteAuthorizedKeys reads a host's ~/.ssh/authorized_keys and flags
+// keys that aren't in the vault or are stale
+func (a *App) ListRemoteAuthorizedKeys(connectionID string, isGuest bool) (*terminal.AuthorizedKeys, error) {
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	known, err := a.vaultKeyRefs()
+	if err != nil {
+		return nil, err
+	}
+	return a.terminalManager.ListAuthorizedKeys(connectionID, isGuest, known)
+}
+
+// AddRemoteAuthorizedKey appends an authorized_keys line, options and all,
+// on a host
+func (a *App) AddRemoteAuthorizedKey(connectionID string, line string, isGuest bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.AddAuthorizedKey(connectionID, isGuest, line)
+}
+
+// RemoveRemoteAuthorizedKey revokes a key on a host. version comes from
+// ListRemoteAuthorizedKeys.
+func (a *App) RemoveRemoteAuthorizedKey(connectionID string, fingerprint string, version string, isGuest bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.RemoveAuthorizedKey(connectionID, isGuest, fingerprint, version)
+}
+
+// AnnotateRemoteAuthorizedKey sets the comment of a key on a host
+func (a *App) AnnotateRemoteAuthorizedKey(connectionID string, fingerprint string, comment string, version string, isGuest bool) error {
+	if a.terminalManager == nil {
+		return errors.New("terminal manager not initialized")
+	}
+	return a.terminalManager.AnnotateAuthorizedKey(connectionID, isGuest, fingerprint, comment, version)
+}
+
+// GetAuthorizedKeysReport reads authorized_keys on every given host and
+// reports which keys grant access where
+func (a *App) GetAuthorizedKeysReport(connectionIDs []string, isGuest bool) (*terminal.AuthorizedKeysReport, error) {
+	if a.terminalManager == nil {
+		return nil, errors.New("terminal manager not initialized")
+	}
+	known, err := a.vaultKeyRefs()
+	if err != nil {
+		return nil, err
+	}
+	return a.terminalManager.AuthorizedKeysReport(connectionIDs, isGuest, known), nil
+}
+
+// vaultKeyRefs maps the fingerprints of the vault's keys to the keys
+func (a *App) vaultKeyRefs() (map[string]terminal.VaultKeyRef, error) {
+	if a.vault == nil {
+		return nil, errors.New("vault not initialized")
+	}
+	keys, err := a.vault.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]terminal.VaultKeyRef, len(keys))
+	for _, key := range keys {
+		known[key.Fingerprint] = terminal.VaultKeyRef{ID: key.ID, Name: key.Name}
+	}
+	return known, nil
+}
+
+// ExportSSHPublicKey writes a stored public key to a .pub file the user
+// picks. Returns false if cancelled.
+func (a *App) ExportSSHPublicKey(keyID string) (bool, error) {
+	if a.vault == nil {
+		return false, errors.New("vault not initialized")
+	}
+	key, err := a.vault.GetKey(keyID)
+	if err != nil {
+		return false, err
+	}
+	path, err := a.showSaveDialog("Export Public Key", sshkeys.FileName(key.Type)+".pub", "ssh-key")
+	if err != nil || path == "" {
+		return false, err
+	}
+	if err := storage.WriteExport(path, []byte(key.PublicKey+"\n")); err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+	return true, nil
+}
+
+// ExportSSHPrivateKey writes a stored private key, in OpenSSH format and
+// still encrypted if it has a passphrase, to a file the user picks.
+// Returns false if cancelled.
+func (a *App) ExportSSHPrivateKey(keyID string) (bool, error) {
+	if a.vault == nil {
+		return false, errors.New("vault not initialized")
+	}
+	key, err := a.vault.GetKey(keyID)
+	if err != nil {
+		return false, err
+	}
+	privateKey, err := a.vault.PrivateKey(keyID)
+	if err != nil {
+		return false, err
+	}
+	path, err := a.showSaveDialog("Export Private Key", sshkeys.FileName(key.Type), "ssh-key")
+	if err != nil || path == "" {
+		return false, err
+	}
+	// Created 0600, which ssh insists on for private keys
+	if err := storage.WriteExport(path, []byte(privateKey)); err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+	a.auditLog.Record(audit.Entry{
+		Event:       audit.EventCredentialExported,
+		KeyType:     key.Type,
+		Fingerprint: key.Fingerprint,
+		Detail:      "private key " + key.Name,
+	})
+	return true, nil
+}
+
+// QueryAuditLog returns the matching audit entries, newest first
+func (a *App) QueryAuditLog(query audit.Query) ([]audit.Entry, error) {
+	if a.auditLog == nil {
+		return nil, errors.New("audit log not initialized")
+	}
+	return a.auditLog.Query(query)
+}
+
+// VerifyAuditLog checks the audit log's hash chain. A broken chain is
+// reported in the result, not as an error.
+func (a *App) VerifyAuditLog() (audit.Verification, error) {
+	if a.auditLog == nil {
+		return audit.Verification{}, errors.New("audit log not initialized")
+	}
+	return a.auditLog.Verify()
+}
+
+// ExportAuditLog writes the audit log as JSON lines to a file the user
+// picks, for review outside the app. Returns false if cancelled.
+func (a *App) ExportAuditLog() (bool, error) {
+	if a.auditLog == nil {
+		return false, errors.New("audit log not initialized")
+	}
+	data, err := a.auditLog.Export()
+	if err != nil {
+		return false, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	path, err := a.showSaveDialog("Export Audit Log", "host-vault-audit.jsonl", "jsonl")
+	if err != nil || path == "" {
+		return false, err
+	}
+	if err := storage.WriteExport(path, data); err != nil {
+		return false, fmt.Errorf("failed to write file: %w", err)
+	}
+	return true, nil
+}
+
+// RecordAuditEvent records an event only the frontend sees: a credential
+// shown or exported, or a backup restored
+func (a *App) RecordAuditEvent(event string, detail string) error {
+	if a.auditLog == nil {
+		return errors.New("audit log not initialized")
+	}
+	if !frontendAuditEvents[audit.Event(event)] {
+		return fmt.Errorf("unknown audit event: %s", event)
+	}
+	if len(detail) > maxAuditDetailSize {
+		detail = detail[:maxAuditDetailSize]
+	}
+	_, err := a.auditLog.Append(audit.Entry{
+		Event:  audit.Event(event),
+		Detail: detail,
+		Source: "frontend",
+	})
+	return err
+}
@@ -15,11 +15,14 @@ import { ErrorBoundary } from './components/ErrorBoundary';
 import { SettingsPage } from './pages/SettingsPage';
 import { TerminalPage } from './pages/TerminalPage';
 import { applyTheme } from './lib/themes';
+import { useVaultActivity } from './hooks/useVaultActivity';
 
 function App() {
   const { isAuthenticated, isGuestMode, masterPasswordSet, user } = useAuthStore();
   const { config, loadUserConfig, loadGuestConfig } = useUserConfigStore();
 
+  useVaultActivity();
+
   // Prevent Ctrl+A (Select All) globally for desktop app feel
   React.useEffect(() => {
     const handleKeyDown = (e: KeyboardEvent) => {
@@ -16,7 +16,7 @@ import {
 import type { SSHConnection } from '../../types';
 import { decryptPassword, decryptPrivateKey } from '../../lib/encryption/crypto';
 import { useAuthStore } from '../../store/authStore';
-import { ShowOpenFileDialog, ReadFile } from '../../../wailsjs/go/main/App';
+import { ImportFileWithDialog, RecordAuditEvent } from '../../../wailsjs/go/main/App';
 
 export type AuthMethod = 'password' | 'key' | 'certificate';
 
@@ -166,9 +166,8 @@ export const ConnectionModal: React.FC<ConnectionModalProps> = ({
 
   const handleBrowsePrivateKey = async () => {
     try {
-      const filePath = await ShowOpenFileDialog('Select SSH Private Key', 'ssh-key');
-      if (filePath) {
-        const content = await ReadFile(filePath);
+      const content = await ImportFileWithDialog('Select SSH Private Key', 'ssh-key');
+      if (content) {
         setFormData({ ...formData, privateKey: content });
       }
     } catch (error) {
@@ -178,9 +177,8 @@ export const ConnectionModal: React.FC<ConnectionModalProps> = ({
 
   const handleBrowseCertificate = async () => {
     try {
-      const filePath = await ShowOpenFileDialog('Select SSH Certificate', 'ssh-cert');
-      if (filePath) {
-        const content = await ReadFile(filePath);
+      const content = await ImportFileWithDialog('Select SSH Certificate', 'ssh-cert');
+      if (content) {
         setFormData({ ...formData, certificate: content });
       }
     } catch (error) {
@@ -391,7 +389,13 @@ export const ConnectionModal: React.FC<ConnectionModalProps> = ({
                     />
                     <button
                       type="button"
-                      onClick={() => setShowPassword(!showPassword)}
+                      onClick={() => {
+                        if (!showPassword && hasExistingPassword && editingConnection) {
+                          RecordAuditEvent('credential.viewed', `password for ${editingConnection.username}@${editingConnection.host}`)
+                            .catch((error) => console.error('Failed to record audit event:', error));
+                        }
+                        setShowPassword(!showPassword);
+                      }}
                       className="absolute right-3 top-1/2 -translate-y-1/2 text-text-muted hover:text-text-primary"
                     >
                       {showPassword ? <EyeOff className="w-4 h-4" /> : <Eye className="w-4 h-4" />}
@@ -1,98 +1,30 @@
 import React, { useState, useEffect } from 'react';
-import { X, Shield, AlertTriangle, CheckCircle, Loader, Copy } from 'lucide-react';
+import { X, Shield, AlertTriangle, CheckCircle, Copy } from 'lucide-react';
+import type { HostKeyPromptEvent } from '../../types/terminal';
 
-// Access Wails functions through window object to avoid TypeScript import errors
-// These functions will be available after bindings are regenerated with 'wails dev' or 'wails build'
-const getSSHHostKeyInfo = (host: string, port: number): Promise<any> | undefined => {
-  const wailsApp = (window as any)?.go?.main?.App;
-  if (wailsApp?.GetSSHHostKeyInfo) {
-    return wailsApp.GetSSHHostKeyInfo(host, port);
-  }
-  return undefined;
-};
-
-const acceptSSHHostKey = (host: string, port: number, keyBase64: string, isGuest: boolean): Promise<void> | undefined => {
-  const wailsApp = (window as any)?.go?.main?.App;
-  if (wailsApp?.AcceptSSHHostKey) {
-    return wailsApp.AcceptSSHHostKey(host, port, keyBase64, isGuest);
-  }
-  return undefined;
-};
-
-export interface HostKeyInfo {
-  fingerprintSHA256: string;
-  fingerprintMD5: string;
-  keyType: string;
-  keyBase64: string;
-  isKnown: boolean;
-  isMismatch: boolean;
-  expectedFingerprint: string;
-}
+export type HostKeyInfo = HostKeyPromptEvent['HostKey'];
 
 interface HostKeyVerificationModalProps {
-  isOpen: boolean;
-  host: string;
-  port: number;
-  isGuest?: boolean;
+  // The handshake waiting on the user; the modal is hidden without one
+  prompt: HostKeyPromptEvent | null;
   onAccept: () => void;
   onReject: () => void;
 }
 
+/**
+ * Asks whether to trust a host key presented during a handshake that uses
+ * the "ask" host key policy. The backend stores accepted keys.
+ */
 export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> = ({
-  isOpen,
-  host,
-  port,
-  isGuest = false,
+  prompt,
   onAccept,
   onReject,
 }) => {
-  const [hostKeyInfo, setHostKeyInfo] = useState<HostKeyInfo | null>(null);
-  const [isLoading, setIsLoading] = useState(false);
-  const [error, setError] = useState<string | null>(null);
   const [copied, setCopied] = useState(false);
 
   useEffect(() => {
-    if (isOpen) {
-      fetchHostKeyInfo();
-    } else {
-      // Reset state when modal closes
-      setHostKeyInfo(null);
-      setError(null);
-      setCopied(false);
-    }
-  }, [isOpen, host, port]);
-
-  const fetchHostKeyInfo = async () => {
-    setIsLoading(true);
-    setError(null);
-    try {
-      const result = getSSHHostKeyInfo(host, port);
-      if (!result) {
-        throw new Error('Wails bindings not regenerated. Please run "wails dev" or "wails build"');
-      }
-      const info = await result;
-      setHostKeyInfo(info as HostKeyInfo);
-    } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to get host key information');
-    } finally {
-      setIsLoading(false);
-    }
-  };
-
-  const handleAccept = async () => {
-    if (!hostKeyInfo) return;
-
-    try {
-      const result = acceptSSHHostKey(host, port, hostKeyInfo.keyBase64, isGuest);
-      if (!result) {
-        throw new Error('Wails bindings not regenerated. Please run "wails dev" or "wails build"');
-      }
-      await result;
-      onAccept();
-    } catch (err) {
-      setError(err instanceof Error ? err.message : 'Failed to accept host key');
-    }
-  };
+    setCopied(false);
+  }, [prompt?.PromptID]);
 
   const handleCopyFingerprint = (fingerprint: string) => {
     navigator.clipboard.writeText(fingerprint);
@@ -109,7 +41,10 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
     return fp;
   };
 
-  if (!isOpen) return null;
+  if (!prompt) return null;
+
+  const { Host: host, Port: port, HostKey: hostKeyInfo } = prompt;
+
 
   return (
     <div className="fixed inset-0 bg-black/50 backdrop-blur-sm flex items-center justify-center z-50 p-4">
@@ -124,8 +59,7 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
           </div>
           <button
             onClick={onReject}
-            disabled={isLoading}
-            className="p-1 rounded hover:bg-background-lighter text-text-muted hover:text-text-primary transition-colors disabled:opacity-50"
+            className="p-1 rounded hover:bg-background-lighter text-text-muted hover:text-text-primary transition-colors"
           >
             <X className="w-5 h-5" />
           </button>
@@ -133,26 +67,7 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
 
         {/* Content */}
         <div className="p-6 space-y-4">
-          {isLoading ? (
-            <div className="flex items-center justify-center py-8">
-              <Loader className="w-6 h-6 animate-spin text-primary" />
-              <span className="ml-3 text-text-muted">Retrieving host key information...</span>
-            </div>
-          ) : error ? (
-            <div className="bg-error/10 border border-error/20 rounded-lg p-4">
-              <div className="flex items-center gap-2 text-error mb-2">
-                <AlertTriangle className="w-5 h-5" />
-                <span className="font-medium">Error</span>
-              </div>
-              <p className="text-sm text-text-secondary">{error}</p>
-              <button
-                onClick={fetchHostKeyInfo}
-                className="mt-3 text-sm text-primary hover:underline"
-              >
-                Try again
-              </button>
-            </div>
-          ) : hostKeyInfo ? (
+          {hostKeyInfo ? (
             <>
               {/* Warning for mismatch */}
               {hostKeyInfo.isMismatch && (
@@ -185,8 +100,22 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
                   <p className="text-xs text-text-secondary mb-4">
                     {hostKeyInfo.isKnown
                       ? 'This host key is already known, but the fingerprint has changed.'
-                      : 'This is the first time connecting to this host.'}
+                      : hostKeyInfo.knownKeyTypes?.length
+                        ? `The host presented a ${hostKeyInfo.keyType} key; only ${hostKeyInfo.knownKeyTypes.join(', ')} keys are known for it so far.`
+                        : 'This is the first time connecting to this host.'}
                   </p>
+                  {hostKeyInfo.sshfp === 'match' && (
+                    <p className="text-xs text-success mb-4">
+                      {hostKeyInfo.sshfpSecure
+                        ? 'This key matches the host\'s DNSSEC-validated SSHFP record.'
+                        : 'This key matches the host\'s SSHFP record (not DNSSEC validated).'}
+                    </p>
+                  )}
+                  {hostKeyInfo.sshfp === 'mismatch' && (
+                    <p className="text-xs text-error mb-4">
+                      This key does not match the SSHFP records published for this host.
+                    </p>
+                  )}
                 </div>
 
                 <div className="bg-background rounded-lg p-4 border border-border space-y-3">
@@ -250,7 +179,7 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
                 <div className="bg-warning/10 border border-warning/20 rounded-lg p-3">
                   <p className="text-xs text-text-secondary">
                     <strong className="text-warning">Warning:</strong> Only continue if you are
-                    sure this is the correct host. Accepting an unknown or changed host key could
+                    sure this is the correct host. Accepting an unknown host key could
                     expose you to security risks.
                   </p>
                 </div>
@@ -263,27 +192,16 @@ export const HostKeyVerificationModal: React.FC<HostKeyVerificationModalProps> =
         <div className="flex items-center justify-end gap-3 px-6 py-4 border-t border-border bg-background/50">
           <button
             onClick={onReject}
-            disabled={isLoading}
-            className="px-4 py-2 text-sm text-text-secondary hover:text-text-primary transition-colors disabled:opacity-50"
+            className="px-4 py-2 text-sm text-text-secondary hover:text-text-primary transition-colors"
           >
             Cancel
           </button>
           <button
-            onClick={handleAccept}
-            disabled={isLoading || !hostKeyInfo}
-            className="flex items-center gap-2 px-4 py-2 text-sm bg-primary text-background font-medium rounded-lg hover:bg-primary-dark transition-all disabled:opacity-50 disabled:cursor-not-allowed"
+            onClick={onAccept}
+            className="flex items-center gap-2 px-4 py-2 text-sm bg-primary text-background font-medium rounded-lg hover:bg-primary-dark transition-all"
           >
-            {isLoading ? (
-              <>
-                <Loader className="w-4 h-4 animate-spin" />
-                Processing...
-              </>
-            ) : (
-              <>
-                <CheckCircle className="w-4 h-4" />
-                Accept & Continue
-              </>
-            )}
+            <CheckCircle className="w-4 h-4" />
+            Accept & Continue
           </button>
         </div>
       </div>
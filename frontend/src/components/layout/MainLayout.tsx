@@ -6,10 +6,14 @@ import { useAppStore } from '../../store/appStore';
 import { useLocation } from 'react-router-dom';
 import { ROUTES } from '../../lib/constants';
 import { TerminalDndProvider } from '../terminal/TerminalDndProvider';
+import { HostKeyVerificationModal } from '../connections/HostKeyVerificationModal';
+import { useTerminalStore } from '../../store/terminalStore';
 
 export const MainLayout: React.FC<{ children: React.ReactNode }> = ({ children }) => {
   const { toastNotifications, removeToast } = useAppStore();
   const location = useLocation();
+  const hostKeyPrompt = useTerminalStore((state) => state.hostKeyPrompts[0] ?? null);
+  const respondHostKeyPrompt = useTerminalStore((state) => state.respondHostKeyPrompt);
   
   // Check if we're on the terminal page
   const isTerminalPage = location.pathname === ROUTES.TERMINAL;
@@ -28,6 +32,11 @@ export const MainLayout: React.FC<{ children: React.ReactNode }> = ({ children }
           </main>
         </div>
         <ToastContainer toasts={toastNotifications} onRemove={removeToast} />
+        <HostKeyVerificationModal
+          prompt={hostKeyPrompt}
+          onAccept={() => hostKeyPrompt && respondHostKeyPrompt(hostKeyPrompt.PromptID, true)}
+          onReject={() => hostKeyPrompt && respondHostKeyPrompt(hostKeyPrompt.PromptID, false)}
+        />
       </TerminalDndProvider>
     </div>
   );
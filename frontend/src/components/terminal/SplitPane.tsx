@@ -322,16 +322,23 @@ const Pane: React.FC<{
       // Create a new terminal session (duplicate)
       let newSessionId: string;
       
-      if (session.type === 'ssh' && session.metadata.sshConfig) {
+      if (session.type === 'ssh' && session.metadata.sshConfig?.connectionId) {
+        // Saved connections reopen from the vault
+        newSessionId = await useTerminalStore.getState().createVaultSSHTerminal(
+          session.metadata.sshConfig.connectionId,
+          `${session.title} (Copy)`
+        );
+      } else if (session.type === 'ssh' && session.metadata.sshConfig) {
         // For SSH sessions, create a new SSH connection
-        const { host, port, username, password, privateKey } = session.metadata.sshConfig;
+        const { host, port, username, password, privateKey, hostKeyPolicy } = session.metadata.sshConfig;
         newSessionId = await useTerminalStore.getState().createSSHTerminal(
           host,
           port,
           username,
           password || '',
           privateKey,
-          `${session.title} (Copy)`
+          `${session.title} (Copy)`,
+          hostKeyPolicy
         );
       } else {
         // For local terminals, create a new local terminal
@@ -11,7 +11,7 @@ import {
   TerminalDisconnectedEvent,
   TerminalReconnectNeededEvent,
 } from '../../types/terminal';
-import { WriteToTerminal, ResizeTerminal } from '../../../wailsjs/go/main/App';
+import { WriteToTerminal, ResizeTerminal, AckTerminalOutput } from '../../../wailsjs/go/main/App';
 import { EventsOn, EventsOff, ClipboardGetText, ClipboardSetText } from '../../../wailsjs/runtime/runtime';
 import { getTerminalThemeFromCSS } from '../../lib/terminalTheme';
 import { useUserConfigStore } from '../../store/userConfigStore';
@@ -27,7 +27,7 @@ interface TerminalInstance {
   fitAddon: FitAddon;
   searchAddon: SearchAddon;
   container: HTMLDivElement;
-  outputBuffer: string[];
+  outputBuffer: (string | Uint8Array)[];
   isConsuming: boolean;
   inputDisposable: { dispose: () => void } | null;
   resizeObserver: ResizeObserver | null;
@@ -53,7 +53,7 @@ const MAX_TOTAL_TERMINAL_MEMORY = 100 * 1024 * 1024; // 100MB total for all term
 let globalEventsRegistered = false;
 
 // Memory management for output buffers
-function manageOutputBuffer(instance: TerminalInstance, newData: string) {
+function manageOutputBuffer(instance: TerminalInstance, newData: string | Uint8Array) {
   instance.outputBuffer.push(newData);
   instance.bufferSize += newData.length;
 
@@ -67,6 +67,16 @@ function manageOutputBuffer(instance: TerminalInstance, newData: string) {
   }
 }
 
+// Binary framing: hand xterm the exact bytes so it does its own UTF-8 decoding
+function decodeBase64Output(data: string): Uint8Array {
+  const binary = atob(data);
+  const bytes = new Uint8Array(binary.length);
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i);
+  }
+  return bytes;
+}
+
 function registerGlobalEvents() {
   if (globalEventsRegistered) return;
   globalEventsRegistered = true;
@@ -81,13 +91,22 @@ function registerGlobalEvents() {
   }, 30000); // Check every 30 seconds
 
   EventsOn('terminal:output', (event: TerminalOutputEvent) => {
+    // The backend pauses output until chunks are acknowledged
+    const ack = () => {
+      AckTerminalOutput(event.SessionID, event.Seq).catch(() => {});
+    };
     const inst = terminalInstances.get(event.SessionID);
     if (inst) {
+      const data = event.Encoding === 'base64' ? decodeBase64Output(event.Data) : event.Data;
       if (inst.isConsuming) {
-        inst.term.write(event.Data);
+        // Ack once xterm has parsed the chunk so a busy renderer slows the producer
+        inst.term.write(data, ack);
       } else {
-        manageOutputBuffer(inst, event.Data);
+        manageOutputBuffer(inst, data);
+        ack();
       }
+    } else {
+      ack();
     }
   });
 
@@ -0,0 +1,33 @@
+import { useEffect } from 'react';
+import { TouchVault } from '../../wailsjs/go/main/App';
+
+// How often activity is reported at most; the idle lock counts minutes
+const REPORT_INTERVAL_MS = 30_000;
+
+/**
+ * Report keyboard and pointer activity to the backend so the vault's idle
+ * lock only fires when the app is really unattended
+ */
+export function useVaultActivity() {
+  useEffect(() => {
+    if (typeof window === 'undefined' || !window.go?.main?.App) {
+      return;
+    }
+
+    let lastReport = 0;
+    const report = () => {
+      const now = Date.now();
+      if (now - lastReport < REPORT_INTERVAL_MS) {
+        return;
+      }
+      lastReport = now;
+      TouchVault().catch(() => {});
+    };
+
+    const events = ['keydown', 'mousedown', 'mousemove', 'wheel', 'touchstart'];
+    events.forEach((name) => window.addEventListener(name, report, { capture: true, passive: true }));
+    return () => {
+      events.forEach((name) => window.removeEventListener(name, report, { capture: true }));
+    };
+  }, []);
+}
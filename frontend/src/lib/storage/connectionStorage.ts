@@ -1,6 +1,19 @@
-import { GetGuestConnectionsPath, GetUserConnectionsPath, ReadFile, WriteFile, FileExists } from '../../../wailsjs/go/main/App';
 import type { SSHConnection } from '../../types';
 import { encryptDataWithKeyphrase, decryptDataWithKeyphrase } from '../encryption/crypto';
+import {
+  readVersionedFile,
+  writeVersionedFile,
+  mergeById,
+  storeFileKey,
+  STORE_FILE_VERSIONS,
+  type StoreFile,
+} from './versionedFile';
+
+/**
+ * IDs of the connections last read from or written to each file, used to
+ * tell connections deleted here from ones another instance added
+ */
+const baseConnectionIds = new Map<string, Set<string>>();
 
 /**
  * Check if Wails runtime is available
@@ -9,6 +22,13 @@ const isWailsAvailable = (): boolean => {
   return typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App;
 };
 
+/**
+ * The connections file of guest mode, or of a signed-in user
+ */
+const connectionsFile = (isGuest: boolean, userId?: string): StoreFile => {
+  return { store: 'connections', profile: !isGuest && userId ? userId : '' };
+};
+
 /**
  * Get guest encryption keyphrase
  */
@@ -136,29 +156,17 @@ export const loadConnectionsFromFile = async (
   }
 
   try {
-    let connectionsPath: string;
-
-    if (isGuest) {
-      connectionsPath = await GetGuestConnectionsPath();
-    } else if (userId) {
-      connectionsPath = await GetUserConnectionsPath(userId);
-    } else {
-      // Fallback to guest connections
-      connectionsPath = await GetGuestConnectionsPath();
-    }
-
-    const exists = await FileExists(connectionsPath);
-    if (!exists) {
-      return [];
-    }
+    const file = connectionsFile(isGuest, userId);
 
-    const fileContent = await ReadFile(connectionsPath);
+    const fileContent = await readVersionedFile(file);
     if (!fileContent) {
+      baseConnectionIds.set(storeFileKey(file), new Set());
       return [];
     }
 
     const data = JSON.parse(fileContent);
     const connections: SSHConnection[] = data.connections || [];
+    baseConnectionIds.set(storeFileKey(file), new Set(connections.map((conn) => conn.id)));
 
     // Decrypt credentials for guest mode when loading
     if (isGuest) {
@@ -180,12 +188,15 @@ export const loadConnectionsFromFile = async (
 };
 
 /**
- * Save connections to file system
+ * Save connections to file system. If another app instance changed the file
+ * since it was loaded, its additions are merged in and the merged list is
+ * passed to onExternalChanges.
  */
 export const saveConnectionsToFile = async (
   connections: SSHConnection[],
   isGuest: boolean,
-  userId?: string
+  userId?: string,
+  onExternalChanges?: (connections: SSHConnection[]) => void
 ): Promise<boolean> => {
   if (!isWailsAvailable()) {
     // Fallback to localStorage if Wails is not available (dev mode)
@@ -194,38 +205,43 @@ export const saveConnectionsToFile = async (
   }
 
   try {
-    let connectionsPath: string;
-
-    if (isGuest) {
-      connectionsPath = await GetGuestConnectionsPath();
-    } else if (userId) {
-      connectionsPath = await GetUserConnectionsPath(userId);
-    } else {
-      // Fallback to guest connections
-      connectionsPath = await GetGuestConnectionsPath();
-    }
+    const file = connectionsFile(isGuest, userId);
+    const key = storeFileKey(file);
 
     // Encrypt credentials for guest mode before saving
     let connectionsToSave = connections;
-    if (isGuest) {
-      const keyphrase = await getGuestEncryptionKeyphrase();
-      if (keyphrase) {
-        connectionsToSave = await Promise.all(
-          connections.map((conn) => encryptConnectionCredentials(conn, keyphrase))
-        );
-      }
+    const keyphrase = isGuest ? await getGuestEncryptionKeyphrase() : undefined;
+    if (keyphrase) {
+      connectionsToSave = await Promise.all(
+        connections.map((conn) => encryptConnectionCredentials(conn, keyphrase))
+      );
     }
 
-    const connectionsData = JSON.stringify(
-      {
-        connections: connectionsToSave,
-        lastUpdated: Date.now(),
-      },
-      null,
-      2
-    );
+    let saved = connectionsToSave;
+    const merged = await writeVersionedFile(file, (onDisk) => {
+      saved = connectionsToSave;
+      if (onDisk) {
+        const diskConnections: SSHConnection[] = JSON.parse(onDisk).connections || [];
+        saved = mergeById(connectionsToSave, diskConnections, baseConnectionIds.get(key) ?? new Set());
+      }
+      return JSON.stringify(
+        {
+          version: STORE_FILE_VERSIONS.connections,
+          connections: saved,
+          lastUpdated: Date.now(),
+        },
+        null,
+        2
+      );
+    });
+    baseConnectionIds.set(key, new Set(saved.map((conn) => conn.id)));
 
-    await WriteFile(connectionsPath, connectionsData);
+    if (merged && onExternalChanges) {
+      // Connections from disk carry encrypted credentials in guest mode
+      onExternalChanges(
+        keyphrase ? await Promise.all(saved.map((conn) => decryptConnectionCredentials(conn, keyphrase))) : saved
+      );
+    }
     return true;
   } catch (error) {
     console.error('Failed to save connections to file:', error);
@@ -1,6 +1,7 @@
-import { GetGuestConfigPath, GetUserConfigPath, ReadFile, WriteFile, FileExists } from '../../../wailsjs/go/main/App';
+import { ReadStoreFile, WriteStoreFile } from '../../../wailsjs/go/main/App';
 import type { UserConfig } from '../../types/config';
 import { DEFAULT_USER_CONFIG } from '../../types/config';
+import { STORE_FILE_VERSIONS } from './versionedFile';
 
 /**
  * Check if Wails runtime is available
@@ -9,6 +10,13 @@ const isWailsAvailable = (): boolean => {
   return typeof window !== 'undefined' && window.go && window.go.main && window.go.main.App;
 };
 
+/**
+ * Store profile of the config: a signed-in user's ID, or '' for guest mode
+ */
+const configProfile = (isGuest: boolean, userId?: string): string => {
+  return !isGuest && userId ? userId : '';
+};
+
 /**
  * Load config from file system
  */
@@ -19,23 +27,8 @@ export const loadConfigFromFile = async (isGuest: boolean, userId?: string): Pro
   }
 
   try {
-    let configPath: string;
-    
-    if (isGuest) {
-      configPath = await GetGuestConfigPath();
-    } else if (userId) {
-      configPath = await GetUserConfigPath(userId);
-    } else {
-      // Fallback to guest config
-      configPath = await GetGuestConfigPath();
-    }
-
-    const exists = await FileExists(configPath);
-    if (!exists) {
-      return null;
-    }
-
-    const fileContent = await ReadFile(configPath);
+    // A missing file reads as empty
+    const fileContent = await ReadStoreFile('config', configProfile(isGuest, userId), '');
     if (!fileContent) {
       return null;
     }
@@ -59,23 +52,13 @@ export const saveConfigToFile = async (config: Partial<UserConfig>, isGuest: boo
   }
 
   try {
-    let configPath: string;
-    
-    if (isGuest) {
-      configPath = await GetGuestConfigPath();
-    } else if (userId) {
-      configPath = await GetUserConfigPath(userId);
-    } else {
-      // Fallback to guest config
-      configPath = await GetGuestConfigPath();
-    }
-
     const configData = JSON.stringify({
       ...config,
+      version: STORE_FILE_VERSIONS.config,
       lastUpdated: Date.now(),
     }, null, 2);
 
-    await WriteFile(configPath, configData);
+    await WriteStoreFile('config', configProfile(isGuest, userId), '', configData);
     return true;
   } catch (error) {
     console.error('Failed to save config to file:', error);
@@ -1,15 +1,37 @@
 /**
- * SQLite interface for future Go backend integration
- * 
- * This file contains TypeScript type definitions and placeholder functions
- * for SQLite operations that will be handled by the Go backend via Wails bindings.
+ * SQLite database operations, handled by the Go backend via Wails bindings.
+ * Records belong to the current profile: the signed-in user, or guest mode.
  */
 
 import type { SSHConnection, Command, Keychain } from '../../types';
+import {
+  CreateDatabaseBackup,
+  CreateDatabaseCommand,
+  CreateDatabaseConnection,
+  CreateDatabaseKeychain,
+  DeleteDatabaseCommand,
+  DeleteDatabaseConnection,
+  DeleteDatabaseKeychain,
+  GetDatabaseCommand,
+  GetDatabaseConnection,
+  GetDatabaseKeychain,
+  GetDatabasePath,
+  ListDatabaseBackups,
+  ListDatabaseCommands,
+  ListDatabaseConnections,
+  ListDatabaseKeychains,
+  RepairDatabase,
+  RestoreDatabaseBackup,
+  UpdateDatabaseCommand,
+  UpdateDatabaseConnection,
+  UpdateDatabaseKeychain,
+  ValidateDatabase,
+} from '../../../wailsjs/go/main/App';
+import { database } from '../../../wailsjs/go/models';
+import { useAuthStore } from '../../store/authStore';
 
 /**
  * SQLite database operations interface
- * These methods will be called via Wails Go bindings
  */
 export interface SQLiteOperations {
   // Connection operations
@@ -46,100 +68,146 @@ export interface SQLiteOperations {
 }
 
 /**
- * Placeholder SQLite client
- * This will be replaced with actual Wails bindings once Go backend is implemented
+ * Profile the records belong to: a user ID, or '' for guest mode
+ */
+const currentProfile = (): string => {
+  const { isGuestMode, user } = useAuthStore.getState();
+  return isGuestMode || !user?.id ? '' : user.id;
+};
+
+const isNotFoundError = (error: unknown): boolean => {
+  return String(error).includes('record not found');
+};
+
+/**
+ * Resolve to null instead of rejecting when the record doesn't exist
+ */
+const orNull = async <T>(promise: Promise<T>): Promise<T | null> => {
+  try {
+    return await promise;
+  } catch (error) {
+    if (isNotFoundError(error)) {
+      return null;
+    }
+    throw error;
+  }
+};
+
+// The generated model classes carry the same JSON fields as the app types
+const toConnection = (conn: database.Connection): SSHConnection => conn as unknown as SSHConnection;
+const toCommand = (command: database.Command): Command => command as unknown as Command;
+const toKeychain = (keychain: database.Keychain): Keychain => keychain as unknown as Keychain;
+
+/**
+ * SQLite client backed by the Go database. Updates merge the changes into
+ * the stored record and fail if it changed since it was read.
  */
 class SQLiteClient implements SQLiteOperations {
   async getConnections(): Promise<SSHConnection[]> {
-    // TODO: Implement via Wails Go bindings
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return (await ListDatabaseConnections(currentProfile())).map(toConnection);
   }
 
   async getConnection(id: string): Promise<SSHConnection | null> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const conn = await orNull(GetDatabaseConnection(currentProfile(), id));
+    return conn && toConnection(conn);
   }
 
   async createConnection(connection: Omit<SSHConnection, 'id' | 'createdAt' | 'updatedAt'>): Promise<SSHConnection> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const created = await CreateDatabaseConnection(currentProfile(), database.Connection.createFrom(connection));
+    return toConnection(created);
   }
 
   async updateConnection(id: string, updates: Partial<SSHConnection>): Promise<SSHConnection> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const profile = currentProfile();
+    const current = await GetDatabaseConnection(profile, id);
+    const merged = database.Connection.createFrom({ ...current, ...updates, id, version: updates.version ?? current.version });
+    return toConnection(await UpdateDatabaseConnection(profile, merged));
   }
 
   async deleteConnection(id: string): Promise<void> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    await DeleteDatabaseConnection(currentProfile(), id);
   }
 
   async getCommands(): Promise<Command[]> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return (await ListDatabaseCommands(currentProfile())).map(toCommand);
   }
 
   async getCommand(id: string): Promise<Command | null> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const command = await orNull(GetDatabaseCommand(currentProfile(), id));
+    return command && toCommand(command);
   }
 
   async createCommand(command: Omit<Command, 'id' | 'createdAt' | 'updatedAt'>): Promise<Command> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const created = await CreateDatabaseCommand(currentProfile(), database.Command.createFrom(command));
+    return toCommand(created);
   }
 
   async updateCommand(id: string, updates: Partial<Command>): Promise<Command> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const profile = currentProfile();
+    const current = await GetDatabaseCommand(profile, id);
+    const merged = database.Command.createFrom({ ...current, ...updates, id, version: updates.version ?? current.version });
+    return toCommand(await UpdateDatabaseCommand(profile, merged));
   }
 
   async deleteCommand(id: string): Promise<void> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    await DeleteDatabaseCommand(currentProfile(), id);
   }
 
   async getKeychains(): Promise<Keychain[]> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return (await ListDatabaseKeychains(currentProfile())).map(toKeychain);
   }
 
   async getKeychain(id: string): Promise<Keychain | null> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const keychain = await orNull(GetDatabaseKeychain(currentProfile(), id));
+    return keychain && toKeychain(keychain);
   }
 
   async createKeychain(keychain: Omit<Keychain, 'id' | 'createdAt' | 'updatedAt'>): Promise<Keychain> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const created = await CreateDatabaseKeychain(currentProfile(), database.Keychain.createFrom(keychain));
+    return toKeychain(created);
   }
 
   async updateKeychain(id: string, updates: Partial<Keychain>): Promise<Keychain> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    const profile = currentProfile();
+    const current = await GetDatabaseKeychain(profile, id);
+    const merged = database.Keychain.createFrom({ ...current, ...updates, id, version: updates.version ?? current.version });
+    return toKeychain(await UpdateDatabaseKeychain(profile, merged));
   }
 
   async deleteKeychain(id: string): Promise<void> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    await DeleteDatabaseKeychain(currentProfile(), id);
   }
 
   async createBackup(): Promise<string> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return await CreateDatabaseBackup();
   }
 
   async restoreBackup(backupPath: string): Promise<void> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    // Backups are named by ListDatabaseBackups; only the file name is used
+    await RestoreDatabaseBackup(backupPath.split(/[\\/]/).pop() ?? backupPath);
   }
 
   async listBackups(): Promise<string[]> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return await ListDatabaseBackups();
   }
 
   async initializeDatabase(): Promise<void> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    // The backend opens the database and imports the JSON files at startup
+    await GetDatabasePath();
   }
 
   async validateDatabase(): Promise<boolean> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return (await ValidateDatabase()).length === 0;
   }
 
   async repairDatabase(): Promise<boolean> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return (await RepairDatabase()).length === 0;
   }
 
   async getDatabasePath(): Promise<string> {
-    throw new Error('SQLite operations not yet implemented. Awaiting Go backend integration.');
+    return await GetDatabasePath();
   }
 }
 
 // Export singleton instance
 export const sqliteClient = new SQLiteClient();
-
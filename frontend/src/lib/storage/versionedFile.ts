@@ -0,0 +1,95 @@
+import { GetStoreFileVersion, ReadStoreFile, WriteStoreFileIfUnchanged } from '../../../wailsjs/go/main/App';
+
+/**
+ * A file in one of the backend's named stores. profile is a user ID, or ''
+ * for guest mode; name is only used by the workspaces and backups stores.
+ */
+export interface StoreFile {
+  store: 'config' | 'connections' | 'commands' | 'workspaces' | 'backups';
+  profile: string;
+  name?: string;
+}
+
+/**
+ * Format version written to each versioned store file. The backend migrates
+ * older files at startup, so these must match its latest migrations.
+ */
+export const STORE_FILE_VERSIONS = {
+  config: 1,
+  connections: 1,
+  commands: 1,
+} as const;
+
+/**
+ * Key identifying a store file in maps
+ */
+export const storeFileKey = (file: StoreFile): string => {
+  return `${file.store}/${file.profile}/${file.name ?? ''}`;
+};
+
+/**
+ * Version of each file as last read or written by this window. Another app
+ * instance writing the file changes its version on disk.
+ */
+const versions = new Map<string, string>();
+
+const MAX_WRITE_ATTEMPTS = 3;
+
+const isFileChangedError = (error: unknown): boolean => {
+  return String(error).includes('file changed on disk');
+};
+
+/**
+ * Read a file and remember its version. Returns null if it doesn't exist.
+ */
+export const readVersionedFile = async (file: StoreFile): Promise<string | null> => {
+  const { store, profile, name = '' } = file;
+  // Taken before reading so a write in between is caught on the next save
+  const version = await GetStoreFileVersion(store, profile, name);
+  versions.set(storeFileKey(file), version);
+  if (!version) {
+    return null;
+  }
+  return await ReadStoreFile(store, profile, name);
+};
+
+/**
+ * Write a file only if nobody else changed it since it was last read here.
+ * render is first called with null; if the file changed meanwhile it is
+ * called again with the current contents so the caller can merge them in.
+ * Returns true when such a merge happened.
+ */
+export const writeVersionedFile = async (
+  file: StoreFile,
+  render: (onDisk: string | null) => string
+): Promise<boolean> => {
+  const { store, profile, name = '' } = file;
+  const key = storeFileKey(file);
+  if (!versions.has(key)) {
+    versions.set(key, await GetStoreFileVersion(store, profile, name));
+  }
+
+  let data = render(null);
+  for (let attempt = 1; ; attempt++) {
+    try {
+      versions.set(key, await WriteStoreFileIfUnchanged(store, profile, name, data, versions.get(key) ?? ''));
+      return attempt > 1;
+    } catch (error) {
+      if (!isFileChangedError(error) || attempt >= MAX_WRITE_ATTEMPTS) {
+        throw error;
+      }
+      data = render(await readVersionedFile(file));
+    }
+  }
+};
+
+/**
+ * Merge records by id after a concurrent write: records added on disk by
+ * another instance are kept, records deleted here (known in baseIds but
+ * missing locally) stay deleted.
+ */
+export const mergeById = <T extends { id: string }>(local: T[], onDisk: T[], baseIds: Set<string>): T[] => {
+  const localIds = new Set(local.map((item) => item.id));
+  const added = onDisk.filter((item) => !localIds.has(item.id) && !baseIds.has(item.id));
+  return [...local, ...added];
+};
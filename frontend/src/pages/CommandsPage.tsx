@@ -25,7 +25,7 @@ import { ROUTES } from '../lib/constants';
 import { useTerminalStore } from '../store/terminalStore';
 import { getActiveSessionId } from '../lib/terminalUtils';
 
-import { ShowSaveFileDialog, ShowOpenFileDialog, WriteFile, ReadFile, WriteToTerminal } from '../../wailsjs/go/main/App';
+import { ExportFileWithDialog, ImportFileWithDialog, WriteToTerminal } from '../../wailsjs/go/main/App';
 const isWailsAvailable = (): boolean => {
   return typeof window !== 'undefined' && window.go?.main?.App;
 };
@@ -116,10 +116,7 @@ export const CommandsPage: React.FC = () => {
   const handleExport = async () => {
     const data = exportSnippets();
     try {
-      const filePath = await ShowSaveFileDialog('Export Commands', 'commands.json', 'json');
-      if (filePath) {
-        await WriteFile(filePath, data);
-      }
+      await ExportFileWithDialog('Export Commands', 'commands.json', 'json', data);
     } catch (error) {
       console.error('Export failed:', error);
       // Fallback to browser download
@@ -135,9 +132,8 @@ export const CommandsPage: React.FC = () => {
 
   const handleImport = async () => {
     try {
-      const filePath = await ShowOpenFileDialog('Import Commands', 'json');
-      if (filePath) {
-        const content = await ReadFile(filePath);
+      const content = await ImportFileWithDialog('Import Commands', 'json');
+      if (content) {
         await importSnippets(content);
       }
     } catch (error) {
@@ -18,15 +18,15 @@ import { useTerminalStore } from '../store/terminalStore';
 import { ConnectionModal, ConnectionFormData } from '../components/connections/ConnectionModal';
 import { QuickConnectModal } from '../components/connections/QuickConnectModal';
 import { PasswordPromptModal } from '../components/connections/PasswordPromptModal';
-import { HostKeyVerificationModal } from '../components/connections/HostKeyVerificationModal';
 import { ConnectionFlowModal } from '../components/connections/ConnectionFlowModal';
 import type { AuthMethod } from '../components/connections/ConnectionFlowModal';
 import { ConnectionCard } from '../components/connections/ConnectionCard';
 import { DeleteConfirmModal } from '../components/connections/DeleteConfirmModal';
 import type { SSHConnection } from '../types';
+import { HostKeyPolicy } from '../types/terminal';
 import { encryptPassword, encryptPrivateKey, decryptPassword, decryptPrivateKey, encryptDataWithKeyphrase, decryptDataWithKeyphrase } from '../lib/encryption/crypto';
 import { useAuthStore } from '../store/authStore';
-import { ShowSaveFileDialog, WriteFile } from '../../wailsjs/go/main/App';
+import { ExportFileWithDialog, RecordAuditEvent } from '../../wailsjs/go/main/App';
 import { loadConnectionsFromFile, saveConnectionsToFile } from '../lib/storage/connectionStorage';
 
 // Access Wails function to get guest encryption keyphrase
@@ -56,24 +56,6 @@ const tryParseJSON = (str: string): any => {
   }
 };
 
-// Access Wails functions through window object to avoid TypeScript import errors
-// These functions will be available after bindings are regenerated with 'wails dev' or 'wails build'
-const getSSHHostKeyInfo = (host: string, port: number): Promise<any> | undefined => {
-  const wailsApp = (window as any)?.go?.main?.App;
-  if (wailsApp?.GetSSHHostKeyInfo) {
-    return wailsApp.GetSSHHostKeyInfo(host, port);
-  }
-  return undefined;
-};
-
-const acceptSSHHostKey = (host: string, port: number, keyBase64: string, isGuest: boolean): Promise<void> | undefined => {
-  const wailsApp = (window as any)?.go?.main?.App;
-  if (wailsApp?.AcceptSSHHostKey) {
-    return wailsApp.AcceptSSHHostKey(host, port, keyBase64, isGuest);
-  }
-  return undefined;
-};
-
 export const ConnectionsPage: React.FC = () => {
   const navigate = useNavigate();
   const {
@@ -96,15 +78,9 @@ export const ConnectionsPage: React.FC = () => {
   const [editingConnection, setEditingConnection] = useState<SSHConnection | null>(null);
   const [showQuickConnect, setShowQuickConnect] = useState(false);
   const [showPasswordPrompt, setShowPasswordPrompt] = useState(false);
-  const [showHostKeyVerification, setShowHostKeyVerification] = useState(false);
   const [showConnectionFlow, setShowConnectionFlow] = useState(false);
   const [showDeleteConfirm, setShowDeleteConfirm] = useState(false);
   const [pendingDeleteIds, setPendingDeleteIds] = useState<string[]>([]);
-  const [hostKeyVerificationInfo, setHostKeyVerificationInfo] = useState<{
-    host: string;
-    port: number;
-    connection: SSHConnection;
-  } | null>(null);
   const [flowConnection, setFlowConnection] = useState<SSHConnection | null>(null);
   const [isConnecting, setIsConnecting] = useState(false);
   const [pendingConnection, setPendingConnection] = useState<{
@@ -303,7 +279,8 @@ export const ConnectionsPage: React.FC = () => {
   const saveToStorage = async (conns: SSHConnection[]) => {
     try {
       const userId = user?.id;
-      await saveConnectionsToFile(conns, isGuestMode, userId);
+      // Connections another window added meanwhile are merged in
+      await saveConnectionsToFile(conns, isGuestMode, userId, setConnections);
     } catch (error) {
       console.error('Failed to save connections to file:', error);
       // Fallback to localStorage
@@ -643,30 +620,7 @@ export const ConnectionsPage: React.FC = () => {
     });
 
     try {
-      // Check host key first
-      const hostKeyInfoResult = getSSHHostKeyInfo(conn.host, conn.port);
-      if (hostKeyInfoResult) {
-        try {
-          const hostKeyInfo = await hostKeyInfoResult;
-
-          // If host is not known or there's a mismatch, show verification modal
-          if (!hostKeyInfo.isKnown || hostKeyInfo.isMismatch) {
-            setHostKeyVerificationInfo({
-              host: conn.host,
-              port: conn.port,
-              connection: conn,
-            });
-            setShowHostKeyVerification(true);
-            return; // Wait for user to accept/reject
-          }
-        } catch (error) {
-          // If we can't get host key info, still try to connect
-          // (the backend will handle the verification)
-          console.warn('Failed to get host key info, proceeding with connection:', error);
-        }
-      }
-
-      // Host is verified, show connection flow modal
+      // Unknown host keys are asked about during the handshake itself
       // Get the latest connection from the store to ensure we have all fields including encrypted credentials
       const latestConnection = connections.find(c => c.id === conn.id) || conn;
       console.log('[CONN] Setting flow connection:', {
@@ -800,32 +754,16 @@ export const ConnectionsPage: React.FC = () => {
         authMethod,
       });
 
-      // Check host key before connecting
-      const hostKeyInfoResult = getSSHHostKeyInfo(flowConnection.host, flowConnection.port);
-      if (hostKeyInfoResult) {
-        try {
-          const hostKeyInfo = await hostKeyInfoResult;
-          
-          // If host is not known or there's a mismatch, show verification modal
-          if (!hostKeyInfo.isKnown || hostKeyInfo.isMismatch) {
-            setHostKeyVerificationInfo({
-              host: flowConnection.host,
-              port: flowConnection.port,
-              connection: flowConnection,
-            });
-            setShowHostKeyVerification(true);
-            setIsConnecting(false);
-            setShowConnectionFlow(false);
-            return; // Wait for user to accept/reject
-          }
-        } catch (error) {
-          // If we can't get host key info, still try to connect
-          // (the backend will handle the verification)
-          console.warn('Failed to get host key info, proceeding with connection:', error);
-        }
-      }
-
-      await createSSHTerminal(flowConnection.host, flowConnection.port, flowConnection.username, password, privateKey, flowConnection.name);
+      // With "ask" the backend prompts for unknown host keys mid-handshake
+      await createSSHTerminal(
+        flowConnection.host,
+        flowConnection.port,
+        flowConnection.username,
+        password,
+        privateKey,
+        flowConnection.name,
+        HostKeyPolicy.Ask
+      );
 
       // Save credentials if provided
       if (authMethod === 'password' && credentials.password) {
@@ -910,25 +848,6 @@ export const ConnectionsPage: React.FC = () => {
       console.error('Failed to connect:', error);
       setIsConnecting(false);
       const errorMessage = error instanceof Error ? error.message : String(error);
-      
-      // Check if error is due to host key not known
-      if (errorMessage.includes('host key not known')) {
-        // Show host key verification modal
-        setHostKeyVerificationInfo({
-          host: flowConnection.host,
-          port: flowConnection.port,
-          connection: flowConnection,
-        });
-        setShowHostKeyVerification(true);
-        setShowConnectionFlow(false);
-        setConnectingState({
-          connectionId: flowConnection.id,
-          error: null,
-          isRetrying: false,
-        });
-        return;
-      }
-      
       setConnectingState({
         connectionId: flowConnection.id,
         error: errorMessage,
@@ -948,64 +867,6 @@ export const ConnectionsPage: React.FC = () => {
     });
   };
 
-  const handleHostKeyAccept = async () => {
-    if (!hostKeyVerificationInfo) {
-      return;
-    }
-
-    try {
-      // Get the host key info again to get the keyBase64
-      const hostKeyInfoResult = getSSHHostKeyInfo(hostKeyVerificationInfo.host, hostKeyVerificationInfo.port);
-      if (!hostKeyInfoResult) {
-        throw new Error('Wails bindings not available. Please run "wails dev" or "wails build"');
-      }
-      const hostKeyInfo = await hostKeyInfoResult;
-
-      // Accept the host key (pass isGuestMode to determine if key should be persisted)
-      const acceptResult = acceptSSHHostKey(hostKeyVerificationInfo.host, hostKeyVerificationInfo.port, hostKeyInfo.keyBase64, isGuestMode);
-      if (!acceptResult) {
-        throw new Error('Wails bindings not available. Please run "wails dev" or "wails build"');
-      }
-      await acceptResult;
-
-      // Close host key modal and show connection flow
-      // Get the latest connection from the store to ensure we have all fields including encrypted credentials
-      const latestConnection = connections.find(c => c.id === hostKeyVerificationInfo.connection.id) || hostKeyVerificationInfo.connection;
-      console.log('[CONN] Setting flow connection after host key accept:', {
-        id: latestConnection.id,
-        host: latestConnection.host,
-        hasPasswordEncrypted: !!latestConnection.passwordEncrypted,
-        hasPrivateKeyEncrypted: !!latestConnection.privateKeyEncrypted,
-        passwordEncryptedLength: latestConnection.passwordEncrypted?.length || 0,
-      });
-      setShowHostKeyVerification(false);
-      setFlowConnection(latestConnection);
-      setShowConnectionFlow(true);
-      setHostKeyVerificationInfo(null);
-    } catch (error) {
-      console.error('Failed to accept host key:', error);
-      setConnectingState({
-        connectionId: hostKeyVerificationInfo.connection.id,
-        error: error instanceof Error ? error.message : 'Failed to accept host key',
-        isRetrying: false,
-      });
-      setShowHostKeyVerification(false);
-      setHostKeyVerificationInfo(null);
-    }
-  };
-
-  const handleHostKeyReject = () => {
-    setShowHostKeyVerification(false);
-    setHostKeyVerificationInfo(null);
-    if (hostKeyVerificationInfo) {
-      setConnectingState({
-        connectionId: hostKeyVerificationInfo.connection.id,
-        error: null,
-        isRetrying: false,
-      });
-    }
-  };
-
   const handleDismissError = () => {
     setConnectingState({
       connectionId: null,
@@ -1051,7 +912,9 @@ export const ConnectionsPage: React.FC = () => {
       pendingConnection.port,
       pendingConnection.username,
       password,
-      ''
+      '',
+      '',
+      HostKeyPolicy.Ask
     )
       .then(() => {
         setIsConnecting(false);
@@ -1109,9 +972,10 @@ export const ConnectionsPage: React.FC = () => {
         ? `ssh-connections-selected-${selectedConnectionIds.size}.json`
         : 'ssh-connections.json';
 
-      const filePath = await ShowSaveFileDialog('Export SSH Connections', defaultFilename, 'json');
-      if (filePath) {
-        await WriteFile(filePath, data);
+      const exported = await ExportFileWithDialog('Export SSH Connections', defaultFilename, 'json', data);
+      if (exported) {
+        RecordAuditEvent('credential.exported', `${connectionsToExport.length} connection(s)`)
+          .catch((error) => console.error('Failed to record audit event:', error));
       }
     } catch (error) {
       console.error('Export failed:', error);
@@ -1473,18 +1337,6 @@ export const ConnectionsPage: React.FC = () => {
         }}
       />
 
-      {/* Host Key Verification Modal */}
-      {hostKeyVerificationInfo && (
-        <HostKeyVerificationModal
-          isOpen={showHostKeyVerification}
-          host={hostKeyVerificationInfo.host}
-          port={hostKeyVerificationInfo.port}
-          isGuest={isGuestMode}
-          onAccept={handleHostKeyAccept}
-          onReject={handleHostKeyReject}
-        />
-      )}
-
       {/* Connection Flow Modal */}
       <ConnectionFlowModal
         isOpen={showConnectionFlow}
@@ -1493,7 +1345,7 @@ export const ConnectionsPage: React.FC = () => {
         onConnect={handleConnectionFlowConnect}
         isConnecting={isConnecting}
         error={connectingState.error}
-        hostVerified={true} // Host is already verified if we got here
+        hostVerified={true} // Host keys are verified during the handshake
       />
 
       {pendingConnection && (
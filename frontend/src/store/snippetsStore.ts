@@ -1,11 +1,12 @@
 import { create } from 'zustand';
 import {
-  GetGuestSnippetsPath,
-  GetUserSnippetsPath,
-  ReadFile,
-  WriteFile,
-  FileExists,
-} from '../../wailsjs/go/main/App';
+  readVersionedFile,
+  writeVersionedFile,
+  mergeById,
+  storeFileKey,
+  STORE_FILE_VERSIONS,
+  type StoreFile,
+} from '../lib/storage/versionedFile';
 import { useAuthStore } from './authStore';
 
 export interface Snippet {
@@ -18,6 +19,7 @@ export interface Snippet {
 }
 
 interface SnippetsData {
+  version?: number;
   snippets: Snippet[];
   lastUpdated: number;
 }
@@ -38,25 +40,28 @@ const isWailsAvailable = (): boolean => {
   return typeof window !== 'undefined' && window.go?.main?.App;
 };
 
-const getSnippetsPath = async (): Promise<string> => {
+const getSnippetsFile = (): StoreFile => {
   const { isGuestMode, user } = useAuthStore.getState();
-  if (isGuestMode || !user?.id) {
-    return GetGuestSnippetsPath();
-  }
-  return GetUserSnippetsPath(user.id);
+  return { store: 'commands', profile: isGuestMode || !user?.id ? '' : user.id };
 };
 
+// IDs of the snippets last read or written, to tell deletions made here
+// from snippets another instance added
+const baseSnippetIds = new Map<string, Set<string>>();
+
 const loadFromFile = async (): Promise<Snippet[]> => {
   if (!isWailsAvailable()) {
     return loadFromLocalStorage();
   }
   try {
-    const path = await getSnippetsPath();
-    const exists = await FileExists(path);
-    if (!exists) return [];
-    const content = await ReadFile(path);
-    if (!content) return [];
+    const file = getSnippetsFile();
+    const content = await readVersionedFile(file);
+    if (!content) {
+      baseSnippetIds.set(storeFileKey(file), new Set());
+      return [];
+    }
     const data: SnippetsData = JSON.parse(content);
+    baseSnippetIds.set(storeFileKey(file), new Set((data.snippets || []).map((s) => s.id)));
     // Ensure all snippets have order, migrate old data
     const snippets = (data.snippets || []).map((s, i) => ({
       ...s,
@@ -69,18 +74,40 @@ const loadFromFile = async (): Promise<Snippet[]> => {
   }
 };
 
-const saveToFile = async (snippets: Snippet[]): Promise<void> => {
+// saveToFile returns the merged list when another instance added snippets
+// since they were loaded, null otherwise
+const saveToFile = async (snippets: Snippet[]): Promise<Snippet[] | null> => {
   if (!isWailsAvailable()) {
     saveToLocalStorage(snippets);
-    return;
+    return null;
   }
   try {
-    const path = await getSnippetsPath();
-    const data: SnippetsData = { snippets, lastUpdated: Date.now() };
-    await WriteFile(path, JSON.stringify(data, null, 2));
+    const file = getSnippetsFile();
+    const key = storeFileKey(file);
+    let saved = snippets;
+    const merged = await writeVersionedFile(file, (onDisk) => {
+      saved = snippets;
+      if (onDisk) {
+        const diskSnippets: Snippet[] = JSON.parse(onDisk).snippets || [];
+        saved = mergeById(snippets, diskSnippets, baseSnippetIds.get(key) ?? new Set());
+      }
+      const data: SnippetsData = { version: STORE_FILE_VERSIONS.commands, snippets: saved, lastUpdated: Date.now() };
+      return JSON.stringify(data, null, 2);
+    });
+    baseSnippetIds.set(key, new Set(saved.map((s) => s.id)));
+    return merged ? saved : null;
   } catch (error) {
     console.error('Failed to save snippets to file:', error);
     saveToLocalStorage(snippets);
+    return null;
+  }
+};
+
+// persist saves snippets and picks up the ones other windows added meanwhile
+const persist = async (snippets: Snippet[]): Promise<void> => {
+  const merged = await saveToFile(snippets);
+  if (merged) {
+    useSnippetsStore.setState({ snippets: [...merged].sort((a, b) => a.order - b.order) });
   }
 };
 
@@ -134,13 +161,13 @@ export const useSnippetsStore = create<SnippetsState>()((set, get) => ({
     };
     const updated = [...currentSnippets, newSnippet];
     set({ snippets: updated });
-    await saveToFile(updated);
+    await persist(updated);
   },
 
   updateSnippet: async (id, updates) => {
     const updated = get().snippets.map((s) => (s.id === id ? { ...s, ...updates } : s));
     set({ snippets: updated });
-    await saveToFile(updated);
+    await persist(updated);
   },
 
   deleteSnippet: async (id) => {
@@ -148,7 +175,7 @@ export const useSnippetsStore = create<SnippetsState>()((set, get) => ({
     // Reindex order after deletion
     const reindexed = updated.map((s, i) => ({ ...s, order: i }));
     set({ snippets: reindexed });
-    await saveToFile(reindexed);
+    await persist(reindexed);
   },
 
   reorderSnippets: async (activeId, overId) => {
@@ -164,7 +191,7 @@ export const useSnippetsStore = create<SnippetsState>()((set, get) => ({
     // Update order values
     const reordered = snippets.map((s, i) => ({ ...s, order: i }));
     set({ snippets: reordered });
-    await saveToFile(reordered);
+    await persist(reordered);
   },
 
   exportSnippets: () => {
@@ -183,7 +210,7 @@ export const useSnippetsStore = create<SnippetsState>()((set, get) => ({
         }
       }
       set({ snippets: merged });
-      await saveToFile(merged);
+      await persist(merged);
       return true;
     } catch {
       return false;
@@ -11,6 +11,9 @@ import {
   SessionMetadata,
   SessionState,
   TerminalClosedEvent,
+  TerminalCwdEvent,
+  HostKeyPolicy,
+  HostKeyPromptEvent,
 } from "../types/terminal";
 import { Workspace, SerializedSession } from "../types/workspace";
 import {
@@ -26,13 +29,18 @@ import {
 import {
   CreateLocalTerminal,
   CreateSSHTerminal,
+  CreateSSHTerminalForConnection,
   DuplicateTerminal,
   CloseTerminal,
   GetTerminalMetadata,
+  GetVaultConnection,
   ReconnectTerminal,
+  ReconnectTerminalForConnection,
+  RespondSSHHostKeyPrompt,
 } from "../../wailsjs/go/main/App";
 import { EventsOn } from "../../wailsjs/runtime/runtime";
 import { destroyTerminalInstance } from "../components/terminal/Terminal";
+import { useAuthStore } from "./authStore";
 
 // Check if Wails bindings are available
 const isWailsAvailable = (): boolean => {
@@ -136,6 +144,10 @@ interface TerminalStore {
   activePaneId: string | null; // Track focused pane globally
   connectingSessionId: string | null;
   workspaces: Workspace[]; // Saved workspaces
+  hostKeyPrompts: HostKeyPromptEvent[]; // Handshakes waiting on the user, oldest first
+
+  // Answer a host key prompt raised during a handshake
+  respondHostKeyPrompt: (promptId: string, accept: boolean) => Promise<void>;
 
   // Session management
   addSession: (session: TerminalSession) => void;
@@ -187,8 +199,10 @@ interface TerminalStore {
     username: string,
     password: string,
     privateKey?: string,
-    name?: string
+    name?: string,
+    hostKeyPolicy?: HostKeyPolicy
   ) => Promise<string>;
+  createVaultSSHTerminal: (connectionId: string, name?: string) => Promise<string>;
   createQuickSSHTerminal: (
     username: string,
     host: string,
@@ -202,7 +216,8 @@ interface TerminalStore {
     port: number,
     username: string,
     password: string,
-    privateKey?: string
+    privateKey?: string,
+    hostKeyPolicy?: HostKeyPolicy
   ) => Promise<void>;
   setConnecting: (sessionId: string | null) => void;
 
@@ -225,6 +240,19 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
   activePaneId: null,
   connectingSessionId: null,
   workspaces: loadWorkspacesFromStorage(),
+  hostKeyPrompts: [],
+
+  respondHostKeyPrompt: async (promptId, accept) => {
+    set((state) => ({
+      hostKeyPrompts: state.hostKeyPrompts.filter((p) => p.PromptID !== promptId),
+    }));
+    try {
+      await RespondSSHHostKeyPrompt(promptId, accept);
+    } catch (error) {
+      // The handshake gave up waiting meanwhile
+      console.warn("[TERM] Host key prompt already closed:", error);
+    }
+  },
 
   // Session management
   addSession: (session) => {
@@ -417,14 +445,17 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
              // ... logic similar to before ...
              // Simplified for brevity:
              const newTitle = originalSession.title + " (Copy)";
-             if (originalSession.type === SessionType.SSH && originalSession.metadata.sshConfig) {
+             if (originalSession.type === SessionType.SSH && originalSession.metadata.sshConfig?.connectionId) {
+                 await get().createVaultSSHTerminal(originalSession.metadata.sshConfig.connectionId, newTitle);
+             } else if (originalSession.type === SessionType.SSH && originalSession.metadata.sshConfig) {
                  await get().createSSHTerminal(
                      originalSession.metadata.sshConfig.host,
                      originalSession.metadata.sshConfig.port,
                      originalSession.metadata.sshConfig.username,
                      originalSession.metadata.sshConfig.password,
                      originalSession.metadata.sshConfig.privateKey,
-                     newTitle
+                     newTitle,
+                     originalSession.metadata.sshConfig.hostKeyPolicy
                  );
              } else {
                  const newSessionId = await DuplicateTerminal(originalSession.id);
@@ -771,7 +802,8 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
     username,
     password,
     privateKey = "",
-    name = ""
+    name = "",
+    hostKeyPolicy = HostKeyPolicy.Strict
   ) => {
     if (!isWailsAvailable()) {
       throw new Error(
@@ -787,7 +819,9 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
         port,
         username,
         password,
-        privateKey
+        privateKey,
+        hostKeyPolicy,
+        useAuthStore.getState().isGuestMode
       );
 
       const session: TerminalSession = {
@@ -805,6 +839,7 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
             username,
             password,
             privateKey: privateKey || undefined,
+            hostKeyPolicy,
           },
         },
         title: name || `SSH: ${username}@${host}`,
@@ -830,6 +865,65 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
     }
   },
 
+  createVaultSSHTerminal: async (connectionId, name = "") => {
+    if (!isWailsAvailable()) {
+      throw new Error(
+        "Wails backend not available. Please restart the application."
+      );
+    }
+    const tempId = `connecting-${uuid()}`;
+    set({ connectingSessionId: tempId });
+
+    try {
+      // Only the connection's public details come back; Go resolves the
+      // credentials itself
+      const connection = await GetVaultConnection(connectionId);
+      const sessionId = await CreateSSHTerminalForConnection(
+        connectionId,
+        useAuthStore.getState().isGuestMode
+      );
+
+      const session: TerminalSession = {
+        id: sessionId,
+        type: SessionType.SSH,
+        metadata: {
+          workingDirectory: "",
+          shell: "remote-shell",
+          environment: {},
+          createdAt: new Date().toISOString(),
+          state: SessionState.Active,
+          sshConfig: {
+            host: connection.host,
+            port: connection.port,
+            username: connection.username,
+            password: "",
+            hostKeyPolicy: (connection.hostKeyPolicy as HostKeyPolicy) || undefined,
+            connectionId,
+          },
+        },
+        title: name || connection.name || `SSH: ${connection.username}@${connection.host}`,
+      };
+
+      get().addSession(session);
+
+      const paneId = uuid();
+      const tab: TerminalTab = {
+        id: uuid(),
+        layout: { id: paneId, sessionId } as TerminalPane,
+        title: session.title,
+      };
+
+      get().addTab(tab);
+      set({ connectingSessionId: null });
+
+      return sessionId;
+    } catch (error) {
+      set({ connectingSessionId: null });
+      console.error("[TERM] Failed to create SSH terminal for saved connection:", error);
+      throw error;
+    }
+  },
+
   createQuickSSHTerminal: async (username, host, port, password = "") => {
     if (!isWailsAvailable()) {
       throw new Error(
@@ -843,7 +937,9 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
         port,
         username,
         password,
-        ""
+        "",
+        HostKeyPolicy.Strict,
+        useAuthStore.getState().isGuestMode
       );
 
       const session: TerminalSession = {
@@ -939,7 +1035,8 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
     port,
     username,
     password,
-    privateKey = ""
+    privateKey = "",
+    hostKeyPolicy = HostKeyPolicy.Strict
   ) => {
     if (!isWailsAvailable()) {
       throw new Error(
@@ -947,13 +1044,22 @@ export const useTerminalStore = create<TerminalStore>((set, get) => ({
       );
     }
     try {
+      const connectionId = get().sessions.get(sessionId)?.metadata.sshConfig?.connectionId;
+      if (connectionId) {
+        // Vault connections are reconnected with their stored credentials
+        await ReconnectTerminalForConnection(sessionId, useAuthStore.getState().isGuestMode);
+        get().updateSessionState(sessionId, SessionState.Active);
+        return;
+      }
       await ReconnectTerminal(
         sessionId,
         host,
         port,
         username,
         password,
-        privateKey
+        privateKey,
+        hostKeyPolicy,
+        useAuthStore.getState().isGuestMode
       );
       get().updateSessionState(sessionId, SessionState.Active);
     } catch (error) {
@@ -1165,6 +1271,34 @@ if (typeof window !== "undefined") {
           destroyTerminalInstance(event.SessionID);
       }
     });
+
+    // Host key decisions for policy "ask" are made inline during the handshake
+    EventsOn("terminal:host-key-prompt", (event: HostKeyPromptEvent) => {
+      useTerminalStore.setState((state) => ({
+        hostKeyPrompts: [...state.hostKeyPrompts, event],
+      }));
+    });
+
+    EventsOn("terminal:host-key-prompt-expired", (event: HostKeyPromptEvent) => {
+      useTerminalStore.setState((state) => ({
+        hostKeyPrompts: state.hostKeyPrompts.filter((p) => p.PromptID !== event.PromptID),
+      }));
+    });
+
+    // Keep the session's working directory in sync with the shell
+    EventsOn("terminal:cwd-changed", (event: TerminalCwdEvent) => {
+      useTerminalStore.setState((state) => {
+        const session = state.sessions.get(event.SessionID);
+        if (!session) return state;
+
+        const sessions = new Map(state.sessions);
+        sessions.set(event.SessionID, {
+          ...session,
+          metadata: { ...session.metadata, workingDirectory: event.WorkingDirectory },
+        });
+        return { sessions };
+      });
+    });
   };
   
   if (document.readyState === "loading") {
@@ -1198,14 +1332,17 @@ async function createSessionFromConfig(
   config: SerializedSession,
   store: ReturnType<typeof useTerminalStore.getState>
 ): Promise<string> {
-  if (config.type === SessionType.SSH && config.sshConfig) {
+  if (config.type === SessionType.SSH && config.sshConfig?.connectionId) {
+    return await store.createVaultSSHTerminal(config.sshConfig.connectionId, config.title);
+  } else if (config.type === SessionType.SSH && config.sshConfig) {
     return await store.createSSHTerminal(
       config.sshConfig.host,
       config.sshConfig.port,
       config.sshConfig.username,
       config.sshConfig.password,
       config.sshConfig.privateKey,
-      config.title
+      config.title,
+      config.sshConfig.hostKeyPolicy
     );
   } else {
     return await store.createLocalTerminal(
@@ -24,9 +24,19 @@ export interface SessionMetadata {
     username: string;
     password: string;
     privateKey?: string;
+    hostKeyPolicy?: HostKeyPolicy;
+    connectionId?: string; // Saved in the vault; credentials are resolved in Go
   };
 }
 
+// Mirrors OpenSSH's StrictHostKeyChecking for one connection
+export enum HostKeyPolicy {
+  Strict = 'strict',
+  AcceptNew = 'accept-new',
+  Ask = 'ask',
+  Off = 'off'
+}
+
 export interface TerminalSession {
   id: string;
   type: SessionType;
@@ -62,6 +72,9 @@ export type LayoutNode = TerminalPane | SplitPane;
 export interface TerminalOutputEvent {
   SessionID: string;
   Data: string;
+  // 'base64' when Data carries the raw byte stream (see SetTerminalOutputFraming)
+  Encoding?: 'base64';
+  Seq: number;
 }
 
 export interface TerminalErrorEvent {
@@ -81,6 +94,56 @@ export interface TerminalReconnectNeededEvent {
   SessionID: string;
 }
 
+// Payload of terminal:cwd-changed (OSC 7 or /proc tracking)
+export interface TerminalCwdEvent {
+  SessionID: string;
+  WorkingDirectory: string;
+}
+
+// Command detected via OSC 133 shell integration; offsets are byte
+// positions in the session's output stream
+export interface CommandRecord {
+  id: number;
+  command: string;
+  promptOffset: number;
+  outputStart: number;
+  outputEnd: number;
+  startedAt: string;
+  finishedAt?: string;
+  exitCode?: number;
+  outputTruncated: boolean;
+  running: boolean;
+}
+
+// Payload of terminal:command-started and terminal:command-finished
+export interface TerminalCommandEvent {
+  SessionID: string;
+  Command: CommandRecord;
+}
+
+// Payload of terminal:host-key-prompt (policy "ask"); answer with
+// RespondSSHHostKeyPrompt. terminal:host-key-prompt-expired carries the
+// same PromptID when the handshake gave up waiting.
+export interface HostKeyPromptEvent {
+  PromptID: string;
+  Host: string;
+  Port: number;
+  HostKey: {
+    fingerprintSHA256: string;
+    fingerprintMD5: string;
+    keyType: string;
+    keyBase64: string;
+    isKnown: boolean;
+    isMismatch: boolean;
+    isRevoked: boolean;
+    isCertified: boolean;
+    expectedFingerprint: string;
+    knownKeyTypes?: string[];
+    sshfp?: 'none' | 'match' | 'mismatch' | 'error';
+    sshfpSecure?: boolean;
+  };
+}
+
 export enum TabAction {
   Duplicate = 'duplicate',
   Close = 'close',
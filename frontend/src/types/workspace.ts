@@ -2,7 +2,7 @@
  * Workspace types for saving and restoring terminal layouts
  */
 
-import { LayoutNode, SessionType } from './terminal';
+import { HostKeyPolicy, LayoutNode, SessionType } from './terminal';
 
 /**
  * Serializable session configuration (without runtime state)
@@ -18,6 +18,8 @@ export interface SerializedSession {
     username: string;
     password: string;
     privateKey?: string;
+    hostKeyPolicy?: HostKeyPolicy;
+    connectionId?: string;
   };
 }
 
@@ -1,66 +1,216 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {terminal} from '../models';
+import {database} from '../models';
+import {sshkeys} from '../models';
+import {vault} from '../models';
+import {audit} from '../models';
 
 export function AcceptSSHHostKey(arg1:string,arg2:number,arg3:string,arg4:boolean):Promise<void>;
 
+export function AcceptSSHHostKeys(arg1:Array<terminal.HostKeyAcceptance>,arg2:boolean):Promise<number>;
+
+export function AckTerminalOutput(arg1:string,arg2:number):Promise<void>;
+
+export function AddRemoteAuthorizedKey(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
+export function AnnotateRemoteAuthorizedKey(arg1:string,arg2:string,arg3:string,arg4:string,arg5:boolean):Promise<void>;
+
+export function ChangeVaultPassword(arg1:string,arg2:string):Promise<void>;
+
 export function CloseTerminal(arg1:string):Promise<void>;
 
+export function ConvertPrivateKey(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function CreateDatabaseBackup():Promise<string>;
+
+export function CreateDatabaseCommand(arg1:string,arg2:database.Command):Promise<database.Command>;
+
+export function CreateDatabaseConnection(arg1:string,arg2:database.Connection):Promise<database.Connection>;
+
+export function CreateDatabaseKeychain(arg1:string,arg2:database.Keychain):Promise<database.Keychain>;
+
 export function CreateLocalTerminal(arg1:string,arg2:string,arg3:Record<string, string>):Promise<string>;
 
-export function CreateSSHTerminal(arg1:string,arg2:number,arg3:string,arg4:string,arg5:string):Promise<string>;
+export function CreateSSHTerminal(arg1:string,arg2:number,arg3:string,arg4:string,arg5:string,arg6:string,arg7:boolean):Promise<string>;
 
-export function DeleteFile(arg1:string):Promise<void>;
+export function CreateSSHTerminalForConnection(arg1:string,arg2:boolean):Promise<string>;
+
+export function DeleteDatabaseCommand(arg1:string,arg2:string):Promise<void>;
+
+export function DeleteDatabaseConnection(arg1:string,arg2:string):Promise<void>;
+
+export function DeleteDatabaseKeychain(arg1:string,arg2:string):Promise<void>;
 
 export function DeleteFromKeychain(arg1:string):Promise<void>;
 
+export function DeleteSSHKey(arg1:string):Promise<void>;
+
+export function DeleteStoreFile(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function DeleteVaultConnection(arg1:string):Promise<void>;
+
 export function DuplicateTerminal(arg1:string):Promise<string>;
 
-export function FileExists(arg1:string):Promise<boolean>;
+export function ExportAuditLog():Promise<boolean>;
+
+export function ExportFileWithDialog(arg1:string,arg2:string,arg3:string,arg4:string):Promise<boolean>;
+
+export function ExportSSHKnownHostEntries(arg1:Array<string>):Promise<boolean>;
+
+export function ExportSSHKnownHosts():Promise<boolean>;
+
+export function ExportSSHPrivateKey(arg1:string):Promise<boolean>;
+
+export function ExportSSHPublicKey(arg1:string):Promise<boolean>;
+
+export function ForgetGuestSSHHostKeys():Promise<number>;
+
+export function ForgetVaultMasterKey():Promise<void>;
+
+export function GenerateSSHKey(arg1:string,arg2:sshkeys.GenerateOptions):Promise<vault.SSHKey>;
 
 export function GetAppDataPath():Promise<string>;
 
+export function GetAuthorizedKeysReport(arg1:Array<string>,arg2:boolean):Promise<terminal.AuthorizedKeysReport>;
+
 export function GetBackupPath():Promise<string>;
 
-export function GetDatabasePath():Promise<string>;
+export function GetDatabaseCommand(arg1:string,arg2:string):Promise<database.Command>;
 
-export function GetFromKeychain(arg1:string):Promise<string>;
+export function GetDatabaseConnection(arg1:string,arg2:string):Promise<database.Connection>;
 
-export function GetGuestConfigPath():Promise<string>;
+export function GetDatabaseKeychain(arg1:string,arg2:string):Promise<database.Keychain>;
 
-export function GetGuestConnectionsPath():Promise<string>;
+export function GetDatabasePath():Promise<string>;
+
+export function GetFromKeychain(arg1:string):Promise<string>;
 
 export function GetGuestEncryptionKeyphrase():Promise<string>;
 
-export function GetGuestSnippetsPath():Promise<string>;
+export function GetKeychainBackend():Promise<string>;
 
 export function GetSSHHostKeyInfo(arg1:string,arg2:number):Promise<Record<string, any>>;
 
+export function GetStoreFileVersion(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function GetTerminalCommandHistory(arg1:string):Promise<Array<terminal.CommandRecord>>;
+
+export function GetTerminalCommandOutput(arg1:string,arg2:number):Promise<string>;
+
 export function GetTerminalMetadata(arg1:string):Promise<terminal.SessionMetadata>;
 
-export function GetUserConfigPath(arg1:string):Promise<string>;
+export function GetTerminalOutputStats(arg1:string):Promise<terminal.OutputStats>;
 
-export function GetUserConnectionsPath(arg1:string):Promise<string>;
+export function GetVaultAutoLockSettings():Promise<vault.AutoLockSettings>;
 
-export function GetUserSnippetsPath(arg1:string):Promise<string>;
+export function GetVaultConnection(arg1:string):Promise<vault.Connection>;
+
+export function GetVaultStatus():Promise<vault.Status>;
 
 export function Greet(arg1:string):Promise<string>;
 
-export function ListFiles(arg1:string):Promise<Array<string>>;
+export function ImportFileWithDialog(arg1:string,arg2:string):Promise<string>;
+
+export function ImportSSHKey(arg1:string,arg2:string,arg3:string):Promise<vault.SSHKey>;
+
+export function ImportSSHKnownHosts():Promise<number>;
+
+export function ImportSSHKnownHostsFromFile():Promise<number>;
+
+export function InspectPrivateKey(arg1:string,arg2:string):Promise<sshkeys.PrivateKeyInfo>;
+
+export function InstallSSHKeyForConnection(arg1:string,arg2:string,arg3:string,arg4:boolean,arg5:boolean):Promise<terminal.KeyInstallResult>;
 
-export function ReadFile(arg1:string):Promise<string>;
+export function ListDatabaseBackups():Promise<Array<string>>;
 
-export function ReconnectTerminal(arg1:string,arg2:string,arg3:number,arg4:string,arg5:string,arg6:string):Promise<void>;
+export function ListDatabaseCommands(arg1:string):Promise<Array<database.Command>>;
+
+export function ListDatabaseConnections(arg1:string):Promise<Array<database.Connection>>;
+
+export function ListDatabaseKeychains(arg1:string):Promise<Array<database.Keychain>>;
+
+export function ListDatabaseMigrations():Promise<Array<database.AppliedMigration>>;
+
+export function ListRemoteAuthorizedKeys(arg1:string,arg2:boolean):Promise<terminal.AuthorizedKeys>;
+
+export function ListSSHKeys():Promise<Array<vault.SSHKey>>;
+
+export function ListSSHKnownHosts():Promise<Array<terminal.KnownHostRecord>>;
+
+export function ListStoreFiles(arg1:string,arg2:string):Promise<Array<string>>;
+
+export function ListVaultConnections():Promise<Array<vault.Connection>>;
+
+export function LockVault():Promise<void>;
+
+export function QueryAuditLog(arg1:audit.Query):Promise<Array<audit.Entry>>;
+
+export function ReadStoreFile(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ReconnectTerminal(arg1:string,arg2:string,arg3:number,arg4:string,arg5:string,arg6:string,arg7:string,arg8:boolean):Promise<void>;
+
+export function ReconnectTerminalForConnection(arg1:string,arg2:boolean):Promise<void>;
+
+export function RecordAuditEvent(arg1:string,arg2:string):Promise<void>;
+
+export function RekeySSHHostKey(arg1:string,arg2:number,arg3:string,arg4:boolean):Promise<void>;
+
+export function RememberVaultMasterKey(arg1:string):Promise<void>;
+
+export function RemoveRemoteAuthorizedKey(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<void>;
+
+export function RemoveSSHHostKey(arg1:string,arg2:number):Promise<void>;
+
+export function RemoveSSHKnownHost(arg1:string):Promise<void>;
+
+export function RenameSSHKey(arg1:string,arg2:string):Promise<void>;
+
+export function RepairDatabase():Promise<Array<string>>;
 
 export function ResizeTerminal(arg1:string,arg2:number,arg3:number):Promise<void>;
 
+export function RespondSSHHostKeyPrompt(arg1:string,arg2:boolean):Promise<void>;
+
+export function RestoreDatabaseBackup(arg1:string):Promise<void>;
+
+export function RotateGuestEncryptionKey():Promise<void>;
+
 export function SaveToKeychain(arg1:string,arg2:string):Promise<void>;
 
+export function SaveVaultConnection(arg1:vault.Connection):Promise<vault.Connection>;
+
+export function ScanSSHHostKeys(arg1:Array<terminal.HostKeyScanTarget>):Promise<terminal.HostKeyScanReport>;
+
+export function SetSSHFPConfig(arg1:terminal.SSHFPConfig):Promise<void>;
+
+export function SetTerminalOutputFraming(arg1:string):Promise<void>;
+
+export function SetUseSystemKnownHosts(arg1:boolean):Promise<void>;
+
+export function SetVaultAutoLockSettings(arg1:vault.AutoLockSettings):Promise<void>;
+
+export function SetVaultCredential(arg1:string,arg2:vault.Credential):Promise<void>;
+
+export function SetupVault(arg1:string):Promise<void>;
+
 export function ShowMessageDialog(arg1:string,arg2:string,arg3:string):Promise<string>;
 
-export function ShowOpenFileDialog(arg1:string,arg2:string):Promise<string>;
+export function TouchVault():Promise<void>;
+
+export function UnlockVault(arg1:string):Promise<void>;
+
+export function UnlockVaultFromKeychain():Promise<boolean>;
+
+export function UpdateDatabaseCommand(arg1:string,arg2:database.Command):Promise<database.Command>;
 
-export function ShowSaveFileDialog(arg1:string,arg2:string,arg3:string):Promise<string>;
+export function UpdateDatabaseConnection(arg1:string,arg2:database.Connection):Promise<database.Connection>;
+
+export function UpdateDatabaseKeychain(arg1:string,arg2:database.Keychain):Promise<database.Keychain>;
+
+export function ValidateDatabase():Promise<Array<string>>;
+
+export function VerifyAuditLog():Promise<audit.Verification>;
 
 export function WindowClose():Promise<void>;
 
@@ -70,6 +220,8 @@ export function WindowMaximize():Promise<void>;
 
 export function WindowMinimize():Promise<void>;
 
-export function WriteFile(arg1:string,arg2:string):Promise<void>;
+export function WriteStoreFile(arg1:string,arg2:string,arg3:string,arg4:string):Promise<void>;
+
+export function WriteStoreFileIfUnchanged(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
 
 export function WriteToTerminal(arg1:string,arg2:string):Promise<void>;
@@ -6,120 +6,412 @@ export function AcceptSSHHostKey(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['AcceptSSHHostKey'](arg1, arg2, arg3, arg4);
 }
 
+export function AcceptSSHHostKeys(arg1, arg2) {
+  return window['go']['main']['App']['AcceptSSHHostKeys'](arg1, arg2);
+}
+
+export function AckTerminalOutput(arg1, arg2) {
+  return window['go']['main']['App']['AckTerminalOutput'](arg1, arg2);
+}
+
+export function AddRemoteAuthorizedKey(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddRemoteAuthorizedKey'](arg1, arg2, arg3);
+}
+
+export function AnnotateRemoteAuthorizedKey(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['AnnotateRemoteAuthorizedKey'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ChangeVaultPassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangeVaultPassword'](arg1, arg2);
+}
+
 export function CloseTerminal(arg1) {
   return window['go']['main']['App']['CloseTerminal'](arg1);
 }
 
+export function ConvertPrivateKey(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ConvertPrivateKey'](arg1, arg2, arg3, arg4);
+}
+
+export function CreateDatabaseBackup() {
+  return window['go']['main']['App']['CreateDatabaseBackup']();
+}
+
+export function CreateDatabaseCommand(arg1, arg2) {
+  return window['go']['main']['App']['CreateDatabaseCommand'](arg1, arg2);
+}
+
+export function CreateDatabaseConnection(arg1, arg2) {
+  return window['go']['main']['App']['CreateDatabaseConnection'](arg1, arg2);
+}
+
+export function CreateDatabaseKeychain(arg1, arg2) {
+  return window['go']['main']['App']['CreateDatabaseKeychain'](arg1, arg2);
+}
+
 export function CreateLocalTerminal(arg1, arg2, arg3) {
   return window['go']['main']['App']['CreateLocalTerminal'](arg1, arg2, arg3);
 }
 
-export function CreateSSHTerminal(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['CreateSSHTerminal'](arg1, arg2, arg3, arg4, arg5);
+export function CreateSSHTerminal(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['CreateSSHTerminal'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
 }
 
-export function DeleteFile(arg1) {
-  return window['go']['main']['App']['DeleteFile'](arg1);
+export function CreateSSHTerminalForConnection(arg1, arg2) {
+  return window['go']['main']['App']['CreateSSHTerminalForConnection'](arg1, arg2);
+}
+
+export function DeleteDatabaseCommand(arg1, arg2) {
+  return window['go']['main']['App']['DeleteDatabaseCommand'](arg1, arg2);
+}
+
+export function DeleteDatabaseConnection(arg1, arg2) {
+  return window['go']['main']['App']['DeleteDatabaseConnection'](arg1, arg2);
+}
+
+export function DeleteDatabaseKeychain(arg1, arg2) {
+  return window['go']['main']['App']['DeleteDatabaseKeychain'](arg1, arg2);
 }
 
 export function DeleteFromKeychain(arg1) {
   return window['go']['main']['App']['DeleteFromKeychain'](arg1);
 }
 
+export function DeleteSSHKey(arg1) {
+  return window['go']['main']['App']['DeleteSSHKey'](arg1);
+}
+
+export function DeleteStoreFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteStoreFile'](arg1, arg2, arg3);
+}
+
+export function DeleteVaultConnection(arg1) {
+  return window['go']['main']['App']['DeleteVaultConnection'](arg1);
+}
+
 export function DuplicateTerminal(arg1) {
   return window['go']['main']['App']['DuplicateTerminal'](arg1);
 }
 
-export function FileExists(arg1) {
-  return window['go']['main']['App']['FileExists'](arg1);
+export function ExportAuditLog() {
+  return window['go']['main']['App']['ExportAuditLog']();
+}
+
+export function ExportFileWithDialog(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ExportFileWithDialog'](arg1, arg2, arg3, arg4);
+}
+
+export function ExportSSHKnownHostEntries(arg1) {
+  return window['go']['main']['App']['ExportSSHKnownHostEntries'](arg1);
+}
+
+export function ExportSSHKnownHosts() {
+  return window['go']['main']['App']['ExportSSHKnownHosts']();
+}
+
+export function ExportSSHPrivateKey(arg1) {
+  return window['go']['main']['App']['ExportSSHPrivateKey'](arg1);
+}
+
+export function ExportSSHPublicKey(arg1) {
+  return window['go']['main']['App']['ExportSSHPublicKey'](arg1);
+}
+
+export function ForgetGuestSSHHostKeys() {
+  return window['go']['main']['App']['ForgetGuestSSHHostKeys']();
+}
+
+export function ForgetVaultMasterKey() {
+  return window['go']['main']['App']['ForgetVaultMasterKey']();
+}
+
+export function GenerateSSHKey(arg1, arg2) {
+  return window['go']['main']['App']['GenerateSSHKey'](arg1, arg2);
 }
 
 export function GetAppDataPath() {
   return window['go']['main']['App']['GetAppDataPath']();
 }
 
+export function GetAuthorizedKeysReport(arg1, arg2) {
+  return window['go']['main']['App']['GetAuthorizedKeysReport'](arg1, arg2);
+}
+
 export function GetBackupPath() {
   return window['go']['main']['App']['GetBackupPath']();
 }
 
-export function GetDatabasePath() {
-  return window['go']['main']['App']['GetDatabasePath']();
+export function GetDatabaseCommand(arg1, arg2) {
+  return window['go']['main']['App']['GetDatabaseCommand'](arg1, arg2);
 }
 
-export function GetFromKeychain(arg1) {
-  return window['go']['main']['App']['GetFromKeychain'](arg1);
+export function GetDatabaseConnection(arg1, arg2) {
+  return window['go']['main']['App']['GetDatabaseConnection'](arg1, arg2);
 }
 
-export function GetGuestConfigPath() {
-  return window['go']['main']['App']['GetGuestConfigPath']();
+export function GetDatabaseKeychain(arg1, arg2) {
+  return window['go']['main']['App']['GetDatabaseKeychain'](arg1, arg2);
 }
 
-export function GetGuestConnectionsPath() {
-  return window['go']['main']['App']['GetGuestConnectionsPath']();
+export function GetDatabasePath() {
+  return window['go']['main']['App']['GetDatabasePath']();
+}
+
+export function GetFromKeychain(arg1) {
+  return window['go']['main']['App']['GetFromKeychain'](arg1);
 }
 
 export function GetGuestEncryptionKeyphrase() {
   return window['go']['main']['App']['GetGuestEncryptionKeyphrase']();
 }
 
-export function GetGuestSnippetsPath() {
-  return window['go']['main']['App']['GetGuestSnippetsPath']();
+export function GetKeychainBackend() {
+  return window['go']['main']['App']['GetKeychainBackend']();
 }
 
 export function GetSSHHostKeyInfo(arg1, arg2) {
   return window['go']['main']['App']['GetSSHHostKeyInfo'](arg1, arg2);
 }
 
+export function GetStoreFileVersion(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetStoreFileVersion'](arg1, arg2, arg3);
+}
+
+export function GetTerminalCommandHistory(arg1) {
+  return window['go']['main']['App']['GetTerminalCommandHistory'](arg1);
+}
+
+export function GetTerminalCommandOutput(arg1, arg2) {
+  return window['go']['main']['App']['GetTerminalCommandOutput'](arg1, arg2);
+}
+
 export function GetTerminalMetadata(arg1) {
   return window['go']['main']['App']['GetTerminalMetadata'](arg1);
 }
 
-export function GetUserConfigPath(arg1) {
-  return window['go']['main']['App']['GetUserConfigPath'](arg1);
+export function GetTerminalOutputStats(arg1) {
+  return window['go']['main']['App']['GetTerminalOutputStats'](arg1);
 }
 
-export function GetUserConnectionsPath(arg1) {
-  return window['go']['main']['App']['GetUserConnectionsPath'](arg1);
+export function GetVaultAutoLockSettings() {
+  return window['go']['main']['App']['GetVaultAutoLockSettings']();
 }
 
-export function GetUserSnippetsPath(arg1) {
-  return window['go']['main']['App']['GetUserSnippetsPath'](arg1);
+export function GetVaultConnection(arg1) {
+  return window['go']['main']['App']['GetVaultConnection'](arg1);
+}
+
+export function GetVaultStatus() {
+  return window['go']['main']['App']['GetVaultStatus']();
 }
 
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
-export function ListFiles(arg1) {
-  return window['go']['main']['App']['ListFiles'](arg1);
+export function ImportFileWithDialog(arg1, arg2) {
+  return window['go']['main']['App']['ImportFileWithDialog'](arg1, arg2);
+}
+
+export function ImportSSHKey(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportSSHKey'](arg1, arg2, arg3);
+}
+
+export function ImportSSHKnownHosts() {
+  return window['go']['main']['App']['ImportSSHKnownHosts']();
+}
+
+export function ImportSSHKnownHostsFromFile() {
+  return window['go']['main']['App']['ImportSSHKnownHostsFromFile']();
+}
+
+export function InspectPrivateKey(arg1, arg2) {
+  return window['go']['main']['App']['InspectPrivateKey'](arg1, arg2);
+}
+
+export function InstallSSHKeyForConnection(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['InstallSSHKeyForConnection'](arg1, arg2, arg3, arg4, arg5);
 }
 
-export function ReadFile(arg1) {
-  return window['go']['main']['App']['ReadFile'](arg1);
+export function ListDatabaseBackups() {
+  return window['go']['main']['App']['ListDatabaseBackups']();
 }
 
-export function ReconnectTerminal(arg1, arg2, arg3, arg4, arg5, arg6) {
-  return window['go']['main']['App']['ReconnectTerminal'](arg1, arg2, arg3, arg4, arg5, arg6);
+export function ListDatabaseCommands(arg1) {
+  return window['go']['main']['App']['ListDatabaseCommands'](arg1);
+}
+
+export function ListDatabaseConnections(arg1) {
+  return window['go']['main']['App']['ListDatabaseConnections'](arg1);
+}
+
+export function ListDatabaseKeychains(arg1) {
+  return window['go']['main']['App']['ListDatabaseKeychains'](arg1);
+}
+
+export function ListDatabaseMigrations() {
+  return window['go']['main']['App']['ListDatabaseMigrations']();
+}
+
+export function ListRemoteAuthorizedKeys(arg1, arg2) {
+  return window['go']['main']['App']['ListRemoteAuthorizedKeys'](arg1, arg2);
+}
+
+export function ListSSHKeys() {
+  return window['go']['main']['App']['ListSSHKeys']();
+}
+
+export function ListSSHKnownHosts() {
+  return window['go']['main']['App']['ListSSHKnownHosts']();
+}
+
+export function ListStoreFiles(arg1, arg2) {
+  return window['go']['main']['App']['ListStoreFiles'](arg1, arg2);
+}
+
+export function ListVaultConnections() {
+  return window['go']['main']['App']['ListVaultConnections']();
+}
+
+export function LockVault() {
+  return window['go']['main']['App']['LockVault']();
+}
+
+export function QueryAuditLog(arg1) {
+  return window['go']['main']['App']['QueryAuditLog'](arg1);
+}
+
+export function ReadStoreFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReadStoreFile'](arg1, arg2, arg3);
+}
+
+export function ReconnectTerminal(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
+  return window['go']['main']['App']['ReconnectTerminal'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
+}
+
+export function ReconnectTerminalForConnection(arg1, arg2) {
+  return window['go']['main']['App']['ReconnectTerminalForConnection'](arg1, arg2);
+}
+
+export function RecordAuditEvent(arg1, arg2) {
+  return window['go']['main']['App']['RecordAuditEvent'](arg1, arg2);
+}
+
+export function RekeySSHHostKey(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RekeySSHHostKey'](arg1, arg2, arg3, arg4);
+}
+
+export function RememberVaultMasterKey(arg1) {
+  return window['go']['main']['App']['RememberVaultMasterKey'](arg1);
+}
+
+export function RemoveRemoteAuthorizedKey(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RemoveRemoteAuthorizedKey'](arg1, arg2, arg3, arg4);
+}
+
+export function RemoveSSHHostKey(arg1, arg2) {
+  return window['go']['main']['App']['RemoveSSHHostKey'](arg1, arg2);
+}
+
+export function RemoveSSHKnownHost(arg1) {
+  return window['go']['main']['App']['RemoveSSHKnownHost'](arg1);
+}
+
+export function RenameSSHKey(arg1, arg2) {
+  return window['go']['main']['App']['RenameSSHKey'](arg1, arg2);
+}
+
+export function RepairDatabase() {
+  return window['go']['main']['App']['RepairDatabase']();
 }
 
 export function ResizeTerminal(arg1, arg2, arg3) {
   return window['go']['main']['App']['ResizeTerminal'](arg1, arg2, arg3);
 }
 
+export function RespondSSHHostKeyPrompt(arg1, arg2) {
+  return window['go']['main']['App']['RespondSSHHostKeyPrompt'](arg1, arg2);
+}
+
+export function RestoreDatabaseBackup(arg1) {
+  return window['go']['main']['App']['RestoreDatabaseBackup'](arg1);
+}
+
+export function RotateGuestEncryptionKey() {
+  return window['go']['main']['App']['RotateGuestEncryptionKey']();
+}
+
 export function SaveToKeychain(arg1, arg2) {
   return window['go']['main']['App']['SaveToKeychain'](arg1, arg2);
 }
 
+export function SaveVaultConnection(arg1) {
+  return window['go']['main']['App']['SaveVaultConnection'](arg1);
+}
+
+export function ScanSSHHostKeys(arg1) {
+  return window['go']['main']['App']['ScanSSHHostKeys'](arg1);
+}
+
+export function SetSSHFPConfig(arg1) {
+  return window['go']['main']['App']['SetSSHFPConfig'](arg1);
+}
+
+export function SetTerminalOutputFraming(arg1) {
+  return window['go']['main']['App']['SetTerminalOutputFraming'](arg1);
+}
+
+export function SetUseSystemKnownHosts(arg1) {
+  return window['go']['main']['App']['SetUseSystemKnownHosts'](arg1);
+}
+
+export function SetVaultAutoLockSettings(arg1) {
+  return window['go']['main']['App']['SetVaultAutoLockSettings'](arg1);
+}
+
+export function SetVaultCredential(arg1, arg2) {
+  return window['go']['main']['App']['SetVaultCredential'](arg1, arg2);
+}
+
+export function SetupVault(arg1) {
+  return window['go']['main']['App']['SetupVault'](arg1);
+}
+
 export function ShowMessageDialog(arg1, arg2, arg3) {
   return window['go']['main']['App']['ShowMessageDialog'](arg1, arg2, arg3);
 }
 
-export function ShowOpenFileDialog(arg1, arg2) {
-  return window['go']['main']['App']['ShowOpenFileDialog'](arg1, arg2);
+export function TouchVault() {
+  return window['go']['main']['App']['TouchVault']();
 }
 
-export function ShowSaveFileDialog(arg1, arg2, arg3) {
-  return window['go']['main']['App']['ShowSaveFileDialog'](arg1, arg2, arg3);
+export function UnlockVault(arg1) {
+  return window['go']['main']['App']['UnlockVault'](arg1);
+}
+
+export function UnlockVaultFromKeychain() {
+  return window['go']['main']['App']['UnlockVaultFromKeychain']();
+}
+
+export function UpdateDatabaseCommand(arg1, arg2) {
+  return window['go']['main']['App']['UpdateDatabaseCommand'](arg1, arg2);
+}
+
+export function UpdateDatabaseConnection(arg1, arg2) {
+  return window['go']['main']['App']['UpdateDatabaseConnection'](arg1, arg2);
+}
+
+export function UpdateDatabaseKeychain(arg1, arg2) {
+  return window['go']['main']['App']['UpdateDatabaseKeychain'](arg1, arg2);
+}
+
+export function ValidateDatabase() {
+  return window['go']['main']['App']['ValidateDatabase']();
+}
+
+export function VerifyAuditLog() {
+  return window['go']['main']['App']['VerifyAuditLog']();
 }
 
 export function WindowClose() {
@@ -138,8 +430,12 @@ export function WindowMinimize() {
   return window['go']['main']['App']['WindowMinimize']();
 }
 
-export function WriteFile(arg1, arg2) {
-  return window['go']['main']['App']['WriteFile'](arg1, arg2);
+export function WriteStoreFile(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['WriteStoreFile'](arg1, arg2, arg3, arg4);
+}
+
+export function WriteStoreFileIfUnchanged(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['WriteStoreFileIfUnchanged'](arg1, arg2, arg3, arg4, arg5);
 }
 
 export function WriteToTerminal(arg1, arg2) {
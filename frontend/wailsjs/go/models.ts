@@ -1,5 +1,962 @@
+export namespace audit {
+	
+	export class Entry {
+	    seq: number;
+	    // Go type: time
+	    time: any;
+	    event: string;
+	    host?: string;
+	    port?: number;
+	    user?: string;
+	    authMethod?: string;
+	    connectionId?: string;
+	    sessionId?: string;
+	    keyType?: string;
+	    fingerprint?: string;
+	    durationMs?: number;
+	    detail?: string;
+	    source?: string;
+	    prevHash: string;
+	    hash?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.event = source["event"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.authMethod = source["authMethod"];
+	        this.connectionId = source["connectionId"];
+	        this.sessionId = source["sessionId"];
+	        this.keyType = source["keyType"];
+	        this.fingerprint = source["fingerprint"];
+	        this.durationMs = source["durationMs"];
+	        this.detail = source["detail"];
+	        this.source = source["source"];
+	        this.prevHash = source["prevHash"];
+	        this.hash = source["hash"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Query {
+	    events?: string[];
+	    host?: string;
+	    // Go type: time
+	    since: any;
+	    // Go type: time
+	    until: any;
+	    limit?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Query(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.events = source["events"];
+	        this.host = source["host"];
+	        this.since = this.convertValues(source["since"], null);
+	        this.until = this.convertValues(source["until"], null);
+	        this.limit = source["limit"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Verification {
+	    valid: boolean;
+	    entries: number;
+	    headHash: string;
+	    brokenAt?: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Verification(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.valid = source["valid"];
+	        this.entries = source["entries"];
+	        this.headHash = source["headHash"];
+	        this.brokenAt = source["brokenAt"];
+	        this.error = source["error"];
+	    }
+	}
+
+}
+
+export namespace database {
+	
+	export class AppliedMigration {
+	    version: number;
+	    name: string;
+	    checksum: string;
+	    // Go type: time
+	    appliedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new AppliedMigration(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.name = source["name"];
+	        this.checksum = source["checksum"];
+	        this.appliedAt = this.convertValues(source["appliedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CommandVariable {
+	    name: string;
+	    defaultValue?: string;
+	    description?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandVariable(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.defaultValue = source["defaultValue"];
+	        this.description = source["description"];
+	    }
+	}
+	export class Command {
+	    id: string;
+	    userId: string;
+	    title: string;
+	    command: string;
+	    description?: string;
+	    variables?: CommandVariable[];
+	    tags?: string[];
+	    scope: string;
+	    version: number;
+	    // Go type: time
+	    deletedAt?: any;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Command(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.userId = source["userId"];
+	        this.title = source["title"];
+	        this.command = source["command"];
+	        this.description = source["description"];
+	        this.variables = this.convertValues(source["variables"], CommandVariable);
+	        this.tags = source["tags"];
+	        this.scope = source["scope"];
+	        this.version = source["version"];
+	        this.deletedAt = this.convertValues(source["deletedAt"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Connection {
+	    id: string;
+	    userId: string;
+	    name: string;
+	    host: string;
+	    port: number;
+	    username: string;
+	    privateKeyEncrypted?: string;
+	    publicKey?: string;
+	    passwordEncrypted?: string;
+	    connectionConfig?: Record<string, any>;
+	    importSource?: string;
+	    tags?: string[];
+	    isFavorite: boolean;
+	    version: number;
+	    // Go type: time
+	    deletedAt?: any;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Connection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.userId = source["userId"];
+	        this.name = source["name"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.username = source["username"];
+	        this.privateKeyEncrypted = source["privateKeyEncrypted"];
+	        this.publicKey = source["publicKey"];
+	        this.passwordEncrypted = source["passwordEncrypted"];
+	        this.connectionConfig = source["connectionConfig"];
+	        this.importSource = source["importSource"];
+	        this.tags = source["tags"];
+	        this.isFavorite = source["isFavorite"];
+	        this.version = source["version"];
+	        this.deletedAt = this.convertValues(source["deletedAt"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Keychain {
+	    id: string;
+	    userId: string;
+	    type: string;
+	    dataEncrypted: string;
+	    metadata?: Record<string, any>;
+	    associatedConnections?: string[];
+	    version: number;
+	    // Go type: time
+	    deletedAt?: any;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Keychain(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.userId = source["userId"];
+	        this.type = source["type"];
+	        this.dataEncrypted = source["dataEncrypted"];
+	        this.metadata = source["metadata"];
+	        this.associatedConnections = source["associatedConnections"];
+	        this.version = source["version"];
+	        this.deletedAt = this.convertValues(source["deletedAt"], null);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace sshkeys {
+	
+	export class GenerateOptions {
+	    type: string;
+	    bits?: number;
+	    comment?: string;
+	    passphrase?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GenerateOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.bits = source["bits"];
+	        this.comment = source["comment"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class Info {
+	    type: string;
+	    bits: number;
+	    comment?: string;
+	    fingerprint: string;
+	    randomArt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Info(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.bits = source["bits"];
+	        this.comment = source["comment"];
+	        this.fingerprint = source["fingerprint"];
+	        this.randomArt = source["randomArt"];
+	    }
+	}
+	export class PrivateKeyInfo {
+	    format: string;
+	    encrypted: boolean;
+	    decrypted: boolean;
+	    key?: Info;
+	    publicKey?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PrivateKeyInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.encrypted = source["encrypted"];
+	        this.decrypted = source["decrypted"];
+	        this.key = this.convertValues(source["key"], Info);
+	        this.publicKey = source["publicKey"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace terminal {
 	
+	export class VaultKeyRef {
+	    id: string;
+	    name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VaultKeyRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	    }
+	}
+	export class AuthorizedKeyEntry {
+	    line: number;
+	    keyType: string;
+	    bits: number;
+	    fingerprint: string;
+	    comment?: string;
+	    options?: string[];
+	    status: string;
+	    staleReason?: string;
+	    vaultKey?: VaultKeyRef;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorizedKeyEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.keyType = source["keyType"];
+	        this.bits = source["bits"];
+	        this.fingerprint = source["fingerprint"];
+	        this.comment = source["comment"];
+	        this.options = source["options"];
+	        this.status = source["status"];
+	        this.staleReason = source["staleReason"];
+	        this.vaultKey = this.convertValues(source["vaultKey"], VaultKeyRef);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuthorizedKeys {
+	    connectionId: string;
+	    host: string;
+	    port: number;
+	    user: string;
+	    entries: AuthorizedKeyEntry[];
+	    invalid?: number[];
+	    version: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorizedKeys(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.entries = this.convertValues(source["entries"], AuthorizedKeyEntry);
+	        this.invalid = source["invalid"];
+	        this.version = source["version"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuthorizedKeysHostResult {
+	    connectionId: string;
+	    host: string;
+	    port: number;
+	    user: string;
+	    keys: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorizedKeysHostResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.keys = source["keys"];
+	        this.error = source["error"];
+	    }
+	}
+	export class KeyAccess {
+	    connectionId: string;
+	    host: string;
+	    port: number;
+	    user: string;
+	    options?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeyAccess(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.connectionId = source["connectionId"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.user = source["user"];
+	        this.options = source["options"];
+	    }
+	}
+	export class KeyGrant {
+	    fingerprint: string;
+	    keyType: string;
+	    bits: number;
+	    comment?: string;
+	    status: string;
+	    staleReason?: string;
+	    vaultKey?: VaultKeyRef;
+	    access: KeyAccess[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeyGrant(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fingerprint = source["fingerprint"];
+	        this.keyType = source["keyType"];
+	        this.bits = source["bits"];
+	        this.comment = source["comment"];
+	        this.status = source["status"];
+	        this.staleReason = source["staleReason"];
+	        this.vaultKey = this.convertValues(source["vaultKey"], VaultKeyRef);
+	        this.access = this.convertValues(source["access"], KeyAccess);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AuthorizedKeysReport {
+	    keys: KeyGrant[];
+	    hosts: AuthorizedKeysHostResult[];
+	    unknown: number;
+	    stale: number;
+	    unreachable: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorizedKeysReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keys = this.convertValues(source["keys"], KeyGrant);
+	        this.hosts = this.convertValues(source["hosts"], AuthorizedKeysHostResult);
+	        this.unknown = source["unknown"];
+	        this.stale = source["stale"];
+	        this.unreachable = source["unreachable"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CommandRecord {
+	    id: number;
+	    command: string;
+	    promptOffset: number;
+	    outputStart: number;
+	    outputEnd: number;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    finishedAt?: any;
+	    exitCode?: number;
+	    outputTruncated: boolean;
+	    running: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.command = source["command"];
+	        this.promptOffset = source["promptOffset"];
+	        this.outputStart = source["outputStart"];
+	        this.outputEnd = source["outputEnd"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.exitCode = source["exitCode"];
+	        this.outputTruncated = source["outputTruncated"];
+	        this.running = source["running"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HostKeyAcceptance {
+	    host: string;
+	    port: number;
+	    keyBase64: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostKeyAcceptance(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.keyBase64 = source["keyBase64"];
+	    }
+	}
+	export class ScannedHostKey {
+	    keyType: string;
+	    fingerprintSHA256: string;
+	    keyBase64: string;
+	    status: string;
+	    expectedFingerprint?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScannedHostKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keyType = source["keyType"];
+	        this.fingerprintSHA256 = source["fingerprintSHA256"];
+	        this.keyBase64 = source["keyBase64"];
+	        this.status = source["status"];
+	        this.expectedFingerprint = source["expectedFingerprint"];
+	    }
+	}
+	export class HostKeyScanResult {
+	    host: string;
+	    port: number;
+	    connectionId?: string;
+	    status: string;
+	    keys: ScannedHostKey[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostKeyScanResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.connectionId = source["connectionId"];
+	        this.status = source["status"];
+	        this.keys = this.convertValues(source["keys"], ScannedHostKey);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HostKeyScanReport {
+	    results: HostKeyScanResult[];
+	    new: number;
+	    matches: number;
+	    mismatches: number;
+	    unreachable: number;
+	    errors: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostKeyScanReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.results = this.convertValues(source["results"], HostKeyScanResult);
+	        this.new = source["new"];
+	        this.matches = source["matches"];
+	        this.mismatches = source["mismatches"];
+	        this.unreachable = source["unreachable"];
+	        this.errors = source["errors"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class HostKeyScanTarget {
+	    host: string;
+	    port: number;
+	    connectionId?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostKeyScanTarget(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.connectionId = source["connectionId"];
+	    }
+	}
+	
+	
+	export class KeyInstallResult {
+	    added: boolean;
+	    verified: boolean;
+	    verifyError?: string;
+	    switchedToKey: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new KeyInstallResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.verified = source["verified"];
+	        this.verifyError = source["verifyError"];
+	        this.switchedToKey = source["switchedToKey"];
+	    }
+	}
+	export class KnownHostRecord {
+	    id: string;
+	    host: string;
+	    port: number;
+	    patterns: string[];
+	    marker?: string;
+	    keyType: string;
+	    fingerprint: string;
+	    // Go type: time
+	    firstSeen?: any;
+	    // Go type: time
+	    lastSeen?: any;
+	    source: string;
+	    guest: boolean;
+	    readOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new KnownHostRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.patterns = source["patterns"];
+	        this.marker = source["marker"];
+	        this.keyType = source["keyType"];
+	        this.fingerprint = source["fingerprint"];
+	        this.firstSeen = this.convertValues(source["firstSeen"], null);
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	        this.source = source["source"];
+	        this.guest = source["guest"];
+	        this.readOnly = source["readOnly"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OutputStats {
+	    events: number;
+	    bytes: number;
+	    avgBytesPerEvent: number;
+	    maxBytesPerEvent: number;
+	    interactiveFlushes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.events = source["events"];
+	        this.bytes = source["bytes"];
+	        this.avgBytesPerEvent = source["avgBytesPerEvent"];
+	        this.maxBytesPerEvent = source["maxBytesPerEvent"];
+	        this.interactiveFlushes = source["interactiveFlushes"];
+	    }
+	}
+	export class SSHFPConfig {
+	    enabled: boolean;
+	    resolver: string;
+	    autoTrust: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHFPConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.resolver = source["resolver"];
+	        this.autoTrust = source["autoTrust"];
+	    }
+	}
+	
 	export class SessionMetadata {
 	    workingDirectory: string;
 	    shell: string;
@@ -44,3 +1001,161 @@ export namespace terminal {
 
 }
 
+export namespace vault {
+	
+	export class AutoLockSettings {
+	    idleMinutes: number;
+	    lockOnSuspend: boolean;
+	    lockOnScreenLock: boolean;
+	    sessionAction: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AutoLockSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.idleMinutes = source["idleMinutes"];
+	        this.lockOnSuspend = source["lockOnSuspend"];
+	        this.lockOnScreenLock = source["lockOnScreenLock"];
+	        this.sessionAction = source["sessionAction"];
+	    }
+	}
+	export class Connection {
+	    id: string;
+	    name: string;
+	    host: string;
+	    port: number;
+	    username: string;
+	    hostKeyPolicy?: string;
+	    tags?: string[];
+	    isFavorite: boolean;
+	    // Go type: time
+	    createdAt: any;
+	    // Go type: time
+	    updatedAt: any;
+	    hasPassword: boolean;
+	    hasPrivateKey: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Connection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.username = source["username"];
+	        this.hostKeyPolicy = source["hostKeyPolicy"];
+	        this.tags = source["tags"];
+	        this.isFavorite = source["isFavorite"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.hasPassword = source["hasPassword"];
+	        this.hasPrivateKey = source["hasPrivateKey"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Credential {
+	    password?: string;
+	    privateKey?: string;
+	    passphrase?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Credential(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.password = source["password"];
+	        this.privateKey = source["privateKey"];
+	        this.passphrase = source["passphrase"];
+	    }
+	}
+	export class SSHKey {
+	    id: string;
+	    name: string;
+	    type: string;
+	    bits: number;
+	    comment?: string;
+	    publicKey: string;
+	    fingerprint: string;
+	    randomArt: string;
+	    encrypted: boolean;
+	    // Go type: time
+	    createdAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSHKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.bits = source["bits"];
+	        this.comment = source["comment"];
+	        this.publicKey = source["publicKey"];
+	        this.fingerprint = source["fingerprint"];
+	        this.randomArt = source["randomArt"];
+	        this.encrypted = source["encrypted"];
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Status {
+	    initialized: boolean;
+	    unlocked: boolean;
+	    passwordRequired: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Status(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.initialized = source["initialized"];
+	        this.unlocked = source["unlocked"];
+	        this.passwordRequired = source["passwordRequired"];
+	    }
+	}
+
+}
+
@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"host-vault/internal/storage"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event names a kind of audited action
+type Event string
+
+const (
+	EventVaultUnlock        Event = "vault.unlock"
+	EventVaultUnlockFailed  Event = "vault.unlock-failed"
+	EventHostKeyAccepted    Event = "host-key.accepted"
+	EventHostKeyMismatch    Event = "host-key.mismatch"
+	EventConnectionOpened   Event = "connection.opened"
+	EventConnectionClosed   Event = "connection.closed"
+	EventCredentialViewed   Event = "credential.viewed"
+	EventCredentialExported Event = "credential.exported"
+	EventBackupRestored     Event = "backup.restored"
+	// EventChainRestarted marks where the log continues after an end it
+	// couldn't chain to, such as a line torn by a crash
+	EventChainRestarted Event = "audit.chain-restarted"
+)
+
+// tailSize is how much of the end of the log is read to find the last
+// entry; entries are far smaller
+const tailSize = 64 << 10
+
+// restartPrefix starts the PrevHash of an EventChainRestarted entry, which
+// holds the hash of the unreadable tail instead of a previous entry's
+const restartPrefix = "restart:"
+
+// errNoIntactEntry is returned by lastEntry when the tail of a non-empty
+// log holds no entry to chain to
+var errNoIntactEntry = errors.New("audit log has no intact entry to chain to")
+
+// hashField ends every line, so an entry's hash covers its line with this
+// field cut off
+const hashField = `,"hash":"`
+
+// Entry is one line of the audit log. Hash is the hex SHA-256 of the
+// line's JSON without the hash field. That JSON includes PrevHash, so
+// every entry vouches for all the ones before it.
+type Entry struct {
+	Seq          int64     `json:"seq"`
+	Time         time.Time `json:"time"`
+	Event        Event     `json:"event"`
+	Host         string    `json:"host,omitempty"`
+	Port         int       `json:"port,omitempty"`
+	User         string    `json:"user,omitempty"`
+	AuthMethod   string    `json:"authMethod,omitempty"`
+	ConnectionID string    `json:"connectionId,omitempty"`
+	SessionID    string    `json:"sessionId,omitempty"`
+	KeyType      string    `json:"keyType,omitempty"`
+	Fingerprint  string    `json:"fingerprint,omitempty"`
+	DurationMs   int64     `json:"durationMs,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	Source       string    `json:"source,omitempty"` // "frontend" for events the UI reported
+	PrevHash     string    `json:"prevHash"`
+	Hash         string    `json:"hash,omitempty"`
+}
+
+// Log is an append-only, hash-chained JSON lines file. Entries are only
+// ever added; the chain makes edits, removals and reordering detectable.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns the audit log kept at path, which is created on the first
+// entry
+func Open(path string) (*Log, error) {
+	if err := storage.EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	return &Log{path: path}, nil
+}
+
+// Record appends entry, logging instead of returning a failure so a broken
+// audit log never blocks what is being audited. A nil Log records nothing.
+func (l *Log) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+	if _, err := l.Append(entry); err != nil {
+		log.Printf("[AUDIT] Failed to record %s: %v", entry.Event, err)
+	}
+}
+
+// Append chains entry to the last one, stamping its sequence number, time
+// and hashes, and returns it as written
+func (l *Log) Append(entry Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Other instances append to the same file
+	unlock, err := storage.Lock(l.path)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, storage.FilePerm)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	last, torn, err := lastEntry(file)
+	if errors.Is(err, errNoIntactEntry) {
+		// Refusing to write would lose every later event. The restart entry
+		// doesn't follow anything, so Verify still reports the break.
+		log.Printf("[AUDIT] No intact entry at the end of %s, restarting the hash chain", l.path)
+		last, err = restartChain(file, &buf, torn)
+		torn = false
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry.Seq = last.Seq + 1
+	entry.PrevHash = last.Hash
+	line, err := sealEntry(&entry)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if torn {
+		// Keep a half-written line from swallowing this one; Verify still
+		// reports it
+		buf.WriteByte('\n')
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return Entry{}, err
+	}
+	return entry, file.Sync()
+}
+
+// sealEntry stamps entry's time and hash and returns its line
+func sealEntry(entry *Entry) ([]byte, error) {
+	entry.Time = time.Now().UTC()
+	entry.Hash = ""
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.Hash = hashLine(body)
+	return json.Marshal(entry)
+}
+
+// restartChain writes an EventChainRestarted entry to buf for a log whose
+// tail can't be chained to. Its PrevHash records the hash of that tail.
+func restartChain(file *os.File, buf *bytes.Buffer, torn bool) (Entry, error) {
+	tail, _, err := readTail(file)
+	if err != nil {
+		return Entry{}, err
+	}
+	restart := Entry{
+		Seq:      1,
+		Event:    EventChainRestarted,
+		Detail:   fmt.Sprintf("no intact entry in the last %d bytes", len(tail)),
+		PrevHash: restartPrefix + hashLine(tail),
+	}
+	line, err := sealEntry(&restart)
+	if err != nil {
+		return Entry{}, err
+	}
+	if torn {
+		buf.WriteByte('\n')
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	return restart, nil
+}
+
+// readTail reads up to tailSize bytes from the end of the file and the
+// offset they start at
+func readTail(file *os.File) ([]byte, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	start := size - tailSize
+	if start < 0 {
+		start = 0
+	}
+	tail := make([]byte, size-start)
+	if _, err := file.ReadAt(tail, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	return tail, start, nil
+}
+
+// lastEntry returns the last intact entry of the file, and whether the
+// file ends in a partial line. An empty file gives the zero Entry; a tail
+// without an intact entry gives errNoIntactEntry.
+func lastEntry(file *os.File) (Entry, bool, error) {
+	tail, start, err := readTail(file)
+	if err != nil || len(tail) == 0 {
+		return Entry{}, false, err
+	}
+	torn := tail[len(tail)-1] != '\n'
+
+	lines := bytes.Split(bytes.TrimRight(tail, "\n"), []byte("\n"))
+	if start > 0 {
+		// The first line was cut by the read
+		lines = lines[1:]
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		var entry Entry
+		if json.Unmarshal(lines[i], &entry) == nil && entry.Hash != "" {
+			return entry, torn, nil
+		}
+	}
+	return Entry{}, torn, errNoIntactEntry
+}
+
+// splitLine separates a line into the JSON its hash covers and the hash
+func splitLine(line []byte) ([]byte, string, bool) {
+	i := bytes.LastIndex(line, []byte(hashField))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", false
+	}
+	body := append(append([]byte{}, line[:i]...), '}')
+	return body, string(line[i+len(hashField) : len(line)-2]), true
+}
+
+func hashLine(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openTestLog(t *testing.T) (*Log, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l, path
+}
+
+func appendEntries(t *testing.T, l *Log, n int) []Entry {
+	t.Helper()
+	var entries []Entry
+	for i := 0; i < n; i++ {
+		entry, err := l.Append(Entry{Event: EventConnectionOpened, Host: "example.com"})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func verify(t *testing.T, l *Log) Verification {
+	t.Helper()
+	result, err := l.Verify()
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	return result
+}
+
+func TestHashChain(t *testing.T) {
+	l, _ := openTestLog(t)
+	entries := appendEntries(t, l, 3)
+
+	for i, entry := range entries {
+		if entry.Seq != int64(i+1) {
+			t.Errorf("entry %d has sequence %d", i, entry.Seq)
+		}
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			t.Errorf("entry %d does not link to the one before", i)
+		}
+	}
+	result := verify(t, l)
+	if !result.Valid || result.Entries != 3 || result.HeadHash != entries[2].Hash {
+		t.Errorf("Verify = %+v, want a valid chain of 3 ending in %s", result, entries[2].Hash)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		line   int
+	}{
+		{
+			name: "edited entry",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte("example.com"), []byte("example.org"), 1)
+				return lines
+			},
+			line: 2,
+		},
+		{
+			name: "removed entry",
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			line: 2,
+		},
+		{
+			name: "reordered entries",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			line: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, path := openTestLog(t)
+			appendEntries(t, l, 3)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tt.tamper(bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")))
+			if err := os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			result := verify(t, l)
+			if result.Valid || result.BrokenAt != tt.line {
+				t.Errorf("Verify = %+v, want broken at line %d", result, tt.line)
+			}
+		})
+	}
+}
+
+func TestAppendAfterTornLine(t *testing.T) {
+	l, path := openTestLog(t)
+	entries := appendEntries(t, l, 2)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":3,"time":"2026-01-`)
+	file.Close()
+
+	entry := appendEntries(t, l, 1)[0]
+	if entry.Seq != 3 || entry.PrevHash != entries[1].Hash {
+		t.Errorf("entry after the torn line = seq %d prev %s, want it chained to seq 2", entry.Seq, entry.PrevHash)
+	}
+	if result := verify(t, l); result.Valid || result.BrokenAt != 3 {
+		t.Errorf("Verify = %+v, want the torn line 3 reported", result)
+	}
+}
+
+func TestAppendRestartsChainWithoutIntactTail(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "torn only line", data: `{"seq":1,"time":"2026-01-`},
+		{name: "torn line longer than the tail", data: `{"seq":1,"detail":"` + strings.Repeat("x", tailSize+1)},
+		{name: "unhashed line", data: `{"seq":1,"event":"vault.unlock"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, path := openTestLog(t)
+			if err := os.WriteFile(path, []byte(tt.data), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			first := appendEntries(t, l, 1)[0]
+			second := appendEntries(t, l, 1)[0]
+			if second.PrevHash != first.Hash || second.Seq != first.Seq+1 {
+				t.Error("entries after the restart are not chained")
+			}
+
+			restarts, err := l.Query(Query{Events: []Event{EventChainRestarted}})
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if len(restarts) != 1 || !strings.HasPrefix(restarts[0].PrevHash, restartPrefix) || first.PrevHash != restarts[0].Hash {
+				t.Fatalf("restart entries = %+v, want one the new entries chain to", restarts)
+			}
+			if result := verify(t, l); result.Valid || result.BrokenAt != 1 {
+				t.Errorf("Verify = %+v, want the break at line 1 reported", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// maxLineSize bounds a single line when reading the log back
+const maxLineSize = 1 << 20
+
+// Query selects audit entries. Zero fields match everything.
+type Query struct {
+	Events []Event   `json:"events,omitempty"`
+	Host   string    `json:"host,omitempty"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Limit  int       `json:"limit,omitempty"` // the newest entries are kept
+}
+
+// Verification is the result of checking the hash chain
+type Verification struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// HeadHash is the hash of the last entry. Noting it down lets a later
+	// check tell that entries were cut off the end, which the chain alone
+	// can't show.
+	HeadHash string `json:"headHash"`
+	// BrokenAt is the line number of the first entry that fails the check
+	BrokenAt int    `json:"brokenAt,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Query returns the matching entries, newest first. Lines that don't
+// parse are skipped; Verify reports them.
+func (l *Log) Query(query Query) ([]Entry, error) {
+	events := make(map[Event]bool, len(query.Events))
+	for _, event := range query.Events {
+		events[event] = true
+	}
+
+	entries := []Entry{}
+	err := l.scan(func(line []byte) error {
+		var entry Entry
+		if json.Unmarshal(line, &entry) != nil {
+			return nil
+		}
+		switch {
+		case len(events) > 0 && !events[entry.Event],
+			query.Host != "" && entry.Host != query.Host,
+			!query.Since.IsZero() && entry.Time.Before(query.Since),
+			!query.Until.IsZero() && entry.Time.After(query.Until):
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	return entries, nil
+}
+
+// Verify walks the whole chain: every line must hash to its hash field,
+// link to the line before and carry the next sequence number
+func (l *Log) Verify() (Verification, error) {
+	var result Verification
+	var previous Entry
+
+	err := l.scan(func(line []byte) error {
+		body, hash, ok := splitLine(line)
+		if !ok {
+			return errors.New("missing hash")
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		switch {
+		case hashLine(body) != hash:
+			return errors.New("hash mismatch, the entry was altered")
+		case entry.PrevHash != previous.Hash:
+			return errors.New("does not follow the previous entry")
+		case entry.Seq != previous.Seq+1:
+			return fmt.Errorf("expected sequence %d, found %d", previous.Seq+1, entry.Seq)
+		}
+		previous = entry
+		result.Entries++
+		return nil
+	})
+
+	var broken *brokenLine
+	if errors.As(err, &broken) {
+		result.BrokenAt = broken.lineNo
+		result.Error = broken.err.Error()
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Valid = true
+	result.HeadHash = previous.Hash
+	return result, nil
+}
+
+// Export returns the log as written, one JSON entry per line
+func (l *Log) Export() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []byte{}, nil
+	}
+	return data, err
+}
+
+// brokenLine is an error in the log's content rather than in reading it
+type brokenLine struct {
+	lineNo int
+	err    error
+}
+
+func (b *brokenLine) Error() string {
+	return b.err.Error()
+}
+
+// scan calls visit for each non-empty line. An error from visit stops the
+// scan and is returned as a *brokenLine with the 1-based line number.
+func (l *Log) scan(visit func(line []byte) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := visit(line); err != nil {
+			return &brokenLine{lineNo: lineNo, err: err}
+		}
+	}
+	return scanner.Err()
+}
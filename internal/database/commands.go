@@ -0,0 +1,233 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScopeGlobal is the scope of commands offered on every connection
+const ScopeGlobal = "global"
+
+// Command is a saved command, shaped like the frontend's Command
+type Command struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"userId"`
+	Title       string            `json:"title"`
+	Command     string            `json:"command"`
+	Description string            `json:"description,omitempty"`
+	Variables   []CommandVariable `json:"variables,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Scope       string            `json:"scope"` // ScopeGlobal or a connection ID
+	Version     int               `json:"version"`
+	DeletedAt   *time.Time        `json:"deletedAt,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// CommandVariable is a placeholder filled in when a command runs
+type CommandVariable struct {
+	Name         string `json:"name"`
+	DefaultValue string `json:"defaultValue,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+const commandColumns = `id, user_id, title, command, description, variables, tags, connection_id, version,
+	deleted_at, created_at, updated_at`
+
+// ListCommands returns a profile's commands in the order they were created
+func (d *DB) ListCommands(userID string) ([]Command, error) {
+	rows, err := d.db.Query(`SELECT `+commandColumns+` FROM commands WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands := []Command{}
+	for rows.Next() {
+		command, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+	return commands, rows.Err()
+}
+
+// GetCommand returns one of a profile's commands
+func (d *DB) GetCommand(userID, id string) (Command, error) {
+	return getCommand(d.db, userID, id)
+}
+
+// CreateCommand adds a command to command.UserID's profile
+func (d *DB) CreateCommand(command Command) (created Command, err error) {
+	err = d.Update(func(tx *Tx) error {
+		created, err = tx.CreateCommand(command)
+		return err
+	})
+	return created, err
+}
+
+// UpdateCommand saves changes to a command
+func (d *DB) UpdateCommand(command Command) (updated Command, err error) {
+	err = d.Update(func(tx *Tx) error {
+		updated, err = tx.UpdateCommand(command)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteCommand removes a command
+func (d *DB) DeleteCommand(userID, id string) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.DeleteCommand(userID, id)
+	})
+}
+
+// CreateCommand adds a command, filling in the ID, timestamps and version
+// like CreateConnection
+func (tx *Tx) CreateCommand(command Command) (Command, error) {
+	connectionID, err := tx.commandScope(command)
+	if err != nil {
+		return Command{}, err
+	}
+	if command.ID == "" {
+		command.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+	if command.CreatedAt.IsZero() {
+		command.CreatedAt = now
+	}
+	if command.UpdatedAt.IsZero() {
+		command.UpdatedAt = command.CreatedAt
+	}
+	if command.Version < 1 {
+		command.Version = 1
+	}
+
+	variables, tags, err := marshalCommandFields(command)
+	if err != nil {
+		return Command{}, err
+	}
+	_, err = tx.tx.Exec(`INSERT INTO commands (`+commandColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		command.ID, command.UserID, command.Title, command.Command, command.Description, variables, tags,
+		connectionID, command.Version, formatNullTime(command.DeletedAt), formatTime(command.CreatedAt),
+		formatTime(command.UpdatedAt))
+	if err != nil {
+		return Command{}, fmt.Errorf("failed to create command: %w", err)
+	}
+	return getCommand(tx.tx, command.UserID, command.ID)
+}
+
+// UpdateCommand saves command over the stored command, checking its version
+// like UpdateConnection
+func (tx *Tx) UpdateCommand(command Command) (Command, error) {
+	connectionID, err := tx.commandScope(command)
+	if err != nil {
+		return Command{}, err
+	}
+	variables, tags, err := marshalCommandFields(command)
+	if err != nil {
+		return Command{}, err
+	}
+
+	result, err := tx.tx.Exec(`UPDATE commands SET title = ?, command = ?, description = ?, variables = ?,
+		tags = ?, connection_id = ?, deleted_at = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND user_id = ? AND version = ?`,
+		command.Title, command.Command, command.Description, variables, tags, connectionID,
+		formatNullTime(command.DeletedAt), formatTime(time.Now()), command.ID, command.UserID, command.Version)
+	if err := affectedOne(result, err, ErrConflict); err != nil {
+		return Command{}, tx.missingOr(err, "commands", command.UserID, command.ID)
+	}
+	return getCommand(tx.tx, command.UserID, command.ID)
+}
+
+// DeleteCommand removes a command
+func (tx *Tx) DeleteCommand(userID, id string) error {
+	result, err := tx.tx.Exec(`DELETE FROM commands WHERE id = ? AND user_id = ?`, id, userID)
+	return affectedOne(result, err, ErrNotFound)
+}
+
+// commandScope validates a command and returns the connection it is scoped
+// to, nil for global commands. The connection must be in the same profile.
+func (tx *Tx) commandScope(command Command) (any, error) {
+	switch {
+	case strings.TrimSpace(command.Title) == "":
+		return nil, errors.New("command title is required")
+	case strings.TrimSpace(command.Command) == "":
+		return nil, errors.New("command is required")
+	case command.Scope == "" || command.Scope == ScopeGlobal:
+		return nil, nil
+	}
+	found, err := exists(tx.tx, "connections", command.UserID, command.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("command scope %s: connection %w", command.Scope, ErrNotFound)
+	}
+	return command.Scope, nil
+}
+
+func getCommand(q querier, userID, id string) (Command, error) {
+	command, err := scanCommand(q.QueryRow(`SELECT `+commandColumns+` FROM commands WHERE id = ? AND user_id = ?`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Command{}, ErrNotFound
+	}
+	return command, err
+}
+
+func scanCommand(row scanner) (Command, error) {
+	var command Command
+	var variables, tags, createdAt, updatedAt string
+	var connectionID, deletedAt sql.NullString
+	err := row.Scan(&command.ID, &command.UserID, &command.Title, &command.Command, &command.Description,
+		&variables, &tags, &connectionID, &command.Version, &deletedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return Command{}, err
+	}
+
+	command.Scope = ScopeGlobal
+	if connectionID.Valid {
+		command.Scope = connectionID.String
+	}
+	if err := json.Unmarshal([]byte(variables), &command.Variables); err != nil {
+		return Command{}, fmt.Errorf("command %s: bad variables: %w", command.ID, err)
+	}
+	if err := json.Unmarshal([]byte(tags), &command.Tags); err != nil {
+		return Command{}, fmt.Errorf("command %s: bad tags: %w", command.ID, err)
+	}
+	if command.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+		return Command{}, err
+	}
+	if command.CreatedAt, err = parseTime(createdAt); err != nil {
+		return Command{}, err
+	}
+	if command.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return Command{}, err
+	}
+	return command, nil
+}
+
+func marshalCommandFields(command Command) (variables, tags string, err error) {
+	if command.Variables == nil {
+		command.Variables = []CommandVariable{}
+	}
+	if command.Tags == nil {
+		command.Tags = []string{}
+	}
+	variablesJSON, err := json.Marshal(command.Variables)
+	if err != nil {
+		return "", "", err
+	}
+	tagsJSON, err := json.Marshal(command.Tags)
+	if err != nil {
+		return "", "", err
+	}
+	return string(variablesJSON), string(tagsJSON), nil
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Connection is a saved SSH connection, shaped like the frontend's
+// SSHConnection
+type Connection struct {
+	ID                  string                 `json:"id"`
+	UserID              string                 `json:"userId"`
+	Name                string                 `json:"name"`
+	Host                string                 `json:"host"`
+	Port                int                    `json:"port"`
+	Username            string                 `json:"username"`
+	PrivateKeyEncrypted string                 `json:"privateKeyEncrypted,omitempty"`
+	PublicKey           string                 `json:"publicKey,omitempty"`
+	PasswordEncrypted   string                 `json:"passwordEncrypted,omitempty"`
+	ConnectionConfig    map[string]interface{} `json:"connectionConfig,omitempty"`
+	ImportSource        string                 `json:"importSource,omitempty"` // openssh, putty, termius or manual
+	Tags                []string               `json:"tags,omitempty"`
+	IsFavorite          bool                   `json:"isFavorite"`
+	Version             int                    `json:"version"`
+	DeletedAt           *time.Time             `json:"deletedAt,omitempty"`
+	CreatedAt           time.Time              `json:"createdAt"`
+	UpdatedAt           time.Time              `json:"updatedAt"`
+}
+
+const connectionColumns = `id, user_id, name, host, port, username, private_key_encrypted, public_key,
+	password_encrypted, connection_config, import_source, tags, is_favorite, version, deleted_at,
+	created_at, updated_at`
+
+// ListConnections returns a profile's connections, sorted by name
+func (d *DB) ListConnections(userID string) ([]Connection, error) {
+	rows, err := d.db.Query(`SELECT `+connectionColumns+` FROM connections WHERE user_id = ? ORDER BY name COLLATE NOCASE, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conns := []Connection{}
+	for rows.Next() {
+		conn, err := scanConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, conn)
+	}
+	return conns, rows.Err()
+}
+
+// GetConnection returns one of a profile's connections
+func (d *DB) GetConnection(userID, id string) (Connection, error) {
+	return getConnection(d.db, userID, id)
+}
+
+// CreateConnection adds a connection to conn.UserID's profile
+func (d *DB) CreateConnection(conn Connection) (created Connection, err error) {
+	err = d.Update(func(tx *Tx) error {
+		created, err = tx.CreateConnection(conn)
+		return err
+	})
+	return created, err
+}
+
+// UpdateConnection saves changes to a connection
+func (d *DB) UpdateConnection(conn Connection) (updated Connection, err error) {
+	err = d.Update(func(tx *Tx) error {
+		updated, err = tx.UpdateConnection(conn)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteConnection removes a connection, with the commands scoped to it
+// and its keychain associations
+func (d *DB) DeleteConnection(userID, id string) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.DeleteConnection(userID, id)
+	})
+}
+
+// CreateConnection adds a connection. The ID is generated unless given;
+// timestamps default to now and the version to 1, so imported records
+// keep theirs.
+func (tx *Tx) CreateConnection(conn Connection) (Connection, error) {
+	if err := validateConnection(conn); err != nil {
+		return Connection{}, err
+	}
+	if conn.ID == "" {
+		conn.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+	if conn.CreatedAt.IsZero() {
+		conn.CreatedAt = now
+	}
+	if conn.UpdatedAt.IsZero() {
+		conn.UpdatedAt = conn.CreatedAt
+	}
+	if conn.Version < 1 {
+		conn.Version = 1
+	}
+
+	config, tags, err := marshalConnectionFields(conn)
+	if err != nil {
+		return Connection{}, err
+	}
+	_, err = tx.tx.Exec(`INSERT INTO connections (`+connectionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		conn.ID, conn.UserID, conn.Name, conn.Host, conn.Port, conn.Username, conn.PrivateKeyEncrypted,
+		conn.PublicKey, conn.PasswordEncrypted, config, conn.ImportSource, tags, conn.IsFavorite,
+		conn.Version, formatNullTime(conn.DeletedAt), formatTime(conn.CreatedAt), formatTime(conn.UpdatedAt))
+	if err != nil {
+		return Connection{}, fmt.Errorf("failed to create connection: %w", err)
+	}
+	return getConnection(tx.tx, conn.UserID, conn.ID)
+}
+
+// UpdateConnection saves conn over the stored connection. conn.Version must
+// be the stored version, else ErrConflict; it is bumped on success.
+func (tx *Tx) UpdateConnection(conn Connection) (Connection, error) {
+	if err := validateConnection(conn); err != nil {
+		return Connection{}, err
+	}
+	config, tags, err := marshalConnectionFields(conn)
+	if err != nil {
+		return Connection{}, err
+	}
+
+	result, err := tx.tx.Exec(`UPDATE connections SET name = ?, host = ?, port = ?, username = ?,
+		private_key_encrypted = ?, public_key = ?, password_encrypted = ?, connection_config = ?,
+		import_source = ?, tags = ?, is_favorite = ?, deleted_at = ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND user_id = ? AND version = ?`,
+		conn.Name, conn.Host, conn.Port, conn.Username, conn.PrivateKeyEncrypted, conn.PublicKey,
+		conn.PasswordEncrypted, config, conn.ImportSource, tags, conn.IsFavorite,
+		formatNullTime(conn.DeletedAt), formatTime(time.Now()), conn.ID, conn.UserID, conn.Version)
+	if err := affectedOne(result, err, ErrConflict); err != nil {
+		return Connection{}, tx.missingOr(err, "connections", conn.UserID, conn.ID)
+	}
+	return getConnection(tx.tx, conn.UserID, conn.ID)
+}
+
+// DeleteConnection removes a connection; foreign keys remove what
+// depends on it
+func (tx *Tx) DeleteConnection(userID, id string) error {
+	result, err := tx.tx.Exec(`DELETE FROM connections WHERE id = ? AND user_id = ?`, id, userID)
+	return affectedOne(result, err, ErrNotFound)
+}
+
+// missingOr tells a record that is gone from one that changed: an UPDATE
+// matching nothing returns ErrNotFound if the record doesn't exist, else err
+func (tx *Tx) missingOr(err error, table, userID, id string) error {
+	if !errors.Is(err, ErrConflict) {
+		return err
+	}
+	found, existsErr := exists(tx.tx, table, userID, id)
+	if existsErr != nil {
+		return existsErr
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return err
+}
+
+func getConnection(q querier, userID, id string) (Connection, error) {
+	conn, err := scanConnection(q.QueryRow(`SELECT `+connectionColumns+` FROM connections WHERE id = ? AND user_id = ?`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Connection{}, ErrNotFound
+	}
+	return conn, err
+}
+
+func scanConnection(row scanner) (Connection, error) {
+	var conn Connection
+	var config, tags, createdAt, updatedAt string
+	var deletedAt sql.NullString
+	err := row.Scan(&conn.ID, &conn.UserID, &conn.Name, &conn.Host, &conn.Port, &conn.Username,
+		&conn.PrivateKeyEncrypted, &conn.PublicKey, &conn.PasswordEncrypted, &config, &conn.ImportSource,
+		&tags, &conn.IsFavorite, &conn.Version, &deletedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return Connection{}, err
+	}
+
+	if err := json.Unmarshal([]byte(config), &conn.ConnectionConfig); err != nil {
+		return Connection{}, fmt.Errorf("connection %s: bad connection config: %w", conn.ID, err)
+	}
+	if err := json.Unmarshal([]byte(tags), &conn.Tags); err != nil {
+		return Connection{}, fmt.Errorf("connection %s: bad tags: %w", conn.ID, err)
+	}
+	if conn.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+		return Connection{}, err
+	}
+	if conn.CreatedAt, err = parseTime(createdAt); err != nil {
+		return Connection{}, err
+	}
+	if conn.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return Connection{}, err
+	}
+	return conn, nil
+}
+
+func marshalConnectionFields(conn Connection) (config, tags string, err error) {
+	if conn.ConnectionConfig == nil {
+		conn.ConnectionConfig = map[string]interface{}{}
+	}
+	if conn.Tags == nil {
+		conn.Tags = []string{}
+	}
+	configJSON, err := json.Marshal(conn.ConnectionConfig)
+	if err != nil {
+		return "", "", fmt.Errorf("bad connection config: %w", err)
+	}
+	tagsJSON, err := json.Marshal(conn.Tags)
+	if err != nil {
+		return "", "", err
+	}
+	return string(configJSON), string(tagsJSON), nil
+}
+
+func validateConnection(conn Connection) error {
+	switch {
+	case strings.TrimSpace(conn.Name) == "":
+		return errors.New("connection name is required")
+	case strings.TrimSpace(conn.Host) == "":
+		return errors.New("connection host is required")
+	case conn.Port < 1 || conn.Port > 65535:
+		return fmt.Errorf("invalid port: %d", conn.Port)
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"host-vault/internal/storage"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registered as "sqlite"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	ErrConflict = errors.New("record changed since it was read")
+)
+
+// pragmas are applied to every pooled connection: foreign keys are off by
+// default in SQLite, and WAL plus a busy timeout let several app instances
+// share the file. Write transactions take the lock up front so two of them
+// can't deadlock upgrading from a read.
+const pragmas = "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+
+// BackupPrefix starts the file names of database backups
+const BackupPrefix = "main-"
+
+// BackupName returns a file name for a backup taken now. Names sort by
+// time; reason, if given, says why the backup was taken.
+func BackupName(reason string) string {
+	name := BackupPrefix + time.Now().UTC().Format("20060102-150405.000")
+	if reason != "" {
+		name += "-" + reason
+	}
+	return name + ".db"
+}
+
+// DB is the app's SQLite database of connections, commands and keychains.
+// Every record belongs to a profile: a user ID, or "" for guest mode.
+type DB struct {
+	db   *sql.DB
+	path string
+}
+
+// querier is what reads need, satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// scanner is a row being read, from QueryRow or Query
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Tx is a write transaction. Its methods see the transaction's own
+// changes.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// Open opens or creates the database at path and migrates its schema to
+// the current version, backing it up into backupDir first
+func Open(path, backupDir string) (*DB, error) {
+	if strings.Contains(path, "?") {
+		return nil, fmt.Errorf("database path must not contain '?': %s", path)
+	}
+	if err := storage.EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path+pragmas)
+	if err != nil {
+		return nil, err
+	}
+	d := &DB{db: db, path: path}
+	err = migrate(db, func(from int) error {
+		name := BackupName(fmt.Sprintf("pre-migration-v%d", from))
+		if err := d.Backup(filepath.Join(backupDir, name)); err != nil {
+			return err
+		}
+		log.Printf("[DB] Backed up database to %s before migrating", name)
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return d, nil
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Path returns the database file
+func (d *DB) Path() string {
+	return d.path
+}
+
+// Update runs fn in a transaction, committed if fn returns nil and rolled
+// back otherwise
+func (d *DB) Update(fn func(tx *Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&Tx{tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Validate runs SQLite's integrity and foreign key checks and returns the
+// problems found; none means the database is sound
+func (d *DB) Validate() ([]string, error) {
+	problems := []string{}
+
+	rows, err := d.db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = d.db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d refers to a missing %s record", table, rowID.Int64, parent))
+	}
+	return problems, rows.Err()
+}
+
+// Repair rebuilds the indexes, removes rows whose references are gone and
+// compacts the file. It returns the problems Validate still finds.
+func (d *DB) Repair() ([]string, error) {
+	err := d.Update(func(tx *Tx) error {
+		for _, stmt := range []string{
+			`REINDEX`,
+			`DELETE FROM commands WHERE connection_id IS NOT NULL AND connection_id NOT IN (SELECT id FROM connections)`,
+			`DELETE FROM keychain_connections WHERE keychain_id NOT IN (SELECT id FROM keychains) OR connection_id NOT IN (SELECT id FROM connections)`,
+		} {
+			if _, err := tx.tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to repair database: %w", err)
+	}
+	if _, err := d.db.Exec(`VACUUM`); err != nil {
+		return nil, fmt.Errorf("failed to compact database: %w", err)
+	}
+	return d.Validate()
+}
+
+// Backup writes a consistent copy of the database to path, which must not
+// exist yet
+func (d *DB) Backup(path string) error {
+	if err := storage.EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s already exists", filepath.Base(path))
+	}
+	if _, err := d.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces every record with those in the backup at path, in one
+// transaction. The backup is left as it was: a copy of it is migrated to
+// the current schema and restored from.
+func (d *DB) Restore(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("backup unavailable: %w", err)
+	}
+	path, err = migratedCopy(filepath.Dir(d.path), data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	// ATTACH belongs to a connection, so everything runs on one
+	conn, err := d.db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), `ATTACH DATABASE ? AS backup`, path); err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `DETACH DATABASE backup`)
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	// Children first when clearing, parents first when filling
+	for _, stmt := range []string{
+		`DELETE FROM main.keychain_connections`,
+		`DELETE FROM main.keychains`,
+		`DELETE FROM main.commands`,
+		`DELETE FROM main.connections`,
+		`INSERT INTO main.connections SELECT * FROM backup.connections`,
+		`INSERT INTO main.commands SELECT * FROM backup.commands`,
+		`INSERT INTO main.keychains SELECT * FROM backup.keychains`,
+		`INSERT INTO main.keychain_connections SELECT * FROM backup.keychain_connections`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// migratedCopy writes a backup's data to a temp file in dir and migrates
+// it, returning the file's path
+func migratedCopy(dir string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(dir, ".restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	path := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	if err == nil {
+		err = migrate(db, nil)
+		if closeErr := db.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to migrate backup: %w", err)
+	}
+	return path, nil
+}
+
+// timeFormat stores timestamps as RFC 3339 text in UTC with a fixed number
+// of fractional digits, so they sort as text
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func formatNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func parseNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// exists reports whether a row with id belongs to userID in table
+func exists(q querier, table, userID, id string) (bool, error) {
+	var found int
+	err := q.QueryRow(`SELECT 1 FROM `+table+` WHERE id = ? AND user_id = ?`, id, userID).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// affectedOne turns an UPDATE or DELETE that matched nothing into err
+func affectedOne(result sql.Result, err error, none error) error {
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return none
+	}
+	return nil
+}
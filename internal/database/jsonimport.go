@@ -0,0 +1,219 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"host-vault/internal/storage"
+	"log"
+	"os"
+	"time"
+)
+
+// metaJSONImported records when the JSON files were imported
+const metaJSONImported = "json-imported-at"
+
+// ImportResult counts what ImportJSON copied
+type ImportResult struct {
+	Profiles    int `json:"profiles"`
+	Connections int `json:"connections"`
+	Commands    int `json:"commands"`
+}
+
+// importedConnection reads a connection as the frontend wrote it, with
+// timestamps that may be missing
+type importedConnection struct {
+	Connection
+	DeletedAt looseTime `json:"deletedAt"`
+	CreatedAt looseTime `json:"createdAt"`
+	UpdatedAt looseTime `json:"updatedAt"`
+}
+
+// importedSnippet is a command as the snippets panel wrote it
+type importedSnippet struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Command     string    `json:"command"`
+	Description string    `json:"description"`
+	CreatedAt   looseTime `json:"createdAt"`
+}
+
+// looseTime accepts an RFC 3339 string or Unix milliseconds, as JSON
+// written by JavaScript holds both
+type looseTime struct {
+	time.Time
+}
+
+func (t *looseTime) UnmarshalJSON(data []byte) error {
+	var millis int64
+	if json.Unmarshal(data, &millis) == nil {
+		t.Time = time.UnixMilli(millis).UTC()
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil
+	}
+	t.Time = parsed.UTC()
+	return nil
+}
+
+// ImportJSON copies the connections and commands the frontend kept in
+// JSON files under root, for guest mode and every user, into the database.
+// It only runs once: after it succeeds, later calls import nothing. The
+// files are left in place. Records whose ID is already in the database
+// are skipped, and so are files that don't parse.
+func (d *DB) ImportJSON(root string) (ImportResult, error) {
+	var result ImportResult
+	err := d.Update(func(tx *Tx) error {
+		var importedAt string
+		err := tx.tx.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaJSONImported).Scan(&importedAt)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		scope := storage.NewScope(root)
+		profiles, err := scope.Profiles()
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			connections, err := tx.importConnections(scope, profile)
+			if err != nil {
+				return err
+			}
+			commands, err := tx.importSnippets(scope, profile)
+			if err != nil {
+				return err
+			}
+			if connections+commands > 0 {
+				result.Profiles++
+			}
+			result.Connections += connections
+			result.Commands += commands
+		}
+
+		_, err = tx.tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)`, metaJSONImported, formatTime(time.Now()))
+		return err
+	})
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("failed to import JSON files: %w", err)
+	}
+	return result, nil
+}
+
+// readJSONStore reads a profile's single-file store into v. It returns
+// false if the file is missing or, after logging why, doesn't parse.
+func readJSONStore(scope *storage.Scope, store storage.Store, profile string, v any) bool {
+	path, err := scope.Path(store, profile, "")
+	if err != nil {
+		log.Printf("[DB] Skipping %s of profile %q: %v", store, profile, err)
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		log.Printf("[DB] Skipping %s: %v", path, err)
+		return false
+	}
+	return true
+}
+
+func (tx *Tx) importConnections(scope *storage.Scope, profile string) (int, error) {
+	var file struct {
+		Connections []importedConnection `json:"connections"`
+	}
+	if !readJSONStore(scope, storage.StoreConnections, profile, &file) {
+		return 0, nil
+	}
+
+	imported := 0
+	for _, record := range file.Connections {
+		conn := record.Connection
+		conn.UserID = profile
+		conn.CreatedAt, conn.UpdatedAt = record.CreatedAt.Time, record.UpdatedAt.Time
+		if !record.DeletedAt.IsZero() {
+			conn.DeletedAt = &record.DeletedAt.Time
+		}
+		if conn.Port == 0 {
+			conn.Port = 22
+		}
+		taken, err := tx.idTaken("connections", conn.ID)
+		if err != nil {
+			return 0, err
+		}
+		if taken {
+			continue
+		}
+		if err := validateConnection(conn); err != nil {
+			log.Printf("[DB] Skipping connection %s of profile %q: %v", conn.ID, profile, err)
+			continue
+		}
+		if _, err := tx.CreateConnection(conn); err != nil {
+			return 0, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+func (tx *Tx) importSnippets(scope *storage.Scope, profile string) (int, error) {
+	var file struct {
+		Snippets []importedSnippet `json:"snippets"`
+	}
+	if !readJSONStore(scope, storage.StoreCommands, profile, &file) {
+		return 0, nil
+	}
+
+	imported := 0
+	for _, snippet := range file.Snippets {
+		command := Command{
+			ID:          snippet.ID,
+			UserID:      profile,
+			Title:       snippet.Name,
+			Command:     snippet.Command,
+			Description: snippet.Description,
+			Scope:       ScopeGlobal,
+			CreatedAt:   snippet.CreatedAt.Time,
+		}
+		taken, err := tx.idTaken("commands", command.ID)
+		if err != nil {
+			return 0, err
+		}
+		if taken {
+			continue
+		}
+		if command.Title == "" || command.Command == "" {
+			log.Printf("[DB] Skipping snippet %s of profile %q: missing name or command", command.ID, profile)
+			continue
+		}
+		if _, err := tx.CreateCommand(command); err != nil {
+			return 0, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// idTaken reports whether any profile has a row with id in table
+func (tx *Tx) idTaken(table, id string) (bool, error) {
+	var found int
+	err := tx.tx.QueryRow(`SELECT 1 FROM `+table+` WHERE id = ?`, id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,274 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Keychain types
+const (
+	KeychainSSHKey   = "ssh_key"
+	KeychainPassword = "password"
+	KeychainToken    = "token"
+)
+
+// Keychain is a stored secret, shaped like the frontend's Keychain
+type Keychain struct {
+	ID            string                 `json:"id"`
+	UserID        string                 `json:"userId"`
+	Type          string                 `json:"type"`
+	DataEncrypted string                 `json:"dataEncrypted"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"` // algorithm, fingerprint, keySize...
+	// AssociatedConnections are IDs of connections in the same profile
+	AssociatedConnections []string   `json:"associatedConnections,omitempty"`
+	Version               int        `json:"version"`
+	DeletedAt             *time.Time `json:"deletedAt,omitempty"`
+	CreatedAt             time.Time  `json:"createdAt"`
+	UpdatedAt             time.Time  `json:"updatedAt"`
+}
+
+const keychainColumns = `id, user_id, type, data_encrypted, metadata, version, deleted_at, created_at, updated_at`
+
+// ListKeychains returns a profile's keychains in the order they were
+// created
+func (d *DB) ListKeychains(userID string) ([]Keychain, error) {
+	associations, err := keychainAssociations(d.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`SELECT `+keychainColumns+` FROM keychains WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keychains := []Keychain{}
+	for rows.Next() {
+		keychain, err := scanKeychain(rows)
+		if err != nil {
+			return nil, err
+		}
+		keychain.AssociatedConnections = associations[keychain.ID]
+		keychains = append(keychains, keychain)
+	}
+	return keychains, rows.Err()
+}
+
+// GetKeychain returns one of a profile's keychains
+func (d *DB) GetKeychain(userID, id string) (Keychain, error) {
+	return getKeychain(d.db, userID, id)
+}
+
+// CreateKeychain adds a keychain to keychain.UserID's profile
+func (d *DB) CreateKeychain(keychain Keychain) (created Keychain, err error) {
+	err = d.Update(func(tx *Tx) error {
+		created, err = tx.CreateKeychain(keychain)
+		return err
+	})
+	return created, err
+}
+
+// UpdateKeychain saves changes to a keychain
+func (d *DB) UpdateKeychain(keychain Keychain) (updated Keychain, err error) {
+	err = d.Update(func(tx *Tx) error {
+		updated, err = tx.UpdateKeychain(keychain)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteKeychain removes a keychain and its associations
+func (d *DB) DeleteKeychain(userID, id string) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.DeleteKeychain(userID, id)
+	})
+}
+
+// CreateKeychain adds a keychain with its associations, filling in the ID,
+// timestamps and version like CreateConnection
+func (tx *Tx) CreateKeychain(keychain Keychain) (Keychain, error) {
+	if err := validateKeychain(keychain); err != nil {
+		return Keychain{}, err
+	}
+	if keychain.ID == "" {
+		keychain.ID = uuid.NewString()
+	}
+	now := time.Now().UTC()
+	if keychain.CreatedAt.IsZero() {
+		keychain.CreatedAt = now
+	}
+	if keychain.UpdatedAt.IsZero() {
+		keychain.UpdatedAt = keychain.CreatedAt
+	}
+	if keychain.Version < 1 {
+		keychain.Version = 1
+	}
+
+	metadata, err := marshalMetadata(keychain.Metadata)
+	if err != nil {
+		return Keychain{}, err
+	}
+	_, err = tx.tx.Exec(`INSERT INTO keychains (`+keychainColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		keychain.ID, keychain.UserID, keychain.Type, keychain.DataEncrypted, metadata, keychain.Version,
+		formatNullTime(keychain.DeletedAt), formatTime(keychain.CreatedAt), formatTime(keychain.UpdatedAt))
+	if err != nil {
+		return Keychain{}, fmt.Errorf("failed to create keychain: %w", err)
+	}
+	if err := tx.setAssociations(keychain); err != nil {
+		return Keychain{}, err
+	}
+	return getKeychain(tx.tx, keychain.UserID, keychain.ID)
+}
+
+// UpdateKeychain saves keychain, associations included, over the stored
+// keychain, checking its version like UpdateConnection
+func (tx *Tx) UpdateKeychain(keychain Keychain) (Keychain, error) {
+	if err := validateKeychain(keychain); err != nil {
+		return Keychain{}, err
+	}
+	metadata, err := marshalMetadata(keychain.Metadata)
+	if err != nil {
+		return Keychain{}, err
+	}
+
+	result, err := tx.tx.Exec(`UPDATE keychains SET type = ?, data_encrypted = ?, metadata = ?, deleted_at = ?,
+		version = version + 1, updated_at = ?
+		WHERE id = ? AND user_id = ? AND version = ?`,
+		keychain.Type, keychain.DataEncrypted, metadata, formatNullTime(keychain.DeletedAt), formatTime(time.Now()),
+		keychain.ID, keychain.UserID, keychain.Version)
+	if err := affectedOne(result, err, ErrConflict); err != nil {
+		return Keychain{}, tx.missingOr(err, "keychains", keychain.UserID, keychain.ID)
+	}
+	if err := tx.setAssociations(keychain); err != nil {
+		return Keychain{}, err
+	}
+	return getKeychain(tx.tx, keychain.UserID, keychain.ID)
+}
+
+// DeleteKeychain removes a keychain
+func (tx *Tx) DeleteKeychain(userID, id string) error {
+	result, err := tx.tx.Exec(`DELETE FROM keychains WHERE id = ? AND user_id = ?`, id, userID)
+	return affectedOne(result, err, ErrNotFound)
+}
+
+// setAssociations replaces the connections a keychain is associated with,
+// which must be in the same profile
+func (tx *Tx) setAssociations(keychain Keychain) error {
+	if _, err := tx.tx.Exec(`DELETE FROM keychain_connections WHERE keychain_id = ?`, keychain.ID); err != nil {
+		return err
+	}
+	for _, connectionID := range keychain.AssociatedConnections {
+		found, err := exists(tx.tx, "connections", keychain.UserID, connectionID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("associated connection %s: %w", connectionID, ErrNotFound)
+		}
+		_, err = tx.tx.Exec(`INSERT OR IGNORE INTO keychain_connections (keychain_id, connection_id) VALUES (?, ?)`,
+			keychain.ID, connectionID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keychainAssociations maps each of a profile's keychains to its
+// connections
+func keychainAssociations(q querier, userID string) (map[string][]string, error) {
+	rows, err := q.Query(`SELECT kc.keychain_id, kc.connection_id FROM keychain_connections kc
+		JOIN keychains k ON k.id = kc.keychain_id WHERE k.user_id = ? ORDER BY kc.connection_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	associations := make(map[string][]string)
+	for rows.Next() {
+		var keychainID, connectionID string
+		if err := rows.Scan(&keychainID, &connectionID); err != nil {
+			return nil, err
+		}
+		associations[keychainID] = append(associations[keychainID], connectionID)
+	}
+	return associations, rows.Err()
+}
+
+func getKeychain(q querier, userID, id string) (Keychain, error) {
+	keychain, err := scanKeychain(q.QueryRow(`SELECT `+keychainColumns+` FROM keychains WHERE id = ? AND user_id = ?`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Keychain{}, ErrNotFound
+	}
+	if err != nil {
+		return Keychain{}, err
+	}
+
+	rows, err := q.Query(`SELECT connection_id FROM keychain_connections WHERE keychain_id = ? ORDER BY connection_id`, id)
+	if err != nil {
+		return Keychain{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var connectionID string
+		if err := rows.Scan(&connectionID); err != nil {
+			return Keychain{}, err
+		}
+		keychain.AssociatedConnections = append(keychain.AssociatedConnections, connectionID)
+	}
+	return keychain, rows.Err()
+}
+
+func scanKeychain(row scanner) (Keychain, error) {
+	var keychain Keychain
+	var metadata, createdAt, updatedAt string
+	var deletedAt sql.NullString
+	err := row.Scan(&keychain.ID, &keychain.UserID, &keychain.Type, &keychain.DataEncrypted, &metadata,
+		&keychain.Version, &deletedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return Keychain{}, err
+	}
+
+	if err := json.Unmarshal([]byte(metadata), &keychain.Metadata); err != nil {
+		return Keychain{}, fmt.Errorf("keychain %s: bad metadata: %w", keychain.ID, err)
+	}
+	if keychain.DeletedAt, err = parseNullTime(deletedAt); err != nil {
+		return Keychain{}, err
+	}
+	if keychain.CreatedAt, err = parseTime(createdAt); err != nil {
+		return Keychain{}, err
+	}
+	if keychain.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return Keychain{}, err
+	}
+	return keychain, nil
+}
+
+func marshalMetadata(metadata map[string]interface{}) (string, error) {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("bad keychain metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+func validateKeychain(keychain Keychain) error {
+	switch keychain.Type {
+	case KeychainSSHKey, KeychainPassword, KeychainToken:
+	default:
+		return fmt.Errorf("invalid keychain type: %q", keychain.Type)
+	}
+	if keychain.DataEncrypted == "" {
+		return errors.New("keychain data is required")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrNewerSchema is returned for a database migrated by a newer release,
+// whose schema this one doesn't know
+var ErrNewerSchema = errors.New("database was written by a newer version of the app")
+
+// migration is one step of the schema's history. Versions start at 1 and
+// have no gaps.
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+func (m migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// AppliedMigration is a migration recorded in the database
+type AppliedMigration struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Checksum  string    `json:"checksum"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+const migrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	checksum   TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`
+
+// Migrations returns the migrations applied to the database, oldest first
+func (d *DB) Migrations() ([]AppliedMigration, error) {
+	return appliedMigrations(d.db)
+}
+
+// migrate applies the pending migrations. backup, if not nil, is called
+// first when the database already holds tables, with the schema version
+// being migrated from. The migrations run in one transaction, so a failing
+// one leaves the schema as it was.
+func migrate(db *sql.DB, backup func(from int) error) error {
+	if _, err := db.Exec(migrationsTable); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	current, err := checkMigrations(applied)
+	if err != nil || current == len(migrations) {
+		return err
+	}
+
+	if backup != nil {
+		var tables int
+		err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name != 'schema_migrations'`).Scan(&tables)
+		if err != nil {
+			return err
+		}
+		if tables > 0 {
+			if err := backup(current); err != nil {
+				return fmt.Errorf("not migrating without a backup: %w", err)
+			}
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	// Another instance may have migrated since the check above; the write
+	// lock taken by Begin keeps it from doing so now
+	applied, err = appliedMigrations(tx)
+	if err == nil {
+		current, err = checkMigrations(applied)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, m := range migrations[current:] {
+		if _, err := tx.Exec(m.SQL); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		_, err := tx.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+			m.Version, m.Name, m.checksum(), formatTime(time.Now()))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, m := range migrations[current:] {
+		log.Printf("[DB] Applied migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// checkMigrations verifies the applied migrations are the first ones known,
+// unchanged, and returns the schema version they add up to
+func checkMigrations(applied []AppliedMigration) (int, error) {
+	for i, a := range applied {
+		if i >= len(migrations) {
+			return 0, fmt.Errorf("schema version %d: %w", applied[len(applied)-1].Version, ErrNewerSchema)
+		}
+		m := migrations[i]
+		if a.Version != m.Version {
+			return 0, fmt.Errorf("migration %d is recorded but %d was expected", a.Version, m.Version)
+		}
+		if a.Checksum != m.checksum() {
+			return 0, fmt.Errorf("migration %d (%s) changed since it was applied", m.Version, m.Name)
+		}
+	}
+	return len(applied), nil
+}
+
+func appliedMigrations(q querier) ([]AppliedMigration, error) {
+	rows, err := q.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := []AppliedMigration{}
+	for rows.Next() {
+		var a AppliedMigration
+		var appliedAt string
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		if a.AppliedAt, err = parseTime(appliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
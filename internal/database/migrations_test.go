@@ -0,0 +1,169 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB opens the database at path and returns it with the directory
+// its backups go to
+func openTestDB(t *testing.T, path string) (*DB, string) {
+	t.Helper()
+	backupDir := filepath.Join(filepath.Dir(path), "backups")
+	d, err := Open(path, backupDir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return d, backupDir
+}
+
+// execRaw runs statements on the database file behind the app's back
+func execRaw(t *testing.T, path, statements string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(statements); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+}
+
+// withMigrations replaces the known migrations for the rest of the test
+func withMigrations(t *testing.T, extra ...migration) {
+	t.Helper()
+	original := migrations
+	migrations = append(append([]migration(nil), original...), extra...)
+	t.Cleanup(func() { migrations = original })
+}
+
+func TestOpenMigratesFreshDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.db")
+	d, backupDir := openTestDB(t, path)
+
+	applied, err := d.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	for i, a := range applied {
+		if a.Version != migrations[i].Version || a.Checksum != migrations[i].checksum() {
+			t.Errorf("migration %d recorded as %+v", migrations[i].Version, a)
+		}
+	}
+	d.Close()
+
+	// Reopening has nothing to do
+	d, _ = openTestDB(t, path)
+	d.Close()
+	if entries, _ := os.ReadDir(backupDir); len(entries) != 0 {
+		t.Errorf("backups = %v, want none for a database that needed no migration", entries)
+	}
+}
+
+func TestOpenRefusesUnknownSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "checksum mismatch",
+			tamper:  `UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`,
+			wantMsg: "changed since it was applied",
+		},
+		{
+			name:    "newer schema",
+			tamper:  `INSERT INTO schema_migrations VALUES (99, 'from the future', 'x', '2030-01-01T00:00:00Z')`,
+			wantErr: ErrNewerSchema,
+		},
+		{
+			name:    "unexpected version",
+			tamper:  `UPDATE schema_migrations SET version = 7 WHERE version = 1`,
+			wantMsg: "was expected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.db")
+			d, _ := openTestDB(t, path)
+			d.Close()
+			execRaw(t, path, tt.tamper)
+
+			d, err := Open(path, t.TempDir())
+			if err == nil {
+				d.Close()
+				t.Fatal("Open succeeded, want the schema refused")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Open error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Open error = %v, want one containing %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestMigrateBacksUpFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.db")
+	d, backupDir := openTestDB(t, path)
+	d.Close()
+
+	withMigrations(t, migration{Version: len(migrations) + 1, Name: "add notes", SQL: `CREATE TABLE notes (id TEXT PRIMARY KEY)`})
+	d, _ = openTestDB(t, path)
+	defer d.Close()
+
+	applied, err := d.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	entries, err := os.ReadDir(backupDir)
+	if err != nil || len(entries) != 1 || !strings.Contains(entries[0].Name(), "pre-migration-v1") {
+		t.Errorf("backups = %v, %v; want one taken before migrating from v1", entries, err)
+	}
+}
+
+func TestMigrateRollsBackOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.db")
+	d, _ := openTestDB(t, path)
+	d.Close()
+
+	shipped := len(migrations)
+	withMigrations(t,
+		migration{Version: len(migrations) + 1, Name: "add notes", SQL: `CREATE TABLE notes (id TEXT PRIMARY KEY)`},
+		migration{Version: len(migrations) + 2, Name: "broken", SQL: `ALTER TABLE missing ADD COLUMN x TEXT`},
+	)
+	if _, err := Open(path, t.TempDir()); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("Open error = %v, want the broken migration reported", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		t.Fatalf("appliedMigrations: %v", err)
+	}
+	if len(applied) != shipped {
+		t.Errorf("applied %d migrations after the failure, want %d", len(applied), shipped)
+	}
+	var notes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'notes'`).Scan(&notes); err != nil || notes != 0 {
+		t.Errorf("notes table left behind (%d, %v), want the whole batch rolled back", notes, err)
+	}
+}
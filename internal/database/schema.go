@@ -0,0 +1,78 @@
+package database
+
+// migrations build the schema, oldest first. Applied migrations are
+// recorded with their checksum, so never edit one that has shipped: append
+// a new one instead.
+var migrations = []migration{
+	{Version: 1, Name: "initial schema", SQL: initialSchema},
+}
+
+// initialSchema creates the tables. JSON columns hold the frontend's
+// free-form fields; relations between records are real foreign keys, so
+// deleting a connection takes its scoped commands and keychain associations
+// with it. It says IF NOT EXISTS because databases from before migrations
+// already have these tables.
+const initialSchema = `
+CREATE TABLE IF NOT EXISTS connections (
+	id                    TEXT PRIMARY KEY,
+	user_id               TEXT NOT NULL DEFAULT '',
+	name                  TEXT NOT NULL,
+	host                  TEXT NOT NULL,
+	port                  INTEGER NOT NULL DEFAULT 22 CHECK (port BETWEEN 1 AND 65535),
+	username              TEXT NOT NULL DEFAULT '',
+	private_key_encrypted TEXT NOT NULL DEFAULT '',
+	public_key            TEXT NOT NULL DEFAULT '',
+	password_encrypted    TEXT NOT NULL DEFAULT '',
+	connection_config     TEXT NOT NULL DEFAULT '{}',
+	import_source         TEXT NOT NULL DEFAULT '',
+	tags                  TEXT NOT NULL DEFAULT '[]',
+	is_favorite           INTEGER NOT NULL DEFAULT 0,
+	version               INTEGER NOT NULL DEFAULT 1,
+	deleted_at            TEXT,
+	created_at            TEXT NOT NULL,
+	updated_at            TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS connections_user ON connections (user_id);
+
+CREATE TABLE IF NOT EXISTS commands (
+	id            TEXT PRIMARY KEY,
+	user_id       TEXT NOT NULL DEFAULT '',
+	title         TEXT NOT NULL,
+	command       TEXT NOT NULL,
+	description   TEXT NOT NULL DEFAULT '',
+	variables     TEXT NOT NULL DEFAULT '[]',
+	tags          TEXT NOT NULL DEFAULT '[]',
+	connection_id TEXT REFERENCES connections (id) ON DELETE CASCADE, -- NULL for global commands
+	version       INTEGER NOT NULL DEFAULT 1,
+	deleted_at    TEXT,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS commands_user ON commands (user_id);
+CREATE INDEX IF NOT EXISTS commands_connection ON commands (connection_id);
+
+CREATE TABLE IF NOT EXISTS keychains (
+	id             TEXT PRIMARY KEY,
+	user_id        TEXT NOT NULL DEFAULT '',
+	type           TEXT NOT NULL CHECK (type IN ('ssh_key', 'password', 'token')),
+	data_encrypted TEXT NOT NULL,
+	metadata       TEXT NOT NULL DEFAULT '{}',
+	version        INTEGER NOT NULL DEFAULT 1,
+	deleted_at     TEXT,
+	created_at     TEXT NOT NULL,
+	updated_at     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS keychains_user ON keychains (user_id);
+
+CREATE TABLE IF NOT EXISTS keychain_connections (
+	keychain_id   TEXT NOT NULL REFERENCES keychains (id) ON DELETE CASCADE,
+	connection_id TEXT NOT NULL REFERENCES connections (id) ON DELETE CASCADE,
+	PRIMARY KEY (keychain_id, connection_id)
+);
+CREATE INDEX IF NOT EXISTS keychain_connections_connection ON keychain_connections (connection_id);
+
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
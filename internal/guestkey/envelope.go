@@ -0,0 +1,134 @@
+package guestkey
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// These match encryptDataWithKeyphrase in the frontend (crypto-js 4.2,
+// whose PBKDF2 defaults to SHA-256)
+const (
+	kdfIterations = 10000
+	kdfKeySize    = 32
+	saltSize      = 16
+)
+
+var errAuthFailed = errors.New("authentication failed")
+
+// envelope is the JSON the frontend stores in place of a guest secret:
+// AES-256-CBC ciphertext with an HMAC-SHA256 tag over its base64 text,
+// under a key derived from the keyphrase and salt
+type envelope struct {
+	Data string `json:"data"`
+	IV   string `json:"iv"`
+	Salt string `json:"salt"`
+	Tag  string `json:"tag"`
+}
+
+// parseEnvelope returns the envelope s holds, if it is one
+func parseEnvelope(s string) (envelope, bool) {
+	var env envelope
+	trimmed := bytes.TrimSpace([]byte(s))
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return env, false
+	}
+	if err := json.Unmarshal(trimmed, &env); err != nil {
+		return env, false
+	}
+	return env, env.Data != "" && env.IV != "" && env.Salt != "" && env.Tag != ""
+}
+
+func encrypt(plaintext []byte, keyphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	key := deriveKey(keyphrase, salt)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	data := base64.StdEncoding.EncodeToString(ciphertext)
+	out, err := json.Marshal(envelope{
+		Data: data,
+		IV:   hex.EncodeToString(iv),
+		Salt: hex.EncodeToString(salt),
+		Tag:  hex.EncodeToString(tag(key, data)),
+	})
+	return string(out), err
+}
+
+// decrypt opens env with keyphrase; errAuthFailed means the keyphrase is
+// not the one it was sealed with
+func decrypt(env envelope, keyphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := hex.DecodeString(env.Tag)
+	if err != nil {
+		return nil, err
+	}
+	key := deriveKey(keyphrase, salt)
+	if !hmac.Equal(tag(key, env.Data), expected) {
+		return nil, errAuthFailed
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Data)
+	if err != nil {
+		return nil, err
+	}
+	ivBytes, err := hex.DecodeString(env.IV)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 || len(ivBytes) > aes.BlockSize {
+		return nil, errors.New("malformed ciphertext")
+	}
+	// The frontend uses 12-byte IVs, which crypto-js pads with zeros
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, ivBytes)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(plaintext) {
+		return nil, errors.New("invalid padding")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func deriveKey(keyphrase string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(keyphrase), salt, kdfIterations, kdfKeySize, sha256.New)
+}
+
+func tag(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
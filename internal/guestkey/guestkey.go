@@ -0,0 +1,307 @@
+package guestkey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"host-vault/internal/keychain"
+	"host-vault/internal/storage"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// keyName holds the current guest keyphrase
+	keyName = "guest-key"
+	// pendingKeyName holds the next keyphrase while a rotation re-encrypts
+	// the guest files, so an interrupted rotation can be finished
+	pendingKeyName = "guest-key-pending"
+	// legacyKeyphrase is what every install used before guest keys were
+	// generated; data sealed with it is migrated to the install's own key
+	legacyKeyphrase = "host-vault-guest-default-keyphrase-change-in-production"
+	// legacyKeyphraseEnv could override legacyKeyphrase
+	legacyKeyphraseEnv = "HOST_VAULT_GUEST_KEYPHRASE"
+	keySize            = 32
+)
+
+// Store keeps the per-install guest keyphrase the frontend encrypts guest
+// credentials with, and re-encrypts the guest files when it changes
+type Store struct {
+	secrets keychain.Keychain
+	files   []string
+	mu      sync.Mutex
+}
+
+// Open loads the guest key from kc, or from a 0600 file in dir when kc is
+// nil, creating it on first run. Secrets in files still sealed with the
+// legacy keyphrase, or left behind by an interrupted rotation, are
+// re-encrypted under the current key.
+func Open(kc keychain.Keychain, dir string, files []string) (*Store, error) {
+	if kc == nil {
+		if err := storage.EnsureDir(dir); err != nil {
+			return nil, fmt.Errorf("failed to create key directory: %w", err)
+		}
+		kc = &keyFile{dir: dir}
+	}
+	s := &Store{secrets: kc, files: files}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.get(keyName)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.get(pendingKeyName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case pending != "":
+		log.Printf("[GUEST] Finishing interrupted guest key rotation")
+		if err := s.switchTo(pending, current); err != nil {
+			return nil, err
+		}
+	case current == "":
+		log.Printf("[GUEST] Generating guest key")
+		if err := s.rotate(""); err != nil {
+			return nil, err
+		}
+	default:
+		// Files restored from a backup or written by an older build can
+		// still hold secrets sealed with the legacy keyphrase
+		candidates := append([]string{current}, legacyKeyphrases()...)
+		for _, path := range s.files {
+			if err := reencryptFile(path, current, candidates); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// Keyphrase returns the current guest keyphrase. It is read on every call
+// so a rotation by another instance is picked up.
+func (s *Store) Keyphrase() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.get(keyName)
+	if err != nil {
+		return "", err
+	}
+	if current == "" {
+		return "", errors.New("guest key missing")
+	}
+	return current, nil
+}
+
+// Rotate replaces the guest key with a new random one and re-encrypts the
+// guest files under it
+func (s *Store) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.get(keyName)
+	if err != nil {
+		return err
+	}
+	if err := s.rotate(current); err != nil {
+		return err
+	}
+	log.Printf("[GUEST] Guest key rotated")
+	return nil
+}
+
+// rotate generates a new key and switches to it from current (must be
+// called with mu held)
+func (s *Store) rotate(current string) error {
+	raw := make([]byte, keySize)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	next := hex.EncodeToString(raw)
+
+	// Stored before any file changes, so the data is never sealed with a
+	// key that isn't kept somewhere
+	if err := s.secrets.Set(pendingKeyName, next); err != nil {
+		return fmt.Errorf("failed to store guest key: %w", err)
+	}
+	return s.switchTo(next, current)
+}
+
+// switchTo re-encrypts the files under next, then makes next the current
+// key (must be called with mu held)
+func (s *Store) switchTo(next, current string) error {
+	candidates := []string{next}
+	if current != "" {
+		candidates = append(candidates, current)
+	}
+	candidates = append(candidates, legacyKeyphrases()...)
+
+	for _, path := range s.files {
+		if err := reencryptFile(path, next, candidates); err != nil {
+			return err
+		}
+	}
+
+	if err := s.secrets.Set(keyName, next); err != nil {
+		return fmt.Errorf("failed to store guest key: %w", err)
+	}
+	if err := s.secrets.Delete(pendingKeyName); err != nil {
+		log.Printf("[GUEST] Failed to remove pending guest key: %v", err)
+	}
+	return nil
+}
+
+// get returns the secret stored under name, or "" if there is none (must
+// be called with mu held)
+func (s *Store) get(name string) (string, error) {
+	value, err := s.secrets.Get(name)
+	if errors.Is(err, keychain.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read guest key: %w", err)
+	}
+	return value, nil
+}
+
+func legacyKeyphrases() []string {
+	keyphrases := []string{legacyKeyphrase}
+	if env := os.Getenv(legacyKeyphraseEnv); env != "" && env != legacyKeyphrase {
+		keyphrases = append([]string{env}, keyphrases...)
+	}
+	return keyphrases
+}
+
+// reencryptFile seals every encrypted string in the JSON file at path with
+// next, opening it with whichever candidate it was sealed with. Values no
+// candidate opens are left alone, and the file is only rewritten if
+// something changed.
+func reencryptFile(path, next string, candidates []string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	err := storage.Update(path, func(current []byte) ([]byte, error) {
+		if len(current) == 0 {
+			return current, nil
+		}
+		decoder := json.NewDecoder(bytes.NewReader(current))
+		decoder.UseNumber()
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+		}
+
+		changed := 0
+		doc, err := reencryptValue(doc, next, candidates, &changed)
+		if err != nil {
+			return nil, err
+		}
+		if changed == 0 {
+			return current, nil
+		}
+		log.Printf("[GUEST] Re-encrypted %d secrets in %s", changed, filepath.Base(path))
+		var out bytes.Buffer
+		encoder := json.NewEncoder(&out)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func reencryptValue(value interface{}, next string, candidates []string, changed *int) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			updated, err := reencryptValue(item, next, candidates, changed)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = updated
+		}
+	case []interface{}:
+		for i, item := range v {
+			updated, err := reencryptValue(item, next, candidates, changed)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = updated
+		}
+	case string:
+		env, ok := parseEnvelope(v)
+		if !ok {
+			return v, nil
+		}
+		for i, keyphrase := range candidates {
+			plaintext, err := decrypt(env, keyphrase)
+			if err != nil {
+				continue
+			}
+			if i == 0 {
+				// Already sealed with next
+				return v, nil
+			}
+			sealed, err := encrypt(plaintext, next)
+			if err != nil {
+				return nil, err
+			}
+			*changed++
+			return sealed, nil
+		}
+		log.Printf("[GUEST] Skipping a secret no known guest key opens")
+	}
+	return value, nil
+}
+
+// keyFile keeps guest keys in plain 0600 files when no keychain is
+// available
+type keyFile struct {
+	dir string
+}
+
+func (k *keyFile) Name() string {
+	return keychain.BackendFile
+}
+
+func (k *keyFile) Set(name, value string) error {
+	return storage.WriteFile(k.path(name), []byte(value))
+}
+
+func (k *keyFile) Get(name string) (string, error) {
+	data, err := os.ReadFile(k.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", keychain.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (k *keyFile) Delete(name string) error {
+	err := os.Remove(k.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (k *keyFile) path(name string) string {
+	return filepath.Join(k.dir, name+".key")
+}
@@ -0,0 +1,107 @@
+package guestkey
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSecret writes a guest file holding plaintext sealed with keyphrase
+func writeSecret(t *testing.T, path, plaintext, keyphrase string) {
+	t.Helper()
+	sealed, err := encrypt([]byte(plaintext), keyphrase)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	data, err := json.Marshal(map[string]string{"password": sealed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readSecret opens the guest file's secret with keyphrase
+func readSecret(t *testing.T, path, keyphrase string) (string, bool) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]string
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("parse %s: %v", path, err)
+	}
+	env, ok := parseEnvelope(doc["password"])
+	if !ok {
+		t.Fatalf("%s holds no envelope", path)
+	}
+	plaintext, err := decrypt(env, keyphrase)
+	return string(plaintext), err == nil
+}
+
+func TestOpenReencryptsLegacySecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		// existingKey opens the store once before the legacy file appears
+		existingKey bool
+	}{
+		{name: "first run"},
+		{name: "existing key", existingKey: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "guest.json")
+			if tt.existingKey {
+				if _, err := Open(nil, dir, []string{path}); err != nil {
+					t.Fatalf("Open: %v", err)
+				}
+			}
+			writeSecret(t, path, "hunter2", legacyKeyphrase)
+
+			store, err := Open(nil, dir, []string{path})
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			keyphrase, err := store.Keyphrase()
+			if err != nil {
+				t.Fatalf("Keyphrase: %v", err)
+			}
+			if plaintext, ok := readSecret(t, path, keyphrase); !ok || plaintext != "hunter2" {
+				t.Errorf("secret under the guest key = %q, %v; want it re-encrypted", plaintext, ok)
+			}
+			if _, ok := readSecret(t, path, legacyKeyphrase); ok {
+				t.Error("secret still opens with the legacy keyphrase")
+			}
+		})
+	}
+}
+
+func TestRotateReencryptsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "guest.json")
+	store, err := Open(nil, dir, []string{path})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	old, err := store.Keyphrase()
+	if err != nil {
+		t.Fatalf("Keyphrase: %v", err)
+	}
+	writeSecret(t, path, "hunter2", old)
+
+	if err := store.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	next, err := store.Keyphrase()
+	if err != nil || next == old {
+		t.Fatalf("Keyphrase after rotation = %q, %v; want a new key", next, err)
+	}
+	if plaintext, ok := readSecret(t, path, next); !ok || plaintext != "hunter2" {
+		t.Errorf("secret under the new key = %q, %v", plaintext, ok)
+	}
+}
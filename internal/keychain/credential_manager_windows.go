@@ -0,0 +1,133 @@
+//go:build windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	// credMaxBlobSize is CRED_MAX_CREDENTIAL_BLOB_SIZE
+	credMaxBlobSize = 5 * 512
+)
+
+var (
+	advapi32        = windows.NewLazySystemDLL("advapi32.dll")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+// credential mirrors CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func platformBackends() []func() (Keychain, error) {
+	return []func() (Keychain, error){
+		func() (Keychain, error) { return newCredentialManager() },
+	}
+}
+
+// credentialManager stores secrets as generic credentials in the Windows
+// Credential Manager, named "host-vault:<key>"
+type credentialManager struct{}
+
+func newCredentialManager() (*credentialManager, error) {
+	if err := advapi32.Load(); err != nil {
+		return nil, fmt.Errorf("credential manager: %w", err)
+	}
+	return &credentialManager{}, nil
+}
+
+func (c *credentialManager) Name() string {
+	return BackendCredentialManager
+}
+
+func (c *credentialManager) Set(key, value string) error {
+	if len(value) > credMaxBlobSize {
+		return fmt.Errorf("secret too large for Credential Manager (%d bytes)", len(value))
+	}
+	target, err := windows.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(service)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	if ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ret == 0 {
+		return fmt.Errorf("failed to store credential: %w", err)
+	}
+	return nil
+}
+
+func (c *credentialManager) Get(key string) (string, error) {
+	target, err := windows.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read credential: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (c *credentialManager) Delete(key string) error {
+	target, err := windows.UTF16PtrFromString(targetName(key))
+	if err != nil {
+		return err
+	}
+
+	ret, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 && !errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+	return nil
+}
+
+func targetName(key string) string {
+	return service + ":" + key
+}
@@ -0,0 +1,160 @@
+package keychain
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"host-vault/internal/storage"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileKeychain is the fallback when no OS keychain is available. Secrets
+// are sealed with AES-GCM under a random key kept in a separate 0600 file,
+// which keeps them out of plain sight and out of exports of the data file,
+// but is no stronger than the file permissions.
+type fileKeychain struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+func newFileKeychain(dir string) (*fileKeychain, error) {
+	if err := storage.EnsureDir(dir); err != nil {
+		return nil, fmt.Errorf("failed to create keychain directory: %w", err)
+	}
+	return &fileKeychain{
+		path:    filepath.Join(dir, "keychain.json"),
+		keyPath: filepath.Join(dir, "keychain.key"),
+	}, nil
+}
+
+func (k *fileKeychain) Name() string {
+	return BackendFile
+}
+
+func (k *fileKeychain) Set(key, value string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	gcm, err := k.cipher(true)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(key))
+
+	return k.update(func(secrets map[string][]byte) {
+		secrets[key] = sealed
+	})
+}
+
+func (k *fileKeychain) Get(key string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	gcm, err := k.cipher(false)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("keychain key file is missing")
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("corrupt keychain entry: %s", key)
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt keychain entry %s: %w", key, err)
+	}
+	return string(plaintext), nil
+}
+
+func (k *fileKeychain) Delete(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.update(func(secrets map[string][]byte) {
+		delete(secrets, key)
+	})
+}
+
+// cipher loads the file key, creating it first if create is set (must be
+// called with mu held)
+func (k *fileKeychain) cipher(create bool) (cipher.AEAD, error) {
+	key, err := os.ReadFile(k.keyPath)
+	if errors.Is(err, os.ErrNotExist) && create {
+		// Update makes concurrent instances agree on a single key
+		err = storage.Update(k.keyPath, func(current []byte) ([]byte, error) {
+			if len(current) > 0 {
+				key = current
+				return current, nil
+			}
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, err
+			}
+			return key, nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid keychain key file")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// read loads the sealed secrets; a missing file holds none (must be called
+// with mu held)
+func (k *fileKeychain) read() (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+	data, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keychain: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse keychain: %w", err)
+	}
+	return secrets, nil
+}
+
+// update applies change to the file under its lock, so other instances'
+// entries survive (must be called with mu held)
+func (k *fileKeychain) update(change func(map[string][]byte)) error {
+	return storage.Update(k.path, func(current []byte) ([]byte, error) {
+		secrets := make(map[string][]byte)
+		if len(current) > 0 {
+			if err := json.Unmarshal(current, &secrets); err != nil {
+				return nil, fmt.Errorf("failed to parse keychain: %w", err)
+			}
+		}
+		change(secrets)
+		return json.MarshalIndent(secrets, "", "  ")
+	})
+}
@@ -0,0 +1,58 @@
+package keychain
+
+import (
+	"errors"
+	"log"
+)
+
+// service namespaces every secret the app stores in an OS keychain
+const service = "host-vault"
+
+// Backend names reported by Keychain.Name
+const (
+	BackendSecretService     = "secret-service"
+	BackendCredentialManager = "credential-manager"
+	BackendFile              = "file"
+)
+
+// ErrNotFound is returned by Get when nothing is stored under the key
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// Keychain stores small secrets under string keys
+type Keychain interface {
+	// Name identifies the backend, e.g. BackendSecretService
+	Name() string
+	Set(key, value string) error
+	// Get returns ErrNotFound for keys that were never set
+	Get(key string) (string, error)
+	// Delete removes a key; deleting a missing key is not an error
+	Delete(key string) error
+}
+
+// Open picks the first OS keychain that answers on this system: the
+// freedesktop Secret Service (GNOME Keyring, KWallet) on Linux, Credential
+// Manager on Windows. Otherwise it falls back to an encrypted file in
+// fallbackDir.
+func Open(fallbackDir string) (Keychain, error) {
+	for _, open := range platformBackends() {
+		kc, err := open()
+		if err == nil {
+			log.Printf("[KEYCHAIN] Using %s", kc.Name())
+			return kc, nil
+		}
+		log.Printf("[KEYCHAIN] Backend unavailable: %v", err)
+	}
+
+	kc, err := newFileKeychain(fallbackDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[KEYCHAIN] No OS keychain available, using encrypted file in %s", fallbackDir)
+	return kc, nil
+}
+
+// IsOSBackend reports whether kc is backed by the operating system rather
+// than the file fallback, whose key sits on the same disk as its secrets
+func IsOSBackend(kc Keychain) bool {
+	return kc != nil && kc.Name() != BackendFile
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package keychain
+
+// platformBackends has no OS keychain on other systems yet; the file
+// fallback is used
+func platformBackends() []func() (Keychain, error) {
+	return nil
+}
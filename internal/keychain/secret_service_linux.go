@@ -0,0 +1,230 @@
+//go:build linux
+
+package keychain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretsDest          = "org.freedesktop.secrets"
+	secretsPath          = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretsIface         = "org.freedesktop.Secret.Service"
+	collectionIface      = "org.freedesktop.Secret.Collection"
+	itemIface            = "org.freedesktop.Secret.Item"
+	promptIface          = "org.freedesktop.Secret.Prompt"
+	sessionIface         = "org.freedesktop.Secret.Session"
+	defaultCollection    = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	noPrompt             = dbus.ObjectPath("/")
+	secretServiceTimeout = 10 * time.Second
+	// secretServicePromptTimeout bounds how long an unlock prompt may stay open
+	secretServicePromptTimeout = 2 * time.Minute
+)
+
+func platformBackends() []func() (Keychain, error) {
+	return []func() (Keychain, error){
+		func() (Keychain, error) { return newSecretService() },
+	}
+}
+
+// secret is the Secret Service's (oayays) secret struct
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// secretService stores secrets in the default collection of the
+// freedesktop Secret Service, i.e. GNOME Keyring or KWallet. Items carry
+// service and account attributes like libsecret's tools use.
+type secretService struct {
+	conn *dbus.Conn
+}
+
+// newSecretService connects to the session bus and checks that a Secret
+// Service answers, starting it through D-Bus activation if needed
+func newSecretService() (*secretService, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("secret service: %w", err)
+	}
+	s := &secretService{conn: conn}
+
+	session, err := s.openSession()
+	if err != nil {
+		return nil, fmt.Errorf("secret service: %w", err)
+	}
+	s.closeSession(session)
+	return s, nil
+}
+
+func (s *secretService) Name() string {
+	return BackendSecretService
+}
+
+func (s *secretService) Set(key, value string) error {
+	session, err := s.openSession()
+	if err != nil {
+		return err
+	}
+	defer s.closeSession(session)
+
+	if err := s.unlock(defaultCollection); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		itemIface + ".Label":      dbus.MakeVariant(service + ": " + key),
+		itemIface + ".Attributes": dbus.MakeVariant(attributes(key)),
+	}
+	stored := secret{Session: session, Value: []byte(value), ContentType: "text/plain; charset=utf8"}
+
+	var item, prompt dbus.ObjectPath
+	err = s.call(defaultCollection, collectionIface+".CreateItem", properties, stored, true).Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	if prompt != noPrompt {
+		return s.prompt(prompt)
+	}
+	return nil
+}
+
+func (s *secretService) Get(key string) (string, error) {
+	item, err := s.find(key)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := s.openSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.closeSession(session)
+
+	var value secret
+	if err := s.call(item, itemIface+".GetSecret", session).Store(&value); err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(value.Value), nil
+}
+
+func (s *secretService) Delete(key string) error {
+	item, err := s.find(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	if err := s.call(item, itemIface+".Delete").Store(&prompt); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	if prompt != noPrompt {
+		return s.prompt(prompt)
+	}
+	return nil
+}
+
+// find returns the item stored under key, unlocking the collection first
+func (s *secretService) find(key string) (dbus.ObjectPath, error) {
+	if err := s.unlock(defaultCollection); err != nil {
+		return "", err
+	}
+
+	var items []dbus.ObjectPath
+	if err := s.call(defaultCollection, collectionIface+".SearchItems", attributes(key)).Store(&items); err != nil {
+		return "", fmt.Errorf("failed to search secrets: %w", err)
+	}
+	if len(items) == 0 {
+		return "", ErrNotFound
+	}
+	return items[0], nil
+}
+
+// openSession opens a plain session; the secret travels over the local
+// session bus only
+func (s *secretService) openSession() (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := s.call(secretsPath, secretsIface+".OpenSession", "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", err
+	}
+	return session, nil
+}
+
+func (s *secretService) closeSession(session dbus.ObjectPath) {
+	s.call(session, sessionIface+".Close")
+}
+
+// unlock unlocks a collection, showing the keyring's own unlock dialog if
+// it is locked
+func (s *secretService) unlock(path dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := s.call(secretsPath, secretsIface+".Unlock", []dbus.ObjectPath{path}).Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	if prompt != noPrompt {
+		return s.prompt(prompt)
+	}
+	return nil
+}
+
+// prompt runs a Secret Service prompt and waits for it to complete
+func (s *secretService) prompt(path dbus.ObjectPath) error {
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(promptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 4)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.call(path, promptIface+".Prompt", "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	timeout := time.After(secretServicePromptTimeout)
+	for {
+		select {
+		case signal := <-signals:
+			if signal.Path != path || signal.Name != promptIface+".Completed" {
+				continue
+			}
+			if len(signal.Body) > 0 {
+				if dismissed, ok := signal.Body[0].(bool); ok && dismissed {
+					return errors.New("keyring prompt dismissed")
+				}
+			}
+			return nil
+		case <-timeout:
+			s.call(path, promptIface+".Dismiss")
+			return errors.New("keyring prompt timed out")
+		}
+	}
+}
+
+func (s *secretService) call(path dbus.ObjectPath, method string, args ...interface{}) *dbus.Call {
+	ctx, cancel := context.WithTimeout(context.Background(), secretServiceTimeout)
+	defer cancel()
+	return s.conn.Object(secretsDest, path).CallWithContext(ctx, method, 0, args...)
+}
+
+func attributes(key string) map[string]string {
+	return map[string]string{"service": service, "account": key}
+}
@@ -0,0 +1,405 @@
+package sshkeys
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+// PuTTY .ppk files, versions 2 and 3, as laid out in the "PPK file format"
+// appendix of the PuTTY manual
+
+const (
+	ppkHeaderPrefix   = "PuTTY-User-Key-File-"
+	ppkMACKeyPrefix   = "putty-private-key-file-mac-key" // version 2
+	ppkEncryptionNone = "none"
+	ppkEncryptionAES  = "aes256-cbc"
+	ppkLineLength     = 64
+	// Argon2id parameters for written files, PuTTYgen's defaults
+	ppkArgon2Memory      = 8192 // KiB
+	ppkArgon2Passes      = 13
+	ppkArgon2Parallelism = 1
+	ppkArgon2SaltSize    = 16
+	// Bounds on Argon2 parameters read from files, which would otherwise
+	// let a crafted key exhaust memory or CPU on import
+	ppkArgon2MaxMemory = 1 << 20 // KiB, 1 GiB
+	ppkArgon2MaxPasses = 1000
+)
+
+// ppkFile is a parsed .ppk file; private is still encrypted
+type ppkFile struct {
+	version     int
+	algorithm   string
+	encryption  string
+	comment     string
+	public      []byte
+	private     []byte
+	kdf         string
+	memory      uint32
+	passes      uint32
+	parallelism uint8
+	salt        []byte
+	mac         []byte
+}
+
+// isPPK reports whether data looks like a PuTTY key file
+func isPPK(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(ppkHeaderPrefix))
+}
+
+func parsePPK(data []byte) (*ppkFile, error) {
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(string(data)), "\r\n", "\n"), "\n")
+	f := &ppkFile{}
+	fields := make(map[string]string)
+
+	for i := 0; i < len(lines); i++ {
+		name, value, ok := strings.Cut(lines[i], ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed .ppk file at line %d", i+1)
+		}
+		value = strings.TrimPrefix(value, " ")
+
+		if i == 0 {
+			version, err := strconv.Atoi(strings.TrimPrefix(name, ppkHeaderPrefix))
+			if err != nil || !strings.HasPrefix(name, ppkHeaderPrefix) {
+				return nil, errors.New("not a PuTTY key file")
+			}
+			if version != 2 && version != 3 {
+				return nil, fmt.Errorf("unsupported .ppk version %d", version)
+			}
+			f.version, f.algorithm = version, value
+			continue
+		}
+
+		if name == "Public-Lines" || name == "Private-Lines" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || i+n >= len(lines) {
+				return nil, fmt.Errorf("malformed %s in .ppk file", name)
+			}
+			blob, err := base64.StdEncoding.DecodeString(strings.Join(lines[i+1:i+1+n], ""))
+			if err != nil {
+				return nil, fmt.Errorf("malformed %s in .ppk file: %w", name, err)
+			}
+			if name == "Public-Lines" {
+				f.public = blob
+			} else {
+				f.private = blob
+			}
+			i += n
+			continue
+		}
+		fields[name] = value
+	}
+
+	f.encryption = fields["Encryption"]
+	f.comment = fields["Comment"]
+	if f.encryption != ppkEncryptionNone && f.encryption != ppkEncryptionAES {
+		return nil, fmt.Errorf("unsupported .ppk encryption %q", f.encryption)
+	}
+	if f.public == nil || f.private == nil {
+		return nil, errors.New("incomplete .ppk file")
+	}
+	mac, err := hex.DecodeString(fields["Private-MAC"])
+	if err != nil || len(mac) == 0 {
+		return nil, errors.New("missing or malformed Private-MAC in .ppk file")
+	}
+	f.mac = mac
+
+	if f.version == 3 && f.encrypted() {
+		f.kdf = fields["Key-Derivation"]
+		memory, err1 := strconv.ParseUint(fields["Argon2-Memory"], 10, 32)
+		passes, err2 := strconv.ParseUint(fields["Argon2-Passes"], 10, 32)
+		parallelism, err3 := strconv.ParseUint(fields["Argon2-Parallelism"], 10, 8)
+		salt, err4 := hex.DecodeString(fields["Argon2-Salt"])
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			return nil, fmt.Errorf("malformed Argon2 parameters in .ppk file: %w", err)
+		}
+		if memory > ppkArgon2MaxMemory || passes < 1 || passes > ppkArgon2MaxPasses || parallelism < 1 {
+			return nil, fmt.Errorf("unsupported Argon2 parameters in .ppk file: memory %d KiB, %d passes, parallelism %d", memory, passes, parallelism)
+		}
+		f.memory, f.passes, f.parallelism, f.salt = uint32(memory), uint32(passes), uint8(parallelism), salt
+	}
+	return f, nil
+}
+
+func (f *ppkFile) encrypted() bool {
+	return f.encryption == ppkEncryptionAES
+}
+
+// privateKey decrypts and checks the private blob and rebuilds the key
+func (f *ppkFile) privateKey(passphrase string) (crypto.PrivateKey, error) {
+	if f.encrypted() && passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	if !f.encrypted() {
+		passphrase = ""
+	}
+
+	cipherKey, iv, macKey, err := f.keys(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	private := f.private
+	if f.encrypted() {
+		if len(private)%aes.BlockSize != 0 {
+			return nil, errors.New("malformed encrypted private key in .ppk file")
+		}
+		block, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			return nil, err
+		}
+		private = make([]byte, len(f.private))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(private, f.private)
+	}
+
+	if !hmac.Equal(f.computeMAC(macKey, private), f.mac) {
+		if f.encrypted() {
+			return nil, ErrIncorrectPassphrase
+		}
+		return nil, errors.New("the .ppk file is corrupted: MAC mismatch")
+	}
+	return ppkPrivateKey(f.public, private)
+}
+
+// keys derives the cipher key, IV and MAC key from the passphrase
+func (f *ppkFile) keys(passphrase string) (cipherKey, iv, macKey []byte, err error) {
+	if f.version == 2 {
+		if f.encrypted() {
+			h0 := sha1.Sum(append([]byte{0, 0, 0, 0}, passphrase...))
+			h1 := sha1.Sum(append([]byte{0, 0, 0, 1}, passphrase...))
+			cipherKey = append(h0[:], h1[:]...)[:32]
+			iv = make([]byte, aes.BlockSize)
+		}
+		mac := sha1.Sum([]byte(ppkMACKeyPrefix + passphrase))
+		return cipherKey, iv, mac[:], nil
+	}
+
+	if !f.encrypted() {
+		return nil, nil, []byte{}, nil
+	}
+	var out []byte
+	switch f.kdf {
+	case "Argon2id":
+		out = argon2.IDKey([]byte(passphrase), f.salt, f.passes, f.memory, f.parallelism, 80)
+	case "Argon2i":
+		out = argon2.Key([]byte(passphrase), f.salt, f.passes, f.memory, f.parallelism, 80)
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported .ppk key derivation %q", f.kdf)
+	}
+	return out[:32], out[32:48], out[48:], nil
+}
+
+// computeMAC authenticates the file's fields and the decrypted private blob
+func (f *ppkFile) computeMAC(macKey, private []byte) []byte {
+	newHash := sha256.New
+	if f.version == 2 {
+		newHash = sha1.New
+	}
+	mac := hmac.New(newHash, macKey)
+	for _, field := range [][]byte{[]byte(f.algorithm), []byte(f.encryption), []byte(f.comment), f.public, private} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+		mac.Write(length[:])
+		mac.Write(field)
+	}
+	return mac.Sum(nil)
+}
+
+// ppkPrivateKey rebuilds a key from its public and private blobs and checks
+// the two halves belong together
+func ppkPrivateKey(public, private []byte) (crypto.PrivateKey, error) {
+	pub, err := ssh.ParsePublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key in .ppk file: %w", err)
+	}
+	crypted, ok := pub.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type in .ppk file: %s", pub.Type())
+	}
+
+	switch pubKey := crypted.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		var blob struct {
+			D, P, Q, Iqmp *big.Int
+			Rest          []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil {
+			return nil, fmt.Errorf("malformed RSA key in .ppk file: %w", err)
+		}
+		key := &rsa.PrivateKey{PublicKey: *pubKey, D: blob.D, Primes: []*big.Int{blob.P, blob.Q}}
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA key in .ppk file: %w", err)
+		}
+		key.Precompute()
+		return key, nil
+
+	case *ecdsa.PublicKey:
+		var blob struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil {
+			return nil, fmt.Errorf("malformed ECDSA key in .ppk file: %w", err)
+		}
+		key := &ecdsa.PrivateKey{PublicKey: *pubKey, D: blob.D}
+		if !matchesPublic(key, pub) {
+			return nil, errors.New("private key in .ppk file does not match its public key")
+		}
+		return key, nil
+
+	case ed25519.PublicKey:
+		var blob struct {
+			Seed []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &blob); err != nil || len(blob.Seed) != ed25519.SeedSize {
+			return nil, errors.New("malformed Ed25519 key in .ppk file")
+		}
+		key := ed25519.NewKeyFromSeed(blob.Seed)
+		if !matchesPublic(key, pub) {
+			return nil, errors.New("private key in .ppk file does not match its public key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type in .ppk file: %s", pub.Type())
+}
+
+// matchesPublic reports whether key is the private half of pub
+func matchesPublic(key crypto.PrivateKey, pub ssh.PublicKey) bool {
+	signer, err := ssh.NewSignerFromKey(key)
+	return err == nil && bytes.Equal(signer.PublicKey().Marshal(), pub.Marshal())
+}
+
+// marshalPPK writes key as a version 3 .ppk file, encrypted with
+// Argon2id-derived AES-256 when passphrase is set
+func marshalPPK(key crypto.PrivateKey, comment, passphrase string) ([]byte, error) {
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var private []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, errors.New("multi-prime RSA keys can't be written as .ppk")
+		}
+		k.Precompute()
+		private = ssh.Marshal(struct{ D, P, Q, Iqmp *big.Int }{k.D, k.Primes[0], k.Primes[1], k.Precomputed.Qinv})
+	case *ecdsa.PrivateKey:
+		private = ssh.Marshal(struct{ D *big.Int }{k.D})
+	case ed25519.PrivateKey:
+		private = ssh.Marshal(struct{ Seed []byte }{k.Seed()})
+	default:
+		return nil, fmt.Errorf("unsupported key type for .ppk: %T", key)
+	}
+
+	f := &ppkFile{
+		version:   3,
+		algorithm: signer.PublicKey().Type(),
+		comment:   comment,
+		public:    signer.PublicKey().Marshal(),
+	}
+	if passphrase != "" {
+		f.kdf = "Argon2id"
+		f.memory, f.passes, f.parallelism = ppkArgon2Memory, ppkArgon2Passes, ppkArgon2Parallelism
+		f.salt = make([]byte, ppkArgon2SaltSize)
+		if _, err := rand.Read(f.salt); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.seal(private, passphrase); err != nil {
+		return nil, err
+	}
+	return f.encode(), nil
+}
+
+// seal sets the file's private blob and MAC, encrypting private with
+// AES-256 when passphrase is set. Version 3 files need their Argon2
+// parameters set first.
+func (f *ppkFile) seal(private []byte, passphrase string) error {
+	f.encryption = ppkEncryptionNone
+	if passphrase == "" {
+		_, _, macKey, err := f.keys("")
+		if err != nil {
+			return err
+		}
+		f.mac = f.computeMAC(macKey, private)
+		f.private = private
+		return nil
+	}
+
+	f.encryption = ppkEncryptionAES
+	if pad := len(private) % aes.BlockSize; pad != 0 {
+		padding := make([]byte, aes.BlockSize-pad)
+		if _, err := rand.Read(padding); err != nil {
+			return err
+		}
+		private = append(private, padding...)
+	}
+	cipherKey, iv, macKey, err := f.keys(passphrase)
+	if err != nil {
+		return err
+	}
+	f.mac = f.computeMAC(macKey, private)
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return err
+	}
+	f.private = make([]byte, len(private))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(f.private, private)
+	return nil
+}
+
+// encode writes the file in its version's text format
+func (f *ppkFile) encode() []byte {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s%d: %s\n", ppkHeaderPrefix, f.version, f.algorithm)
+	fmt.Fprintf(&out, "Encryption: %s\n", f.encryption)
+	fmt.Fprintf(&out, "Comment: %s\n", f.comment)
+	writePPKLines(&out, "Public-Lines", f.public)
+	if f.version == 3 && f.encrypted() {
+		fmt.Fprintf(&out, "Key-Derivation: %s\n", f.kdf)
+		fmt.Fprintf(&out, "Argon2-Memory: %d\n", f.memory)
+		fmt.Fprintf(&out, "Argon2-Passes: %d\n", f.passes)
+		fmt.Fprintf(&out, "Argon2-Parallelism: %d\n", f.parallelism)
+		fmt.Fprintf(&out, "Argon2-Salt: %s\n", hex.EncodeToString(f.salt))
+	}
+	writePPKLines(&out, "Private-Lines", f.private)
+	fmt.Fprintf(&out, "Private-MAC: %s\n", hex.EncodeToString(f.mac))
+	return []byte(out.String())
+}
+
+// writePPKLines writes blob base64-encoded in lines of 64 characters
+func writePPKLines(out *strings.Builder, name string, blob []byte) {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var lines []string
+	for len(encoded) > ppkLineLength {
+		lines = append(lines, encoded[:ppkLineLength])
+		encoded = encoded[ppkLineLength:]
+	}
+	lines = append(lines, encoded)
+	fmt.Fprintf(out, "%s: %d\n", name, len(lines))
+	for _, line := range lines {
+		out.WriteString(line + "\n")
+	}
+}
@@ -0,0 +1,165 @@
+package sshkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const testPPKComment = "test@host-vault"
+
+func newTestKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+// testPPK writes key as a .ppk file of the given version
+func testPPK(t *testing.T, key ed25519.PrivateKey, version int, passphrase string) string {
+	t.Helper()
+	if version == 3 {
+		data, err := marshalPPK(key, testPPKComment, passphrase)
+		if err != nil {
+			t.Fatalf("marshalPPK: %v", err)
+		}
+		return string(data)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	f := &ppkFile{
+		version:   version,
+		algorithm: signer.PublicKey().Type(),
+		comment:   testPPKComment,
+		public:    signer.PublicKey().Marshal(),
+	}
+	if err := f.seal(ssh.Marshal(struct{ Seed []byte }{key.Seed()}), passphrase); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	return string(f.encode())
+}
+
+// corruptMAC flips the last digit of the file's Private-MAC
+func corruptMAC(data string) string {
+	i := strings.LastIndex(data, "\n")
+	last := data[i-1]
+	flipped := byte('0')
+	if last == '0' {
+		flipped = '1'
+	}
+	return data[:i-1] + string(flipped) + data[i:]
+}
+
+func TestParsePPK(t *testing.T) {
+	key := newTestKey(t)
+
+	tests := []struct {
+		name       string
+		version    int
+		passphrase string // written with
+		use        string // read with
+		corrupt    bool
+		wantErr    error
+		wantErrMsg string
+	}{
+		{name: "v2 plain", version: 2},
+		{name: "v2 encrypted", version: 2, passphrase: "secret", use: "secret"},
+		{name: "v2 wrong passphrase", version: 2, passphrase: "secret", use: "wrong", wantErr: ErrIncorrectPassphrase},
+		{name: "v2 missing passphrase", version: 2, passphrase: "secret", wantErr: ErrPassphraseRequired},
+		{name: "v2 bad MAC", version: 2, corrupt: true, wantErrMsg: "MAC mismatch"},
+		{name: "v3 plain", version: 3},
+		{name: "v3 plain ignores passphrase", version: 3, use: "unused"},
+		{name: "v3 encrypted", version: 3, passphrase: "secret", use: "secret"},
+		{name: "v3 wrong passphrase", version: 3, passphrase: "secret", use: "wrong", wantErr: ErrIncorrectPassphrase},
+		{name: "v3 missing passphrase", version: 3, passphrase: "secret", wantErr: ErrPassphraseRequired},
+		{name: "v3 bad MAC", version: 3, corrupt: true, wantErrMsg: "MAC mismatch"},
+		{name: "v3 encrypted bad MAC", version: 3, passphrase: "secret", use: "secret", corrupt: true, wantErr: ErrIncorrectPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := testPPK(t, key, tt.version, tt.passphrase)
+			if tt.corrupt {
+				data = corruptMAC(data)
+			}
+
+			parsed, comment, err := ParsePrivateKey([]byte(data), tt.use)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePrivateKey error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantErrMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("ParsePrivateKey error = %v, want one containing %q", err, tt.wantErrMsg)
+				}
+				return
+			case err != nil:
+				t.Fatalf("ParsePrivateKey: %v", err)
+			}
+
+			if !key.Equal(parsed) {
+				t.Error("parsed key differs from the written one")
+			}
+			if comment != testPPKComment {
+				t.Errorf("comment = %q, want %q", comment, testPPKComment)
+			}
+		})
+	}
+}
+
+func TestParsePPKMalformed(t *testing.T) {
+	key := newTestKey(t)
+	encrypted := testPPK(t, key, 3, "secret")
+	plain := testPPK(t, key, 2, "")
+
+	replace := func(data, old, new string) string {
+		if !strings.Contains(data, old) {
+			t.Fatalf("test file has no %q", old)
+		}
+		return strings.Replace(data, old, new, 1)
+	}
+
+	tests := []struct {
+		name       string
+		data       string
+		wantErrMsg string
+	}{
+		{name: "zero passes", data: replace(encrypted, "Argon2-Passes: 13", "Argon2-Passes: 0"), wantErrMsg: "Argon2"},
+		{name: "too many passes", data: replace(encrypted, "Argon2-Passes: 13", "Argon2-Passes: 1001"), wantErrMsg: "Argon2"},
+		{name: "zero parallelism", data: replace(encrypted, "Argon2-Parallelism: 1", "Argon2-Parallelism: 0"), wantErrMsg: "Argon2"},
+		{name: "parallelism overflow", data: replace(encrypted, "Argon2-Parallelism: 1", "Argon2-Parallelism: 256"), wantErrMsg: "Argon2"},
+		{name: "too much memory", data: replace(encrypted, "Argon2-Memory: 8192", "Argon2-Memory: 1048577"), wantErrMsg: "Argon2"},
+		{name: "memory overflow", data: replace(encrypted, "Argon2-Memory: 8192", "Argon2-Memory: 4294967296"), wantErrMsg: "Argon2"},
+		{name: "bad salt", data: replace(encrypted, "Argon2-Salt: ", "Argon2-Salt: zz"), wantErrMsg: "Argon2"},
+		{name: "unknown key derivation", data: replace(encrypted, "Key-Derivation: Argon2id", "Key-Derivation: scrypt"), wantErrMsg: "key derivation"},
+		{name: "unsupported version", data: replace(plain, ppkHeaderPrefix+"2:", ppkHeaderPrefix+"4:"), wantErrMsg: "version"},
+		{name: "bad version", data: replace(plain, ppkHeaderPrefix+"2:", ppkHeaderPrefix+"x:"), wantErrMsg: "not a PuTTY key file"},
+		{name: "unknown encryption", data: replace(plain, "Encryption: none", "Encryption: des"), wantErrMsg: "encryption"},
+		{name: "line count past end", data: replace(plain, "Public-Lines: 2", "Public-Lines: 99"), wantErrMsg: "Public-Lines"},
+		{name: "negative line count", data: replace(plain, "Private-Lines: 1", "Private-Lines: -1"), wantErrMsg: "Private-Lines"},
+		{name: "bad base64", data: replace(plain, "Private-Lines: 1\n", "Private-Lines: 1\n!"), wantErrMsg: "Private-Lines"},
+		{name: "missing MAC", data: replace(plain, "Private-MAC: ", "Private-Hash: "), wantErrMsg: "Private-MAC"},
+		{name: "line without field name", data: replace(plain, "Encryption: none", "Encryption none"), wantErrMsg: "malformed"},
+		{name: "no private key", data: plain[:strings.Index(plain, "Private-Lines")] + "Private-MAC: 00\n", wantErrMsg: "incomplete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParsePrivateKey([]byte(tt.data), "secret")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+				t.Fatalf("ParsePrivateKey error = %v, want one containing %q", err, tt.wantErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package sshkeys
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// The randomart field, as in OpenSSH's sshkey_fingerprint_randomart
+const (
+	artWidth  = 17
+	artHeight = 9
+	// artSymbols are ranked by how often the bishop visited a square; the
+	// last two mark where it started and ended
+	artSymbols = " .o+=*BOX@%&#/^SE"
+)
+
+// RandomArt draws the SHA256 fingerprint of key the way ssh-keygen -lv
+// does, so the two can be compared at a glance
+func RandomArt(key ssh.PublicKey) string {
+	digest := sha256.Sum256(key.Marshal())
+	maxLevel := len(artSymbols) - 1
+
+	var field [artWidth][artHeight]int
+	x, y := artWidth/2, artHeight/2
+	for _, b := range digest {
+		for i := 0; i < 4; i++ {
+			if b&1 != 0 {
+				x++
+			} else {
+				x--
+			}
+			if b&2 != 0 {
+				y++
+			} else {
+				y--
+			}
+			x = min(max(x, 0), artWidth-1)
+			y = min(max(y, 0), artHeight-1)
+			if field[x][y] < maxLevel-2 {
+				field[x][y]++
+			}
+			b >>= 2
+		}
+	}
+	field[artWidth/2][artHeight/2] = maxLevel - 1
+	field[x][y] = maxLevel
+
+	title := fmt.Sprintf("[%s %d]", artKeyName(key), Bits(key))
+	if len(title) > artWidth {
+		title = "[" + artKeyName(key) + "]"
+	}
+
+	var art strings.Builder
+	art.WriteString(artBorder(title))
+	art.WriteByte('\n')
+	for row := 0; row < artHeight; row++ {
+		art.WriteByte('|')
+		for col := 0; col < artWidth; col++ {
+			art.WriteByte(artSymbols[min(field[col][row], maxLevel)])
+		}
+		art.WriteString("|\n")
+	}
+	art.WriteString(artBorder("[SHA256]"))
+	return art.String()
+}
+
+// artBorder centres label in a border line, leaning left like OpenSSH
+func artBorder(label string) string {
+	left := (artWidth - len(label)) / 2
+	return "+" + strings.Repeat("-", left) + label + strings.Repeat("-", artWidth-left-len(label)) + "+"
+}
+
+// artKeyName is the key type as OpenSSH names it in the art's title
+func artKeyName(key ssh.PublicKey) string {
+	keyType := key.Type()
+	cert := strings.HasSuffix(keyType, "-cert-v01@openssh.com")
+	if c, ok := key.(*ssh.Certificate); ok {
+		keyType = c.Key.Type()
+	}
+
+	var name string
+	switch {
+	case keyType == ssh.KeyAlgoED25519:
+		name = "ED25519"
+	case keyType == ssh.KeyAlgoSKED25519:
+		name = "ED25519-SK"
+	case keyType == ssh.KeyAlgoSKECDSA256:
+		name = "ECDSA-SK"
+	case strings.HasPrefix(keyType, "ecdsa-"):
+		name = "ECDSA"
+	case keyType == ssh.KeyAlgoRSA:
+		name = "RSA"
+	case keyType == ssh.KeyAlgoDSA:
+		name = "DSA"
+	default:
+		name = strings.ToUpper(keyType)
+	}
+	if cert {
+		name += "-CERT"
+	}
+	return name
+}
@@ -0,0 +1,188 @@
+package sshkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyType is a key algorithm, named like ssh-keygen's -t option
+type KeyType string
+
+const (
+	KeyTypeEd25519 KeyType = "ed25519"
+	KeyTypeECDSA   KeyType = "ecdsa"
+	KeyTypeRSA     KeyType = "rsa"
+)
+
+const (
+	defaultECDSABits = 256
+	defaultRSABits   = 3072
+	minRSABits       = 2048
+	maxRSABits       = 8192
+)
+
+// GenerateOptions says what key to generate
+type GenerateOptions struct {
+	Type KeyType `json:"type"`
+	// Bits is 256, 384 or 521 for ECDSA and 2048 to 8192 for RSA; 0 picks
+	// ssh-keygen's default. Ed25519 keys have a fixed size.
+	Bits    int    `json:"bits,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	// Passphrase encrypts the private key when set
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// KeyPair is a generated key in the formats ssh-keygen writes
+type KeyPair struct {
+	PrivateKey string // OpenSSH PEM, encrypted when a passphrase was given
+	PublicKey  string // authorized_keys line
+	Info       Info
+}
+
+// Info describes a public key
+type Info struct {
+	Type        string `json:"type"` // SSH algorithm name, e.g. ssh-ed25519
+	Bits        int    `json:"bits"`
+	Comment     string `json:"comment,omitempty"`
+	Fingerprint string `json:"fingerprint"` // SHA256:... as ssh-keygen -l prints it
+	RandomArt   string `json:"randomArt"`
+}
+
+// Generate creates a key pair
+func Generate(opts GenerateOptions) (*KeyPair, error) {
+	if strings.ContainsAny(opts.Comment, "\r\n") {
+		return nil, fmt.Errorf("comment must be a single line")
+	}
+	private, err := generateKey(opts.Type, opts.Bits)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	var block *pem.Block
+	if opts.Passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, opts.Comment, []byte(opts.Passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, opts.Comment)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	public := signer.PublicKey()
+	return &KeyPair{
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		PublicKey:  AuthorizedKey(public, opts.Comment),
+		Info:       Describe(public, opts.Comment),
+	}, nil
+}
+
+func generateKey(keyType KeyType, bits int) (crypto.PrivateKey, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		if bits != 0 && bits != 256 {
+			return nil, fmt.Errorf("ed25519 keys are 256 bits")
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, defaultECDSABits:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("ecdsa keys are 256, 384 or 521 bits")
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		if bits < minRSABits || bits > maxRSABits {
+			return nil, fmt.Errorf("rsa keys must be between %d and %d bits", minRSABits, maxRSABits)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", keyType)
+}
+
+// Describe returns the type, size, fingerprint and randomart of key
+func Describe(key ssh.PublicKey, comment string) Info {
+	return Info{
+		Type:        key.Type(),
+		Bits:        Bits(key),
+		Comment:     comment,
+		Fingerprint: ssh.FingerprintSHA256(key),
+		RandomArt:   RandomArt(key),
+	}
+}
+
+// Bits returns the key size ssh-keygen reports, 0 for unknown key types
+func Bits(key ssh.PublicKey) int {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		key = cert.Key
+	}
+	crypted, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return 0
+	}
+	switch k := crypted.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+// AuthorizedKey formats key as an authorized_keys line, without the
+// trailing newline
+func AuthorizedKey(key ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if comment = strings.TrimSpace(comment); comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+// ParsePublicKey parses an authorized_keys line or .pub file and returns
+// the key with its comment
+func ParsePublicKey(data string) (ssh.PublicKey, string, error) {
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return key, comment, nil
+}
+
+// FileName is the name ssh-keygen gives a private key of this type, e.g.
+// id_ed25519; the public key adds .pub
+func FileName(keyType string) string {
+	switch {
+	case keyType == ssh.KeyAlgoED25519:
+		return "id_ed25519"
+	case strings.HasPrefix(keyType, "ecdsa-"):
+		return "id_ecdsa"
+	case keyType == ssh.KeyAlgoRSA:
+		return "id_rsa"
+	}
+	return "id_key"
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// versionField holds the format version in every single-file store. Files
+// from before versioning have none and count as version 0.
+const versionField = "version"
+
+// ErrNewerFormat is returned for a file written by a newer release
+var ErrNewerFormat = errors.New("file was written by a newer version of the app")
+
+// fileMigration upgrades a store file's document to version from the one
+// before it
+type fileMigration struct {
+	version int
+	name    string
+	apply   func(doc map[string]interface{}) error
+}
+
+// fileMigrations lists each versioned store's migrations, oldest first.
+// Versions start at 1 and have no gaps; the frontend writes the latest.
+var fileMigrations = map[Store][]fileMigration{
+	StoreConfig: {
+		{version: 1, name: "add version field", apply: func(map[string]interface{}) error { return nil }},
+	},
+	StoreConnections: {
+		{version: 1, name: "add version field", apply: requireList("connections")},
+	},
+	StoreCommands: {
+		{version: 1, name: "add version field", apply: requireList("snippets")},
+	},
+}
+
+// FileVersion returns the format version the app writes for a store, 0 if
+// the store isn't versioned
+func FileVersion(store Store) int {
+	return len(fileMigrations[store])
+}
+
+// FileMigrationResult counts the files MigrateFiles upgraded and those it
+// left alone because they failed to migrate
+type FileMigrationResult struct {
+	Migrated int `json:"migrated"`
+	Failed   int `json:"failed"`
+}
+
+// MigrateFiles brings every profile's versioned store files up to the
+// current format. Each file is copied into backupDir before it changes. A
+// file whose migration fails is left untouched and logged, so one bad
+// file doesn't hold back the others.
+func (s *Scope) MigrateFiles(backupDir string) (FileMigrationResult, error) {
+	var result FileMigrationResult
+	profiles, err := s.Profiles()
+	if err != nil {
+		return result, err
+	}
+	for _, profile := range profiles {
+		for _, store := range []Store{StoreConfig, StoreConnections, StoreCommands} {
+			path, err := s.Path(store, profile, "")
+			if err != nil {
+				log.Printf("[APP] Not migrating %s of profile %q: %v", store, profile, err)
+				result.Failed++
+				continue
+			}
+			migrated, err := migrateFile(path, store, profile, backupDir)
+			if err != nil {
+				log.Printf("[APP] Not migrating %s: %v", path, err)
+				result.Failed++
+			} else if migrated {
+				result.Migrated++
+			}
+		}
+	}
+	return result, nil
+}
+
+// migrateFile upgrades one store file under its lock. It reports whether
+// the file changed; a missing file is left missing.
+func migrateFile(path string, store Store, profile, backupDir string) (bool, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	migrated := false
+	err := Update(path, func(current []byte) ([]byte, error) {
+		if current == nil {
+			return nil, errors.New("file disappeared")
+		}
+		doc, from, err := decodeVersioned(current)
+		if err != nil {
+			return nil, err
+		}
+		pending := fileMigrations[store]
+		if from > len(pending) {
+			return nil, fmt.Errorf("format version %d: %w", from, ErrNewerFormat)
+		}
+		pending = pending[from:]
+		if len(pending) == 0 {
+			return current, nil
+		}
+
+		// Nothing is written unless every migration succeeds
+		for _, m := range pending {
+			if err := m.apply(doc); err != nil {
+				return nil, fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+			}
+			doc[versionField] = m.version
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%s-%s-%s-v%d.json", profileLabel(profile), store, time.Now().UTC().Format("20060102-150405.000"), from)
+		if err := EnsureDir(backupDir); err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		if err := writeAtomic(filepath.Join(backupDir, name), current); err != nil {
+			return nil, fmt.Errorf("not migrating without a backup: %w", err)
+		}
+		log.Printf("[APP] Migrated %s from version %d to %d, backup in %s", path, from, FileVersion(store), name)
+		migrated = true
+		return data, nil
+	})
+	return migrated, err
+}
+
+// decodeVersioned parses a store file into a document and its version
+func decodeVersioned(data []byte) (map[string]interface{}, int, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keeps large numbers, such as millisecond timestamps, exact
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, 0, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if doc == nil {
+		return nil, 0, errors.New("not a JSON object")
+	}
+
+	raw, ok := doc[versionField]
+	if !ok {
+		return doc, 0, nil
+	}
+	number, ok := raw.(json.Number)
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid %s field: %v", versionField, raw)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return nil, 0, fmt.Errorf("invalid %s field: %v", versionField, raw)
+	}
+	return doc, int(version), nil
+}
+
+// requireList returns a migration making sure doc[key] is a list, adding
+// an empty one if it is missing
+func requireList(key string) func(doc map[string]interface{}) error {
+	return func(doc map[string]interface{}) error {
+		value, ok := doc[key]
+		if !ok || value == nil {
+			doc[key] = []interface{}{}
+			return nil
+		}
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("%s is not a list", key)
+		}
+		return nil
+	}
+}
+
+// profileLabel names a profile in backup file names
+func profileLabel(profile string) string {
+	if profile == "" {
+		return guestProfile
+	}
+	return "user-" + profile
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantMigrated bool
+		wantErr      error
+		wantErrMsg   string
+		// want is a substring of the file afterwards; unchanged files must
+		// still equal data
+		want string
+	}{
+		{name: "unversioned", data: `{"connections":[{"id":"a"}]}`, wantMigrated: true, want: `"version": 1`},
+		{name: "missing list", data: `{}`, wantMigrated: true, want: `"connections": []`},
+		{name: "exact timestamps", data: `{"connections":[{"createdAt":1700000000000123456}]}`, wantMigrated: true, want: `1700000000000123456`},
+		{name: "current", data: `{"version":1,"connections":[]}`},
+		{name: "newer", data: `{"version":2,"connections":[]}`, wantErr: ErrNewerFormat},
+		{name: "list of the wrong type", data: `{"connections":{}}`, wantErrMsg: "not a list"},
+		{name: "invalid version", data: `{"version":"one"}`, wantErrMsg: "invalid version"},
+		{name: "invalid JSON", data: `{"connections":`, wantErrMsg: "invalid JSON"},
+		{name: "not an object", data: `null`, wantErrMsg: "not a JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "connections.json")
+			backupDir := filepath.Join(dir, "backups")
+			if err := os.WriteFile(path, []byte(tt.data), FilePerm); err != nil {
+				t.Fatal(err)
+			}
+
+			migrated, err := migrateFile(path, StoreConnections, "", backupDir)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("migrateFile error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantErrMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Fatalf("migrateFile error = %v, want one containing %q", err, tt.wantErrMsg)
+				}
+			case err != nil:
+				t.Fatalf("migrateFile: %v", err)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			backups, _ := os.ReadDir(backupDir)
+			if !tt.wantMigrated {
+				if string(data) != tt.data {
+					t.Errorf("file = %s, want it untouched", data)
+				}
+				if len(backups) != 0 {
+					t.Errorf("backups = %v, want none", backups)
+				}
+				return
+			}
+
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("file = %s, want it to contain %s", data, tt.want)
+			}
+			if !json.Valid(data) {
+				t.Errorf("migrated file is not JSON: %s", data)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("backups = %v, want one", backups)
+			}
+			backup, err := os.ReadFile(filepath.Join(backupDir, backups[0].Name()))
+			if err != nil || string(backup) != tt.data {
+				t.Errorf("backup = %q, %v; want the original file", backup, err)
+			}
+		})
+	}
+}
+
+func TestMigrateFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "connections.json")
+	migrated, err := migrateFile(path, StoreConnections, "", t.TempDir())
+	if err != nil || migrated {
+		t.Fatalf("migrateFile = %v, %v; want nothing done", migrated, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("missing file was created: %v", err)
+	}
+}
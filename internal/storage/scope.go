@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Store is a named area of the app data directory the frontend may use
+type Store string
+
+const (
+	StoreConfig      Store = "config"
+	StoreConnections Store = "connections"
+	StoreCommands    Store = "commands"
+	StoreWorkspaces  Store = "workspaces"
+	StoreBackups     Store = "backups"
+)
+
+// guestProfile is the directory used for the empty profile
+const guestProfile = "guest"
+
+// ErrInvalidPath is returned for names that would leave their store
+var ErrInvalidPath = errors.New("invalid storage path")
+
+// storeLayout places a store within a profile directory. Single-file
+// stores are one file; the others are directories of named files.
+type storeLayout struct {
+	path string
+	dir  bool
+}
+
+var stores = map[Store]storeLayout{
+	StoreConfig:      {path: "config.json"},
+	StoreConnections: {path: "connections.json"},
+	StoreCommands:    {path: "commands.json"},
+	StoreWorkspaces:  {path: "workspaces", dir: true},
+	StoreBackups:     {path: "backups", dir: true},
+}
+
+// safeName allows plain file names only: no separators, no leading dot
+var safeName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// Scope resolves named stores under a root directory, so callers can only
+// name files, never paths. Each profile (a user ID, or "" for guest mode)
+// has its own copy of every store.
+type Scope struct {
+	root string
+}
+
+// NewScope returns a Scope rooted at root
+func NewScope(root string) *Scope {
+	return &Scope{root: root}
+}
+
+// Path returns the file for name in a profile's store. Single-file stores
+// take an empty name.
+func (s *Scope) Path(store Store, profile, name string) (string, error) {
+	layout, ok := stores[store]
+	if !ok {
+		return "", fmt.Errorf("unknown store: %s", store)
+	}
+	profileDir, err := s.profileDir(profile)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(profileDir, layout.path)
+	if layout.dir {
+		if err := checkName(name); err != nil {
+			return "", err
+		}
+		path = filepath.Join(path, name)
+	} else if name != "" {
+		return "", fmt.Errorf("store %s holds a single file: %w", store, ErrInvalidPath)
+	}
+	return path, s.checkPath(path)
+}
+
+// List returns the file names in a profile's directory store, sorted
+func (s *Scope) List(store Store, profile string) ([]string, error) {
+	layout, ok := stores[store]
+	if !ok {
+		return nil, fmt.Errorf("unknown store: %s", store)
+	}
+	if !layout.dir {
+		return nil, fmt.Errorf("store %s holds a single file", store)
+	}
+	profileDir, err := s.profileDir(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(profileDir, layout.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && checkName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Profiles lists guest mode ("") and every user profile with a directory
+func (s *Scope) Profiles() ([]string, error) {
+	profiles := []string{""}
+	entries, err := os.ReadDir(filepath.Join(s.root, "users"))
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && checkName(entry.Name()) == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	return profiles, nil
+}
+
+func (s *Scope) profileDir(profile string) (string, error) {
+	if profile == "" {
+		return filepath.Join(s.root, guestProfile), nil
+	}
+	if err := checkName(profile); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, "users", profile), nil
+}
+
+// checkPath makes sure path is inside the root and isn't a symlink, which
+// could point anywhere
+func (s *Scope) checkPath(path string) error {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ErrInvalidPath
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink: %w", filepath.Base(path), ErrInvalidPath)
+	}
+	return nil
+}
+
+func checkName(name string) error {
+	if !safeName.MatchString(name) || strings.Contains(name, "..") || IsInternalFile(name) {
+		return fmt.Errorf("%q: %w", name, ErrInvalidPath)
+	}
+	return nil
+}
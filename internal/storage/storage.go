@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// DirPerm is used for every directory the app creates
+	DirPerm os.FileMode = 0700
+	// FilePerm is used for every file the app writes
+	FilePerm os.FileMode = 0600
+)
+
+const (
+	// appDirName is the app's directory under the user config directory
+	appDirName = "host-vault"
+	lockSuffix = ".lock"
+	tempInfix  = ".tmp-"
+)
+
+// ErrFileChanged is returned by WriteFileIfUnchanged when another writer
+// got there first
+var ErrFileChanged = errors.New("file changed on disk")
+
+// EnsureDir creates dir with DirPerm and tightens an existing one that is
+// more permissive
+func EnsureDir(dir string) error {
+	if err := os.MkdirAll(dir, DirPerm); err != nil {
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&^DirPerm != 0 {
+		return os.Chmod(dir, DirPerm)
+	}
+	return nil
+}
+
+// AppDataDir returns the app data directory without creating it:
+// %APPDATA%\host-vault on Windows, ~/.config/host-vault on Linux and
+// ~/Library/Application Support/host-vault on macOS
+func AppDataDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no user config directory: %w", err)
+	}
+	dir := filepath.Join(base, appDirName)
+	adoptLegacyDir(dir)
+	return dir, nil
+}
+
+// adoptLegacyDir moves ~/AppData/Roaming/host-vault, where earlier releases
+// kept known_hosts outside Windows, to dir unless dir already exists
+func adoptLegacyDir(dir string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	legacy := filepath.Join(home, "AppData", "Roaming", appDirName)
+	if _, err := os.Lstat(dir); !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if info, err := os.Lstat(legacy); err != nil || !info.IsDir() {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), DirPerm); err != nil {
+		return
+	}
+	if err := os.Rename(legacy, dir); err != nil {
+		log.Printf("[APP] Failed to move %s to %s: %v", legacy, dir, err)
+		return
+	}
+	log.Printf("[APP] Moved app data from %s to %s", legacy, dir)
+}
+
+// IsInternalFile reports whether name is a lock or temp file of this
+// package rather than data, so directory listings can skip it
+func IsInternalFile(name string) bool {
+	return strings.HasSuffix(name, lockSuffix) ||
+		(strings.HasPrefix(name, ".") && strings.Contains(name, tempInfix))
+}
+
+// lockFile takes the advisory lock guarding path. The lock lives in a
+// sibling ".lock" file so the data file itself can be replaced by rename.
+func lockFile(path string) (*flock.Flock, error) {
+	lock := flock.New(path + lockSuffix)
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", filepath.Base(path), err)
+	}
+	return lock, nil
+}
+
+// Lock takes path's advisory lock for callers that change the file in
+// place, e.g. by appending, and returns the function releasing it
+func Lock(path string) (func(), error) {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lock, err := lockFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return func() { lock.Unlock() }, nil
+}
+
+// WriteFile replaces path with data atomically: the data goes to a temp
+// file in the same directory which is synced and renamed over path, all
+// under the file's advisory lock
+func WriteFile(path string, data []byte) error {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return writeAtomic(path, data)
+}
+
+// Update runs a read-modify-write cycle under path's lock. update receives
+// the current contents (nil if the file doesn't exist) and returns the new
+// ones, so changes made meanwhile by another instance are never lost.
+func Update(path string, update func(current []byte) ([]byte, error)) error {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err := update(current)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+// Version identifies the file's current contents by modification time and
+// size; empty if it doesn't exist
+func Version(path string) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36), nil
+}
+
+// WriteFileIfUnchanged writes path only if it is still at version (as
+// returned by Version when it was read) and returns the new version.
+// Otherwise ErrFileChanged tells the caller to reload and retry.
+func WriteFileIfUnchanged(path string, data []byte, version string) (string, error) {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	lock, err := lockFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	current, err := Version(path)
+	if err != nil {
+		return "", err
+	}
+	if current != version {
+		return "", ErrFileChanged
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return "", err
+	}
+	return Version(path)
+}
+
+// WriteExport writes data atomically to a path the user picked, readable
+// by the owner only. Unlike WriteFile it leaves the directory's permissions
+// alone and takes no lock, so nothing but the file appears beside it.
+func WriteExport(path string, data []byte) error {
+	return writeAtomic(path, data)
+}
+
+// writeAtomic does the temp file, fsync and rename dance (must be called
+// with the lock held, except for exports)
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	// CreateTemp uses 0600, so the renamed file ends up with FilePerm
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+tempInfix+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// Removing is a no-op once the rename succeeded
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileIfUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare sets the file up and returns the version the write is
+		// made against
+		prepare func(t *testing.T, path string) string
+		wantErr error
+		want    string
+	}{
+		{
+			name:    "new file",
+			prepare: func(t *testing.T, path string) string { return "" },
+			want:    "mine",
+		},
+		{
+			name: "unchanged",
+			prepare: func(t *testing.T, path string) string {
+				return writeVersion(t, path, "original")
+			},
+			want: "mine",
+		},
+		{
+			name: "changed by another writer",
+			prepare: func(t *testing.T, path string) string {
+				version := writeVersion(t, path, "original")
+				writeVersion(t, path, "theirs, which is longer")
+				return version
+			},
+			wantErr: ErrFileChanged,
+			want:    "theirs, which is longer",
+		},
+		{
+			name: "created by another writer",
+			prepare: func(t *testing.T, path string) string {
+				writeVersion(t, path, "theirs")
+				return ""
+			},
+			wantErr: ErrFileChanged,
+			want:    "theirs",
+		},
+		{
+			name: "removed by another writer",
+			prepare: func(t *testing.T, path string) string {
+				version := writeVersion(t, path, "original")
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+				return version
+			},
+			wantErr: ErrFileChanged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.json")
+			version := tt.prepare(t, path)
+
+			newVersion, err := WriteFileIfUnchanged(path, []byte("mine"), version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteFileIfUnchanged error = %v, want %v", err, tt.wantErr)
+			}
+			data, readErr := os.ReadFile(path)
+			if readErr != nil && !os.IsNotExist(readErr) {
+				t.Fatal(readErr)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file = %q, want %q", data, tt.want)
+			}
+			if err != nil {
+				return
+			}
+
+			if current, _ := Version(path); newVersion != current {
+				t.Errorf("returned version %s, file is at %s", newVersion, current)
+			}
+			// The returned version is good for the next write
+			if _, err := WriteFileIfUnchanged(path, []byte("mine again"), newVersion); err != nil {
+				t.Errorf("write against the returned version: %v", err)
+			}
+		})
+	}
+}
+
+// writeVersion writes data to path and returns the resulting version
+func writeVersion(t *testing.T, path, data string) string {
+	t.Helper()
+	if err := WriteFile(path, []byte(data)); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	version, err := Version(path)
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	return version
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	const writers = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Update(path, func(current []byte) ([]byte, error) {
+				n, _ := strconv.Atoi(string(current))
+				return []byte(strconv.Itoa(n + 1)), nil
+			})
+			if err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != strconv.Itoa(writers) {
+		t.Errorf("counter = %s, want %d", data, writers)
+	}
+}
+
+func TestWriteExportLeavesDirectoryAlone(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "export.txt")
+
+	if err := WriteExport(path, []byte("data")); err != nil {
+		t.Fatalf("WriteExport: %v", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("directory mode = %v, want 0755", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "export.txt" {
+		t.Errorf("directory holds %v, want only the export", entries)
+	}
+	info, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != FilePerm {
+		t.Errorf("export mode = %v, want %v", info.Mode().Perm(), FilePerm)
+	}
+}
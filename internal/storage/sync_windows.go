@@ -0,0 +1,9 @@
+//go:build windows
+
+package storage
+
+// syncDir is a no-op on Windows, where directories can't be opened for
+// syncing and MoveFileEx already writes the rename through
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package sysevents reports system events the app reacts to, such as the
+// machine going to sleep or the screen being locked
+package sysevents
+
+import (
+	"context"
+	"errors"
+)
+
+// Event is a system event
+type Event string
+
+const (
+	Suspend    Event = "suspend"
+	ScreenLock Event = "screen-lock"
+)
+
+// ErrUnsupported means no system events can be watched on this platform
+var ErrUnsupported = errors.New("system events not supported on this platform")
+
+// Watch calls handle for each system event until ctx is done. It returns
+// once watching has started, or fails if no source of events is available.
+func Watch(ctx context.Context, handle func(Event)) error {
+	return watch(ctx, handle)
+}
@@ -0,0 +1,163 @@
+//go:build linux
+
+package sysevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	logindDest         = "org.freedesktop.login1"
+	logindPath         = dbus.ObjectPath("/org/freedesktop/login1")
+	logindManagerIface = "org.freedesktop.login1.Manager"
+	logindSessionIface = "org.freedesktop.login1.Session"
+)
+
+// screenSaverIfaces emit ActiveChanged(true) when the screen locks. Not
+// every desktop has logind send Lock for its own lock screen.
+var screenSaverIfaces = []string{"org.freedesktop.ScreenSaver", "org.gnome.ScreenSaver"}
+
+// watch listens to logind on the system bus for sleep and session locks,
+// and to the desktop's screen saver on the session bus
+func watch(ctx context.Context, handle func(Event)) error {
+	logindErr := watchLogind(ctx, handle)
+	if logindErr != nil {
+		log.Printf("[SYS] logind events unavailable: %v", logindErr)
+	}
+	screenSaverErr := watchScreenSaver(ctx, handle)
+	if screenSaverErr != nil {
+		log.Printf("[SYS] Screen saver events unavailable: %v", screenSaverErr)
+	}
+	if logindErr != nil && screenSaverErr != nil {
+		return errors.Join(logindErr, screenSaverErr)
+	}
+	return nil
+}
+
+func watchLogind(ctx context.Context, handle func(Event)) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return err
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindManagerIface),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	session, err := logindSession(conn)
+	if err != nil {
+		log.Printf("[SYS] No logind session, session locks not watched: %v", err)
+	} else if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(session),
+		dbus.WithMatchInterface(logindSessionIface),
+		dbus.WithMatchMember("Lock"),
+	); err != nil {
+		log.Printf("[SYS] Failed to watch session locks: %v", err)
+	}
+
+	go dispatch(ctx, conn, func(signal *dbus.Signal) {
+		switch signal.Name {
+		case logindManagerIface + ".PrepareForSleep":
+			// Sent with true before sleeping and false after waking
+			if starting, ok := firstBool(signal); ok && starting {
+				handle(Suspend)
+			}
+		case logindSessionIface + ".Lock":
+			if signal.Path == session {
+				handle(ScreenLock)
+			}
+		}
+	})
+	return nil
+}
+
+// logindSession finds the logind session the app runs in
+func logindSession(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	manager := conn.Object(logindDest, logindPath)
+
+	var session dbus.ObjectPath
+	err := manager.Call(logindManagerIface+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&session)
+	if err == nil {
+		return session, nil
+	}
+	// Processes started outside the session's scope, e.g. by a user
+	// service, still inherit its ID
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		if err := manager.Call(logindManagerIface+".GetSession", 0, id).Store(&session); err == nil {
+			return session, nil
+		}
+	}
+	return "", err
+}
+
+func watchScreenSaver(ctx context.Context, handle func(Event)) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+
+	for _, iface := range screenSaverIfaces {
+		err := conn.AddMatchSignal(
+			dbus.WithMatchInterface(iface),
+			dbus.WithMatchMember("ActiveChanged"),
+		)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to watch %s: %w", iface, err)
+		}
+	}
+
+	go dispatch(ctx, conn, func(signal *dbus.Signal) {
+		for _, iface := range screenSaverIfaces {
+			if signal.Name != iface+".ActiveChanged" {
+				continue
+			}
+			if active, ok := firstBool(signal); ok && active {
+				handle(ScreenLock)
+			}
+			return
+		}
+	})
+	return nil
+}
+
+// dispatch passes conn's signals to handle until ctx is done, then closes
+// conn
+func dispatch(ctx context.Context, conn *dbus.Conn, handle func(*dbus.Signal)) {
+	defer conn.Close()
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case signal, ok := <-signals:
+			if !ok {
+				return
+			}
+			handle(signal)
+		}
+	}
+}
+
+func firstBool(signal *dbus.Signal) (bool, bool) {
+	if len(signal.Body) == 0 {
+		return false, false
+	}
+	value, ok := signal.Body[0].(bool)
+	return value, ok
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sysevents
+
+import "context"
+
+func watch(ctx context.Context, handle func(Event)) error {
+	return ErrUnsupported
+}
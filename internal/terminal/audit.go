@@ -0,0 +1,119 @@
+package terminal
+
+import (
+	"host-vault/internal/audit"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// auditedConnection is an SSH connection recorded as opened and not yet
+// as closed
+type auditedConnection struct {
+	entry  audit.Entry
+	opened time.Time
+}
+
+// SetAuditLog sets where SSH connections and host key decisions are
+// recorded
+func (tm *TerminalManager) SetAuditLog(log *audit.Log) {
+	tm.auditMu.Lock()
+	tm.auditLog = log
+	tm.auditMu.Unlock()
+
+	if tm.knownHostsMgr != nil {
+		tm.knownHostsMgr.SetAuditLog(log)
+	}
+}
+
+// auditOpened records connection.opened for the session's current
+// connection
+func (tm *TerminalManager) auditOpened(session *SSHSession, config ConnectionConfig) {
+	conn := &auditedConnection{
+		entry: audit.Entry{
+			Host:         config.Host,
+			Port:         config.Port,
+			User:         config.Username,
+			AuthMethod:   session.AuthMethod(),
+			ConnectionID: session.GetMetadata().ConnectionID,
+			SessionID:    session.ID(),
+		},
+		opened: time.Now(),
+	}
+
+	tm.auditMu.Lock()
+	tm.audited[session.ID()] = conn
+	auditLog := tm.auditLog
+	tm.auditMu.Unlock()
+
+	entry := conn.entry
+	entry.Event = audit.EventConnectionOpened
+	auditLog.Record(entry)
+}
+
+// auditedConnection returns the session's open connection, or nil
+func (tm *TerminalManager) auditedConnection(sessionID string) *auditedConnection {
+	tm.auditMu.Lock()
+	defer tm.auditMu.Unlock()
+	return tm.audited[sessionID]
+}
+
+// auditClosed records connection.closed with the connection's duration.
+// Only the first call for a connection records anything, so the output
+// stream ending after a reconnect replaced it is not counted again.
+func (tm *TerminalManager) auditClosed(conn *auditedConnection) {
+	if conn == nil {
+		return
+	}
+
+	tm.auditMu.Lock()
+	current := tm.audited[conn.entry.SessionID] == conn
+	if current {
+		delete(tm.audited, conn.entry.SessionID)
+	}
+	auditLog := tm.auditLog
+	tm.auditMu.Unlock()
+
+	if current {
+		auditLog.Record(conn.closedEntry())
+	}
+}
+
+// auditCloseAll records every open connection as closed
+func (tm *TerminalManager) auditCloseAll() {
+	tm.auditMu.Lock()
+	conns := tm.audited
+	tm.audited = make(map[string]*auditedConnection)
+	auditLog := tm.auditLog
+	tm.auditMu.Unlock()
+
+	for _, conn := range conns {
+		auditLog.Record(conn.closedEntry())
+	}
+}
+
+func (c *auditedConnection) closedEntry() audit.Entry {
+	entry := c.entry
+	entry.Event = audit.EventConnectionClosed
+	entry.DurationMs = time.Since(c.opened).Milliseconds()
+	return entry
+}
+
+// SetAuditLog sets where accepted and mismatched host keys are recorded
+func (khm *KnownHostsManager) SetAuditLog(log *audit.Log) {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.auditLog = log
+}
+
+// auditHostKey records a host key event; detail says how it came about
+func auditHostKey(auditLog *audit.Log, event audit.Event, host string, port int, key ssh.PublicKey, detail string) {
+	auditLog.Record(audit.Entry{
+		Event:       event,
+		Host:        host,
+		Port:        port,
+		KeyType:     key.Type(),
+		Fingerprint: fingerprintSHA256(key),
+		Detail:      detail,
+	})
+}
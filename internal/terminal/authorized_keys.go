@@ -0,0 +1,161 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Remote scripts run under sh whatever the login shell is, from the home
+// directory. They contain no single quotes.
+const (
+	readAuthorizedKeysScript = `cd || exit 1; cat .ssh/authorized_keys 2>/dev/null || true`
+	// appendAuthorizedKeyScript creates ~/.ssh and authorized_keys with the
+	// permissions sshd's StrictModes insists on, then appends stdin on a
+	// line of its own
+	appendAuthorizedKeyScript = `umask 077; cd || exit 1; ` +
+		`mkdir -p .ssh && chmod 700 .ssh && touch .ssh/authorized_keys && chmod 600 .ssh/authorized_keys || exit 1; ` +
+		`if [ -s .ssh/authorized_keys ] && [ -n "$(tail -c 1 .ssh/authorized_keys)" ]; then echo >> .ssh/authorized_keys; fi; ` +
+		`cat >> .ssh/authorized_keys`
+)
+
+// KeyInstallResult reports what InstallPublicKey did
+type KeyInstallResult struct {
+	Added       bool   `json:"added"`    // false if the key was already authorized
+	Verified    bool   `json:"verified"` // logging in with the key worked afterwards
+	VerifyError string `json:"verifyError,omitempty"`
+	// SwitchedToKey is set by callers that moved the connection over to
+	// the key once it was verified
+	SwitchedToKey bool `json:"switchedToKey"`
+}
+
+// InstallPublicKeyForConnection is InstallPublicKey for a saved
+// connection, logging in with the credentials the resolver holds for it
+func (tm *TerminalManager) InstallPublicKeyForConnection(connectionID string, guestMode bool, authorizedKey, privateKey, passphrase string) (*KeyInstallResult, error) {
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return nil, err
+	}
+	return tm.InstallPublicKey(config, authorizedKey, privateKey, passphrase)
+}
+
+// InstallPublicKey does what ssh-copy-id does: it logs in with config's
+// credentials, appends authorizedKey to ~/.ssh/authorized_keys unless it
+// is already there, then logs in again with privateKey alone to check the
+// key works.
+func (tm *TerminalManager) InstallPublicKey(config ConnectionConfig, authorizedKey, privateKey, passphrase string) (*KeyInstallResult, error) {
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	signer, err := parsePrivateKey(ConnectionConfig{PrivateKey: privateKey, Passphrase: passphrase})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	if !bytes.Equal(signer.PublicKey().Marshal(), key.Marshal()) {
+		return nil, errors.New("private key does not match the public key")
+	}
+
+	authMethods, err := configAuthMethods(config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialSSH(config, authMethods, tm.knownHostsMgr, tm.promptHostKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	current, err := runRemote(client, readAuthorizedKeysScript, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized_keys: %w", err)
+	}
+
+	result := &KeyInstallResult{}
+	if !hasAuthorizedKey(current, key) {
+		// Rebuilt from the parsed key so nothing but one line gets appended
+		line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+		if comment != "" {
+			line += " " + comment
+		}
+		if _, err := runRemote(client, appendAuthorizedKeyScript, strings.NewReader(line+"\n")); err != nil {
+			return nil, fmt.Errorf("failed to update authorized_keys: %w", err)
+		}
+		result.Added = true
+		log.Printf("[SSH] Installed %s key on %s@%s:%d", key.Type(), config.Username, config.Host, config.Port)
+	}
+
+	// The host was just verified, so this is about the key alone
+	verifyClient, err := dialSSH(config, []ssh.AuthMethod{ssh.PublicKeys(signer)}, tm.knownHostsMgr, tm.promptHostKey)
+	if err != nil {
+		log.Printf("[SSH] Key login to %s@%s:%d failed: %v", config.Username, config.Host, config.Port, err)
+		result.VerifyError = err.Error()
+		return result, nil
+	}
+	verifyClient.Close()
+	result.Verified = true
+	return result, nil
+}
+
+// configAuthMethods offers the config's password and private key, in the
+// order sessions try them
+func configAuthMethods(config ConnectionConfig) ([]ssh.AuthMethod, error) {
+	var authMethods []ssh.AuthMethod
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
+	}
+	if config.PrivateKey != "" {
+		signer, err := parsePrivateKey(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if len(authMethods) == 0 {
+		return nil, errors.New("no password or private key to log in with")
+	}
+	return authMethods, nil
+}
+
+// hasAuthorizedKey reports whether an authorized_keys file already lists
+// key, whatever its options and comment
+func hasAuthorizedKey(data []byte, key ssh.PublicKey) bool {
+	want := key.Marshal()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		listed, _, _, _, err := ssh.ParseAuthorizedKey(scanner.Bytes())
+		if err == nil && bytes.Equal(listed.Marshal(), want) {
+			return true
+		}
+	}
+	return false
+}
+
+// runRemote runs a script under sh on the server, feeding it stdin, and
+// returns its output. stderr ends up in the error.
+func runRemote(client *ssh.Client, script string, stdin io.Reader) ([]byte, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run("exec sh -c '" + script + "'"); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,461 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"host-vault/internal/sshkeys"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// authorizedKeysReportConcurrency is how many hosts a report reads at once
+const authorizedKeysReportConcurrency = 8
+
+// minRSABits is the smallest RSA key not flagged as stale
+const minRSABits = 2048
+
+// writeAuthorizedKeysScript replaces authorized_keys with stdin through a
+// temp file, so sshd never sees it half written. A symlinked authorized_keys
+// is rewritten in place at its target so the link survives.
+const writeAuthorizedKeysScript = `umask 077; cd || exit 1; mkdir -p .ssh && chmod 700 .ssh || exit 1; ` +
+	`cat > .ssh/authorized_keys.host-vault && chmod 600 .ssh/authorized_keys.host-vault || exit 1; ` +
+	`if [ -L .ssh/authorized_keys ]; then ` +
+	`target=$(readlink -f .ssh/authorized_keys) && [ -n "$target" ] || ` +
+	`{ echo "cannot resolve the authorized_keys symlink" >&2; rm -f .ssh/authorized_keys.host-vault; exit 1; }; ` +
+	`cat .ssh/authorized_keys.host-vault > "$target"; status=$?; rm -f .ssh/authorized_keys.host-vault; exit $status; fi; ` +
+	`mv -f .ssh/authorized_keys.host-vault .ssh/authorized_keys`
+
+// Authorized key statuses
+const (
+	AuthorizedKeyKnown   = "known"   // matches a vault key
+	AuthorizedKeyUnknown = "unknown" // not in the vault
+	AuthorizedKeyStale   = "stale"   // expired, or of an algorithm too weak to keep
+)
+
+// ErrAuthorizedKeysChanged is returned by edits made against an older
+// version of authorized_keys than the one on the host
+var ErrAuthorizedKeysChanged = errors.New("authorized_keys changed on the host")
+
+// VaultKeyRef names the vault key an authorized key belongs to
+type VaultKeyRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AuthorizedKeyEntry is one key line of an authorized_keys file
+type AuthorizedKeyEntry struct {
+	Line        int    `json:"line"` // 1-based
+	KeyType     string `json:"keyType"`
+	Bits        int    `json:"bits"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+	// Options as written, e.g. from="10.0.0.0/8", command="uptime", restrict
+	Options     []string     `json:"options,omitempty"`
+	Status      string       `json:"status"`
+	StaleReason string       `json:"staleReason,omitempty"`
+	VaultKey    *VaultKeyRef `json:"vaultKey,omitempty"`
+}
+
+// AuthorizedKeys is a host's authorized_keys file as read
+type AuthorizedKeys struct {
+	ConnectionID string               `json:"connectionId"`
+	Host         string               `json:"host"`
+	Port         int                  `json:"port"`
+	User         string               `json:"user"`
+	Entries      []AuthorizedKeyEntry `json:"entries"`
+	// Invalid lists lines that are neither keys nor comments
+	Invalid []int `json:"invalid,omitempty"`
+	// Version identifies the contents read. Edits made against it are
+	// refused once the file has changed, so changes made meanwhile on the
+	// host aren't lost.
+	Version string `json:"version"`
+}
+
+// KeyAccess is a host a key grants access to
+type KeyAccess struct {
+	ConnectionID string   `json:"connectionId"`
+	Host         string   `json:"host"`
+	Port         int      `json:"port"`
+	User         string   `json:"user"`
+	Options      []string `json:"options,omitempty"`
+}
+
+// KeyGrant is one key and every host it opens
+type KeyGrant struct {
+	Fingerprint string       `json:"fingerprint"`
+	KeyType     string       `json:"keyType"`
+	Bits        int          `json:"bits"`
+	Comment     string       `json:"comment,omitempty"`
+	Status      string       `json:"status"`
+	StaleReason string       `json:"staleReason,omitempty"`
+	VaultKey    *VaultKeyRef `json:"vaultKey,omitempty"`
+	Access      []KeyAccess  `json:"access"`
+}
+
+// AuthorizedKeysHostResult is the outcome of reading one host for a report
+type AuthorizedKeysHostResult struct {
+	ConnectionID string `json:"connectionId"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	User         string `json:"user"`
+	Keys         int    `json:"keys"`
+	Error        string `json:"error,omitempty"`
+}
+
+// AuthorizedKeysReport says which keys grant access where. Keys are sorted
+// unknown first, then stale, then known.
+type AuthorizedKeysReport struct {
+	Keys        []KeyGrant                 `json:"keys"`
+	Hosts       []AuthorizedKeysHostResult `json:"hosts"`
+	Unknown     int                        `json:"unknown"`
+	Stale       int                        `json:"stale"`
+	Unreachable int                        `json:"unreachable"`
+}
+
+// ListAuthorizedKeys reads a saved connection's authorized_keys. known maps
+// fingerprints (SHA256:...) of vault keys to the keys, to classify entries.
+func (tm *TerminalManager) ListAuthorizedKeys(connectionID string, guestMode bool, known map[string]VaultKeyRef) (*AuthorizedKeys, error) {
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return nil, err
+	}
+	client, err := tm.dialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	data, err := runRemote(client, readAuthorizedKeysScript, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized_keys: %w", err)
+	}
+	keys := parseAuthorizedKeys(data, known)
+	keys.ConnectionID = connectionID
+	keys.Host, keys.Port, keys.User = config.Host, config.Port, config.Username
+	return keys, nil
+}
+
+// AddAuthorizedKey appends an authorized_keys line, options included, to a
+// saved connection's host
+func (tm *TerminalManager) AddAuthorizedKey(connectionID string, guestMode bool, line string) error {
+	key, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return fmt.Errorf("failed to parse authorized key: %w", err)
+	}
+
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return err
+	}
+	client, err := tm.dialConfig(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data, err := runRemote(client, readAuthorizedKeysScript, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read authorized_keys: %w", err)
+	}
+	if hasAuthorizedKey(data, key) {
+		return fmt.Errorf("%s is already authorized", ssh.FingerprintSHA256(key))
+	}
+	if _, err := runRemote(client, appendAuthorizedKeyScript, strings.NewReader(formatAuthorizedKey(options, key, comment)+"\n")); err != nil {
+		return fmt.Errorf("failed to update authorized_keys: %w", err)
+	}
+	log.Printf("[SSH] Authorized %s on %s@%s:%d", ssh.FingerprintSHA256(key), config.Username, config.Host, config.Port)
+	return nil
+}
+
+// RemoveAuthorizedKey removes every line for the key with fingerprint from
+// a saved connection's authorized_keys. version comes from
+// ListAuthorizedKeys; empty skips the check.
+func (tm *TerminalManager) RemoveAuthorizedKey(connectionID string, guestMode bool, fingerprint, version string) error {
+	return tm.editAuthorizedKeys(connectionID, guestMode, version, func(lines []string) ([]string, error) {
+		kept := lines[:0:0]
+		for _, line := range lines {
+			if key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err == nil && ssh.FingerprintSHA256(key) == fingerprint {
+				continue
+			}
+			kept = append(kept, line)
+		}
+		if len(kept) == len(lines) {
+			return nil, fmt.Errorf("%s is not authorized", fingerprint)
+		}
+		return kept, nil
+	})
+}
+
+// AnnotateAuthorizedKey sets the comment of the key with fingerprint in a
+// saved connection's authorized_keys, keeping its options. version is
+// checked like for RemoveAuthorizedKey.
+func (tm *TerminalManager) AnnotateAuthorizedKey(connectionID string, guestMode bool, fingerprint, comment, version string) error {
+	if strings.ContainsAny(comment, "\r\n") {
+		return errors.New("comment must be a single line")
+	}
+	return tm.editAuthorizedKeys(connectionID, guestMode, version, func(lines []string) ([]string, error) {
+		found := false
+		for i, line := range lines {
+			key, _, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+			if err != nil || ssh.FingerprintSHA256(key) != fingerprint {
+				continue
+			}
+			lines[i] = formatAuthorizedKey(options, key, strings.TrimSpace(comment))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("%s is not authorized", fingerprint)
+		}
+		return lines, nil
+	})
+}
+
+// AuthorizedKeysReport reads the authorized_keys of many saved connections
+// concurrently and groups the keys by who they let in where
+func (tm *TerminalManager) AuthorizedKeysReport(connectionIDs []string, guestMode bool, known map[string]VaultKeyRef) *AuthorizedKeysReport {
+	listings := make([]*AuthorizedKeys, len(connectionIDs))
+	report := &AuthorizedKeysReport{
+		Keys:  []KeyGrant{},
+		Hosts: make([]AuthorizedKeysHostResult, len(connectionIDs)),
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, authorizedKeysReportConcurrency)
+	for i, connectionID := range connectionIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, connectionID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := AuthorizedKeysHostResult{ConnectionID: connectionID}
+			listing, err := tm.ListAuthorizedKeys(connectionID, guestMode, known)
+			if err != nil {
+				result.Error = err.Error()
+				if config, resolveErr := tm.resolveConnection(connectionID, guestMode); resolveErr == nil {
+					result.Host, result.Port, result.User = config.Host, config.Port, config.Username
+				}
+			} else {
+				result.Host, result.Port, result.User = listing.Host, listing.Port, listing.User
+				result.Keys = len(listing.Entries)
+				listings[i] = listing
+			}
+			report.Hosts[i] = result
+		}(i, connectionID)
+	}
+	wg.Wait()
+
+	grants := make(map[string]*KeyGrant)
+	var order []string
+	for i, listing := range listings {
+		if listing == nil {
+			report.Unreachable++
+			continue
+		}
+		for _, entry := range listing.Entries {
+			grant, ok := grants[entry.Fingerprint]
+			if !ok {
+				grant = &KeyGrant{
+					Fingerprint: entry.Fingerprint,
+					KeyType:     entry.KeyType,
+					Bits:        entry.Bits,
+					Status:      entry.Status,
+					VaultKey:    entry.VaultKey,
+				}
+				grants[entry.Fingerprint] = grant
+				order = append(order, entry.Fingerprint)
+			}
+			if grant.Comment == "" {
+				grant.Comment = entry.Comment
+			}
+			// Expiry is per line, so a key is only stale if every line is
+			if grant.Status == AuthorizedKeyStale && entry.Status != AuthorizedKeyStale {
+				grant.Status = entry.Status
+			}
+			if grant.Status == AuthorizedKeyStale && grant.StaleReason == "" {
+				grant.StaleReason = entry.StaleReason
+			}
+			grant.Access = append(grant.Access, KeyAccess{
+				ConnectionID: connectionIDs[i],
+				Host:         listing.Host,
+				Port:         listing.Port,
+				User:         listing.User,
+				Options:      entry.Options,
+			})
+		}
+	}
+
+	rank := map[string]int{AuthorizedKeyUnknown: 0, AuthorizedKeyStale: 1, AuthorizedKeyKnown: 2}
+	for _, fingerprint := range order {
+		grant := grants[fingerprint]
+		switch grant.Status {
+		case AuthorizedKeyUnknown:
+			report.Unknown++
+		case AuthorizedKeyStale:
+			report.Stale++
+		}
+		report.Keys = append(report.Keys, *grant)
+	}
+	sort.SliceStable(report.Keys, func(i, j int) bool {
+		return rank[report.Keys[i].Status] < rank[report.Keys[j].Status]
+	})
+	return report
+}
+
+// editAuthorizedKeys rewrites a saved connection's authorized_keys with
+// edit, unless it no longer matches version
+func (tm *TerminalManager) editAuthorizedKeys(connectionID string, guestMode bool, version string, edit func(lines []string) ([]string, error)) error {
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return err
+	}
+	client, err := tm.dialConfig(config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data, err := runRemote(client, readAuthorizedKeysScript, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read authorized_keys: %w", err)
+	}
+	if version != "" && authorizedKeysVersion(data) != version {
+		return ErrAuthorizedKeysChanged
+	}
+
+	lines, err := edit(strings.Split(strings.TrimRight(string(data), "\n"), "\n"))
+	if err != nil {
+		return err
+	}
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if _, err := runRemote(client, writeAuthorizedKeysScript, strings.NewReader(content)); err != nil {
+		return fmt.Errorf("failed to write authorized_keys: %w", err)
+	}
+	log.Printf("[SSH] Updated authorized_keys on %s@%s:%d", config.Username, config.Host, config.Port)
+	return nil
+}
+
+// dialConfig logs in with config's own credentials
+func (tm *TerminalManager) dialConfig(config ConnectionConfig) (*ssh.Client, error) {
+	authMethods, err := configAuthMethods(config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dialSSH(config, authMethods, tm.knownHostsMgr, tm.promptHostKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return client, nil
+}
+
+// parseAuthorizedKeys classifies each key line of an authorized_keys file
+func parseAuthorizedKeys(data []byte, known map[string]VaultKeyRef) *AuthorizedKeys {
+	keys := &AuthorizedKeys{
+		Entries: []AuthorizedKeyEntry{},
+		Version: authorizedKeysVersion(data),
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		key, comment, options, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			keys.Invalid = append(keys.Invalid, lineNo)
+			continue
+		}
+
+		entry := AuthorizedKeyEntry{
+			Line:        lineNo,
+			KeyType:     key.Type(),
+			Bits:        sshkeys.Bits(key),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     comment,
+			Options:     options,
+			Status:      AuthorizedKeyUnknown,
+		}
+		if ref, ok := known[entry.Fingerprint]; ok {
+			entry.Status = AuthorizedKeyKnown
+			entry.VaultKey = &ref
+		}
+		if reason := staleReason(key, options); reason != "" {
+			entry.Status = AuthorizedKeyStale
+			entry.StaleReason = reason
+		}
+		keys.Entries = append(keys.Entries, entry)
+	}
+	return keys
+}
+
+// staleReason says why a key should no longer be authorized, or "" if
+// nothing is wrong with it
+func staleReason(key ssh.PublicKey, options []string) string {
+	if expiry, ok := expiryTime(options); ok && expiry.Before(time.Now()) {
+		return "expired " + expiry.Format("2006-01-02")
+	}
+	switch {
+	case key.Type() == ssh.KeyAlgoDSA:
+		return "DSA keys are no longer accepted by OpenSSH"
+	case key.Type() == ssh.KeyAlgoRSA && sshkeys.Bits(key) < minRSABits:
+		return fmt.Sprintf("RSA key shorter than %d bits", minRSABits)
+	}
+	return ""
+}
+
+// expiryTime reads an expiry-time="YYYYMMDD[HHMM[SS]][Z]" option. Like
+// sshd, times without Z are local.
+func expiryTime(options []string) (time.Time, bool) {
+	for _, option := range options {
+		name, value, ok := strings.Cut(option, "=")
+		if !ok || !strings.EqualFold(name, "expiry-time") {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		location := time.Local
+		if strings.HasSuffix(value, "Z") || strings.HasSuffix(value, "z") {
+			value, location = value[:len(value)-1], time.UTC
+		}
+		for _, layout := range []string{"20060102", "200601021504", "20060102150405"} {
+			if len(value) == len(layout) {
+				if expiry, err := time.ParseInLocation(layout, value, location); err == nil {
+					return expiry, true
+				}
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatAuthorizedKey writes an authorized_keys line
+func formatAuthorizedKey(options []string, key ssh.PublicKey, comment string) string {
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if len(options) > 0 {
+		line = strings.Join(options, ",") + " " + line
+	}
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+func authorizedKeysVersion(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,196 @@
+package terminal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func authorizedKeyLine(t *testing.T, key ssh.PublicKey, options, comment string) string {
+	t.Helper()
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	if options != "" {
+		line = options + " " + line
+	}
+	if comment != "" {
+		line += " " + comment
+	}
+	return line
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	known := newTestHostKey(t)
+	unknown := newTestHostKey(t)
+	expired := newTestHostKey(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	weak, err := ssh.NewPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := strings.Join([]string{
+		"# managed by hand",
+		authorizedKeyLine(t, known, `from="10.0.0.0/8",restrict`, "alice@laptop"),
+		"",
+		"not a key",
+		authorizedKeyLine(t, unknown, "", ""),
+		authorizedKeyLine(t, expired, `expiry-time="20000101"`, "old"),
+		authorizedKeyLine(t, weak, "", "weak"),
+	}, "\n") + "\n"
+
+	keys := parseAuthorizedKeys([]byte(data), map[string]VaultKeyRef{
+		ssh.FingerprintSHA256(known):   {ID: "k1", Name: "laptop"},
+		ssh.FingerprintSHA256(expired): {ID: "k2", Name: "old"},
+	})
+
+	sum := sha256.Sum256([]byte(data))
+	if keys.Version != hex.EncodeToString(sum[:]) {
+		t.Errorf("version = %s, want the SHA-256 of the file", keys.Version)
+	}
+	if len(keys.Invalid) != 1 || keys.Invalid[0] != 4 {
+		t.Errorf("invalid lines = %v, want [4]", keys.Invalid)
+	}
+
+	tests := []struct {
+		line    int
+		status  string
+		reason  string
+		vaultID string
+		options []string
+		comment string
+	}{
+		{line: 2, status: AuthorizedKeyKnown, vaultID: "k1", options: []string{`from="10.0.0.0/8"`, "restrict"}, comment: "alice@laptop"},
+		{line: 5, status: AuthorizedKeyUnknown},
+		{line: 6, status: AuthorizedKeyStale, reason: "expired 2000-01-01", vaultID: "k2", options: []string{`expiry-time="20000101"`}, comment: "old"},
+		{line: 7, status: AuthorizedKeyStale, reason: "RSA key shorter than 2048 bits", comment: "weak"},
+	}
+	if len(keys.Entries) != len(tests) {
+		t.Fatalf("entries = %+v, want %d", keys.Entries, len(tests))
+	}
+	for i, tt := range tests {
+		entry := keys.Entries[i]
+		if entry.Line != tt.line || entry.Status != tt.status || entry.StaleReason != tt.reason || entry.Comment != tt.comment {
+			t.Errorf("entry %d = %+v, want line %d %s %q %q", i, entry, tt.line, tt.status, tt.reason, tt.comment)
+		}
+		if strings.Join(entry.Options, ",") != strings.Join(tt.options, ",") {
+			t.Errorf("entry %d options = %v, want %v", i, entry.Options, tt.options)
+		}
+		if (entry.VaultKey == nil && tt.vaultID != "") || (entry.VaultKey != nil && entry.VaultKey.ID != tt.vaultID) {
+			t.Errorf("entry %d vault key = %+v, want %q", i, entry.VaultKey, tt.vaultID)
+		}
+	}
+}
+
+func TestExpiryTime(t *testing.T) {
+	tests := []struct {
+		option string
+		want   time.Time
+		ok     bool
+	}{
+		{option: `expiry-time="20300102"`, want: time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local), ok: true},
+		{option: `expiry-time="203001021504"`, want: time.Date(2030, 1, 2, 15, 4, 0, 0, time.Local), ok: true},
+		{option: `expiry-time="20300102150405Z"`, want: time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC), ok: true},
+		{option: `EXPIRY-TIME="20300102"`, want: time.Date(2030, 1, 2, 0, 0, 0, 0, time.Local), ok: true},
+		{option: `expiry-time="2030-01-02"`},
+		{option: `expiry-time="20301302"`},
+		{option: `restrict`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.option, func(t *testing.T) {
+			got, ok := expiryTime([]string{tt.option})
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("expiryTime = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFormatAuthorizedKeyRoundTrip(t *testing.T) {
+	key := newTestHostKey(t)
+	options := []string{`command="uptime"`, `from="10.0.0.0/8,192.0.2.1"`, "no-pty"}
+
+	line := formatAuthorizedKey(options, key, "renamed key")
+	parsed, comment, parsedOptions, _, err := ssh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		t.Fatalf("ParseAuthorizedKey(%q): %v", line, err)
+	}
+	if ssh.FingerprintSHA256(parsed) != ssh.FingerprintSHA256(key) || comment != "renamed key" {
+		t.Errorf("parsed %s %q, want the same key with the new comment", ssh.FingerprintSHA256(parsed), comment)
+	}
+	if strings.Join(parsedOptions, ";") != strings.Join(options, ";") {
+		t.Errorf("options = %v, want %v", parsedOptions, options)
+	}
+}
+
+func TestWriteAuthorizedKeysScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	const content = "ssh-ed25519 AAAA new\n"
+
+	tests := []struct {
+		name    string
+		symlink bool
+	}{
+		{name: "regular file"},
+		{name: "symlink", symlink: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			sshDir := filepath.Join(home, ".ssh")
+			if err := os.Mkdir(sshDir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(sshDir, "authorized_keys")
+			target := path
+			if tt.symlink {
+				target = filepath.Join(home, "dotfiles", "authorized_keys")
+				if err := os.Mkdir(filepath.Dir(target), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(target, path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(target, []byte("ssh-ed25519 AAAA old\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command("sh", "-c", writeAuthorizedKeysScript)
+			cmd.Env = append(os.Environ(), "HOME="+home)
+			cmd.Stdin = strings.NewReader(content)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("script: %v: %s", err, output)
+			}
+
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if isLink := info.Mode()&os.ModeSymlink != 0; isLink != tt.symlink {
+				t.Errorf("authorized_keys symlink = %v, want %v", isLink, tt.symlink)
+			}
+			if data, err := os.ReadFile(target); err != nil || string(data) != content {
+				t.Errorf("target = %q, %v; want %q", data, err, content)
+			}
+			if _, err := os.Stat(filepath.Join(sshDir, "authorized_keys.host-vault")); !os.IsNotExist(err) {
+				t.Errorf("temp file left behind: %v", err)
+			}
+		})
+	}
+}
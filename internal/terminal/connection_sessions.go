@@ -0,0 +1,72 @@
+package terminal
+
+import (
+	"fmt"
+	"log"
+)
+
+// connectionSessionIDs returns the sessions opened for saved connections,
+// i.e. with credentials from the resolver (must be called with mu held)
+func (tm *TerminalManager) connectionSessionIDs() []string {
+	var ids []string
+	for id, session := range tm.sessions {
+		if session.GetMetadata().ConnectionID != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// FreezeConnectionSessions stops input to sessions opened for saved
+// connections until ThawSessions, and returns their IDs. Output keeps
+// flowing so the connections stay alive.
+func (tm *TerminalManager) FreezeConnectionSessions() []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	ids := tm.connectionSessionIDs()
+	for _, id := range ids {
+		tm.frozen[id] = true
+	}
+	if len(ids) > 0 {
+		log.Printf("[TERM] Froze %d sessions", len(ids))
+	}
+	return ids
+}
+
+// ThawSessions lets input through to frozen sessions again and returns
+// their IDs
+func (tm *TerminalManager) ThawSessions() []string {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	ids := make([]string, 0, len(tm.frozen))
+	for id := range tm.frozen {
+		ids = append(ids, id)
+	}
+	tm.frozen = make(map[string]bool)
+	return ids
+}
+
+// CloseConnectionSessions closes the sessions opened for saved connections
+// and returns their IDs
+func (tm *TerminalManager) CloseConnectionSessions() []string {
+	tm.mu.RLock()
+	ids := tm.connectionSessionIDs()
+	tm.mu.RUnlock()
+
+	for _, id := range ids {
+		if err := tm.CloseSession(id); err != nil {
+			log.Printf("[TERM] Failed to close session %s: %v", id, err)
+		}
+	}
+	return ids
+}
+
+// checkFrozen fails for a frozen session (must be called with mu held)
+func (tm *TerminalManager) checkFrozen(sessionID string) error {
+	if tm.frozen[sessionID] {
+		return fmt.Errorf("session %s is frozen while saved credentials are locked", sessionID)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package terminal
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// cwdProbeInterval throttles direct cwd lookups to one per interval
+const cwdProbeInterval = 500 * time.Millisecond
+
+// parseOSC7 extracts the host and directory from an OSC 7 payload such as
+// "7;file://host/home/user/my%20dir". kitty's kitty-shell-cwd:// scheme is
+// accepted too.
+func parseOSC7(payload string) (host, dir string, ok bool) {
+	code, rest := splitOSC(payload)
+	if code != "7" || rest == "" {
+		return "", "", false
+	}
+
+	u, err := url.Parse(rest)
+	if err != nil || (u.Scheme != "file" && u.Scheme != "kitty-shell-cwd") || u.Path == "" {
+		return "", "", false
+	}
+
+	dir = u.Path
+	// file://host/C:/Users/me - drop the slash in front of the drive letter
+	if len(dir) >= 3 && dir[0] == '/' && dir[2] == ':' {
+		dir = strings.ReplaceAll(dir[1:], "/", `\`)
+	}
+	return u.Hostname(), dir, true
+}
+
+// isLocalHost reports whether an OSC 7 host names this machine. A shell
+// reached over ssh from inside a local terminal reports its own host, and
+// its directory means nothing here.
+func isLocalHost(host string) bool {
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	hostname, err := os.Hostname()
+	return err == nil && strings.EqualFold(host, hostname)
+}
@@ -0,0 +1,118 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HostKeyPolicy decides what happens to host keys that known_hosts can't
+// vouch for, after OpenSSH's StrictHostKeyChecking
+type HostKeyPolicy string
+
+const (
+	// HostKeyPolicyStrict rejects unknown and changed keys
+	HostKeyPolicyStrict HostKeyPolicy = "strict"
+	// HostKeyPolicyAcceptNew stores keys of hosts never seen before but
+	// rejects changed keys
+	HostKeyPolicyAcceptNew HostKeyPolicy = "accept-new"
+	// HostKeyPolicyAsk asks the user during the handshake about keys
+	// of hosts never seen before and rejects changed keys
+	HostKeyPolicyAsk HostKeyPolicy = "ask"
+	// HostKeyPolicyOff skips verification entirely and stores nothing
+	HostKeyPolicyOff HostKeyPolicy = "off"
+)
+
+// hostKeyPromptTimeout bounds how long a handshake waits for the user;
+// servers drop unauthenticated connections after a couple of minutes anyway
+const hostKeyPromptTimeout = 2 * time.Minute
+
+// ParseHostKeyPolicy accepts the policy names and OpenSSH's
+// yes/no spellings. Empty means strict.
+func ParseHostKeyPolicy(s string) (HostKeyPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "strict", "yes":
+		return HostKeyPolicyStrict, nil
+	case "accept-new":
+		return HostKeyPolicyAcceptNew, nil
+	case "ask":
+		return HostKeyPolicyAsk, nil
+	case "off", "no":
+		return HostKeyPolicyOff, nil
+	}
+	return "", fmt.Errorf("unknown host key policy: %s", s)
+}
+
+// hostKeyPromptFunc asks the user whether to trust a host key and blocks
+// until they answer
+type hostKeyPromptFunc func(host string, port int, info *HostKeyInfo) bool
+
+// HostKeyPromptEvent is emitted as terminal:host-key-prompt when a
+// connection using the "ask" policy meets a host not in known_hosts. Answer with RespondHostKeyPrompt.
+type HostKeyPromptEvent struct {
+	PromptID string      `json:"PromptID"`
+	Host     string      `json:"Host"`
+	Port     int         `json:"Port"`
+	HostKey  HostKeyInfo `json:"HostKey"`
+}
+
+// hostKeyVerifier builds the HostKeyCallback for a connection. trusted is
+// set when the server presented a plain key already in known_hosts; only
+// then are keys it announces later taken on.
+func hostKeyVerifier(config ConnectionConfig, knownHostsMgr *KnownHostsManager, prompt hostKeyPromptFunc, trusted *bool) (ssh.HostKeyCallback, error) {
+	policy, err := ParseHostKeyPolicy(string(config.HostKeyPolicy))
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == HostKeyPolicyOff || knownHostsMgr == nil {
+		// Fallback to insecure if known hosts manager is not available
+		log.Printf("[SSH] Host key verification disabled for %s:%d", config.Host, config.Port)
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		matched, verifyErr := knownHostsMgr.VerifyHostKey(config.Host, config.Port, remote, key)
+		if verifyErr == nil {
+			// Keys vouched for by a CA or by DNS alone don't get to add more
+			_, isCert := key.(*ssh.Certificate)
+			*trusted = matched == hostKeyKnown && !isCert
+			return nil
+		}
+
+		info, status, checkErr := knownHostsMgr.describeHostKey(config.Host, config.Port, remote, key)
+		// Revoked keys and certificates a trusted CA refused can't be overridden
+		if status == hostKeyRevoked || checkErr != nil {
+			return verifyErr
+		}
+
+		switch policy {
+		case HostKeyPolicyAcceptNew:
+			if status != hostKeyUnknown {
+				return verifyErr
+			}
+			log.Printf("[SSH] Accepting new %s host key for %s:%d", info.KeyType, config.Host, config.Port)
+		case HostKeyPolicyAsk:
+			// Like OpenSSH, only new hosts are asked about: a changed key
+			// is refused outright
+			if prompt == nil || status != hostKeyUnknown {
+				return verifyErr
+			}
+			if !prompt(config.Host, config.Port, info) {
+				return errors.New("host key rejected by user")
+			}
+		default:
+			return verifyErr
+		}
+
+		if err := knownHostsMgr.AddHostKey(config.Host, config.Port, key, config.GuestMode); err != nil {
+			log.Printf("[SSH] Failed to store host key for %s:%d: %v", config.Host, config.Port, err)
+		}
+		return nil
+	}, nil
+}
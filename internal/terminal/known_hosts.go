@@ -3,10 +3,15 @@ package terminal
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"host-vault/internal/audit"
+	"host-vault/internal/storage"
+	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,41 +19,111 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
-// KnownHostsManager manages SSH known_hosts file
+// KnownHostsManager manages SSH known_hosts files. Entries the app trusts
+// live in its own OpenSSH-format file; the user's ~/.ssh/known_hosts can be
+// consulted as an additional read-only source.
 type KnownHostsManager struct {
 	knownHostsPath string
+	version        string // storage.Version of knownHostsPath as last loaded or saved
+	systemPath     string
+	systemVersion  string
+	useSystem      bool
 	mu             sync.RWMutex
-	knownHosts     map[string]*KnownHostEntry
+	entries        []*KnownHostEntry
+	systemEntries  []*KnownHostEntry
+	seenPath       string
+	seenMu         sync.Mutex
+	seen           map[string]*hostKeySeen
+	sshfp          *sshfpResolver // nil unless SSHFP verification is enabled
+	auditLog       *audit.Log
 }
 
-// KnownHostEntry represents a known host entry
+// KnownHostEntry represents one known_hosts line
 type KnownHostEntry struct {
-	Host        string
-	Port        int
+	Host        string   // First plain host of the line, empty for hashed/wildcard lines
+	Port        int      // Port of Host
+	Patterns    []string // Host patterns exactly as written in the file
+	Marker      string   // "cert-authority", "revoked" or empty
 	Fingerprint string
 	KeyType     string
 	PublicKey   ssh.PublicKey
+	Comment     string
+	Source      string // File the entry came from, empty for in-memory entries
+	Guest       bool   // In-memory only, never written to disk
 }
 
 // HostKeyInfo contains information about a host's SSH key
 type HostKeyInfo struct {
-	FingerprintSHA256   string
-	FingerprintMD5      string
-	KeyType             string
-	KeyBase64           string // Base64 encoded public key for storage
-	IsKnown             bool
-	IsMismatch          bool
-	ExpectedFingerprint string
+	FingerprintSHA256   string   `json:"fingerprintSHA256"`
+	FingerprintMD5      string   `json:"fingerprintMD5"`
+	KeyType             string   `json:"keyType"`
+	KeyBase64           string   `json:"keyBase64"` // Base64 encoded public key for storage
+	IsKnown             bool     `json:"isKnown"`
+	IsMismatch          bool     `json:"isMismatch"`
+	IsRevoked           bool     `json:"isRevoked"`
+	IsCertified         bool     `json:"isCertified"` // Host certificate signed by a trusted @cert-authority
+	ExpectedFingerprint string   `json:"expectedFingerprint"`
+	KnownKeyTypes       []string `json:"knownKeyTypes"`   // Key types already trusted for the host
+	SSHFP               string   `json:"sshfp,omitempty"` // SSHFP DNS result when enabled: none, match, mismatch or error
+	SSHFPSecure         bool     `json:"sshfpSecure"`     // The SSHFP answer was DNSSEC validated
+}
+
+type hostKeyStatus int
+
+const (
+	hostKeyUnknown hostKeyStatus = iota
+	hostKeyKnown
+	hostKeyCertified
+	hostKeyMismatch
+	hostKeyRevoked
+	// hostKeySSHFP is a key VerifyHostKey accepted only because it matches
+	// a DNSSEC-validated SSHFP record; known_hosts doesn't vouch for it
+	hostKeySSHFP
+)
+
+func newKnownHostEntry(marker string, patterns []string, key ssh.PublicKey, comment, source string) *KnownHostEntry {
+	entry := &KnownHostEntry{
+		Patterns:    patterns,
+		Marker:      marker,
+		Fingerprint: fingerprintSHA256(key),
+		KeyType:     key.Type(),
+		PublicKey:   key,
+		Comment:     comment,
+		Source:      source,
+	}
+	for _, pattern := range patterns {
+		if isPlainHostPattern(pattern) {
+			entry.Host, entry.Port = parsePlainHostPattern(pattern)
+			break
+		}
+	}
+	return entry
+}
+
+// fingerprintSHA256 returns the base64 SHA256 fingerprint used throughout the app
+func fingerprintSHA256(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// plainHostKey returns the key a certificate was issued for, or key itself.
+// known_hosts lines only ever hold plain keys.
+func plainHostKey(key ssh.PublicKey) ssh.PublicKey {
+	if cert, ok := key.(*ssh.Certificate); ok {
+		return cert.Key
+	}
+	return key
 }
 
 // NewKnownHostsManager creates a new known hosts manager
 func NewKnownHostsManager(appDataPath string) (*KnownHostsManager, error) {
 	knownHostsPath := filepath.Join(appDataPath, "known_hosts")
-	
+
 	manager := &KnownHostsManager{
 		knownHostsPath: knownHostsPath,
-		knownHosts:     make(map[string]*KnownHostEntry),
+		seenPath:       filepath.Join(appDataPath, "known_hosts.seen.json"),
 	}
+	manager.loadSeen()
 
 	// Load existing known hosts
 	if err := manager.loadKnownHosts(); err != nil {
@@ -58,27 +133,60 @@ func NewKnownHostsManager(appDataPath string) (*KnownHostsManager, error) {
 		}
 	}
 
+	// The user's OpenSSH known_hosts is only consulted, read-only, once
+	// SetUseSystemKnownHosts turns it on
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		manager.systemPath = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+
 	return manager, nil
 }
 
+// SetUseSystemKnownHosts enables or disables ~/.ssh/known_hosts as a
+// read-only source of trusted keys. It is off until enabled.
+func (khm *KnownHostsManager) SetUseSystemKnownHosts(enabled bool) {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+
+	khm.useSystem = enabled && khm.systemPath != ""
+	khm.systemEntries = nil
+	if khm.useSystem {
+		khm.loadSystemKnownHosts()
+	}
+}
+
 // GetHostKeyInfo retrieves the host key fingerprint without connecting
 func (khm *KnownHostsManager) GetHostKeyInfo(host string, port int) (*HostKeyInfo, error) {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	
+	capturedKey, capturedRemote, err := captureHostKey(host, port, nil, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _, _ := khm.describeHostKey(host, port, capturedRemote, capturedKey)
+	return info, nil
+}
+
+// captureHostKey runs a handshake just far enough to see the server's host
+// key. algorithms restricts the host key algorithms offered (nil for the
+// library default).
+func captureHostKey(host string, port int, algorithms []string, timeout time.Duration) (ssh.PublicKey, net.Addr, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
 	// Capture the host key during handshake
 	var capturedKey ssh.PublicKey
-	var keyType string
-	
+	var capturedRemote net.Addr
+
 	config := &ssh.ClientConfig{
 		// Use a dummy user - SSH requires a username even if auth fails
 		User: "hostkey-check",
 		// This callback triggers as soon as the server presents its key
 		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
 			capturedKey = key
-			keyType = key.Type()
+			capturedRemote = remote
 			return nil
 		},
-		Timeout: 5 * time.Second,
+		HostKeyAlgorithms: algorithms,
+		Timeout:           timeout,
 		// No auth methods - we only want the handshake, not full authentication
 		Auth: []ssh.AuthMethod{},
 	}
@@ -86,12 +194,14 @@ func (khm *KnownHostsManager) GetHostKeyInfo(host string, port int) (*HostKeyInf
 	// Dial the TCP connection manually
 	netConn, err := net.DialTimeout("tcp", addr, config.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("network connection failed: %w", err)
+		return nil, nil, &hostUnreachableError{err: err}
 	}
 	defer netConn.Close()
+	// Bound the handshake too, not just the dial
+	netConn.SetDeadline(time.Now().Add(config.Timeout))
 
 	// Perform the SSH handshake
-	// This will almost certainly return an error (no auth), but the callback 
+	// This will almost certainly return an error (no auth), but the callback
 	// above will have already captured the key during the handshake phase.
 	sshConn, _, _, err := ssh.NewClientConn(netConn, addr, config)
 	if sshConn != nil {
@@ -101,92 +211,261 @@ func (khm *KnownHostsManager) GetHostKeyInfo(host string, port int) (*HostKeyInf
 	// Check if we captured the key (even if handshake/auth failed)
 	if capturedKey == nil {
 		if err != nil {
-			return nil, fmt.Errorf("could not capture host key during handshake: %w", err)
+			return nil, nil, fmt.Errorf("could not capture host key during handshake: %w", err)
 		}
-		return nil, fmt.Errorf("could not capture host key during handshake")
+		return nil, nil, fmt.Errorf("could not capture host key during handshake")
 	}
+	return capturedKey, capturedRemote, nil
+}
+
+// hostUnreachableError marks a failed TCP dial, as opposed to a server that
+// answered but couldn't agree on a host key
+type hostUnreachableError struct {
+	err error
+}
+
+func (e *hostUnreachableError) Error() string {
+	return "network connection failed: " + e.err.Error()
+}
+
+func (e *hostUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// describeHostKey reports a presented key and how it relates to
+// known_hosts. The error is checkHostKey's, set for revoked keys and
+// rejected certificates.
+func (khm *KnownHostsManager) describeHostKey(host string, port int, remote net.Addr, key ssh.PublicKey) (*HostKeyInfo, hostKeyStatus, error) {
+	// Fingerprints and storage always refer to the plain key, also when the
+	// server presented a certificate
+	plainKey := plainHostKey(key)
 
 	// Calculate fingerprints
-	fingerprintSHA256 := sha256.Sum256(capturedKey.Marshal())
-	fingerprintSHA256Str := base64.StdEncoding.EncodeToString(fingerprintSHA256[:])
-	
+	fingerprintSHA256Str := fingerprintSHA256(plainKey)
+
 	// MD5 fingerprint (legacy, but still useful)
-	fingerprintMD5 := ssh.FingerprintLegacyMD5(capturedKey)
-	
+	fingerprintMD5 := ssh.FingerprintLegacyMD5(plainKey)
+
 	// Encode the key for storage
-	keyBase64 := base64.StdEncoding.EncodeToString(capturedKey.Marshal())
+	keyBase64 := base64.StdEncoding.EncodeToString(plainKey.Marshal())
 
 	// Check if this host is known
 	khm.mu.RLock()
-	knownEntry := khm.getHostKey(host, port)
+	status, expectedFingerprint, err := khm.checkHostKey(host, port, remote, key)
+	knownKeyTypes := khm.knownKeyTypes(host, port, remote)
+	resolver := khm.sshfp
 	khm.mu.RUnlock()
-	
-	isKnown := knownEntry != nil
-	isMismatch := false
-	expectedFingerprint := ""
-	
-	if isKnown {
-		expectedFingerprint = knownEntry.Fingerprint
-		// Compare fingerprints
-		if knownEntry.Fingerprint != fingerprintSHA256Str {
-			isMismatch = true
-		}
+
+	var sshfpStatus string
+	var sshfpSecure bool
+	if resolver != nil {
+		sshfpStatus, sshfpSecure = resolver.check(host, key)
 	}
 
 	return &HostKeyInfo{
 		FingerprintSHA256:   fingerprintSHA256Str,
 		FingerprintMD5:      fingerprintMD5,
-		KeyType:             keyType,
+		KeyType:             key.Type(),
 		KeyBase64:           keyBase64,
-		IsKnown:             isKnown,
-		IsMismatch:          isMismatch,
+		IsKnown:             status != hostKeyUnknown,
+		IsMismatch:          status == hostKeyMismatch || status == hostKeyRevoked,
+		IsRevoked:           status == hostKeyRevoked,
+		IsCertified:         status == hostKeyCertified,
 		ExpectedFingerprint: expectedFingerprint,
-	}, nil
+		KnownKeyTypes:       knownKeyTypes,
+		SSHFP:               sshfpStatus,
+		SSHFPSecure:         sshfpSecure,
+	}, status, err
 }
 
-// VerifyHostKey verifies a host key against known hosts
-func (khm *KnownHostsManager) VerifyHostKey(host string, port int, remoteAddr net.Addr, key ssh.PublicKey) error {
+// VerifyHostKey verifies a host key against known hosts. Host certificates
+// signed by a matching @cert-authority are accepted once their principals
+// and validity check out; keys marked @revoked are always rejected. With
+// SSHFP auto-trust enabled, unknown keys published in DNS are accepted too.
+// The status tells how an accepted key matched: hostKeyKnown, hostKeyCertified
+// or hostKeySSHFP.
+func (khm *KnownHostsManager) VerifyHostKey(host string, port int, remoteAddr net.Addr, key ssh.PublicKey) (hostKeyStatus, error) {
+	khm.refresh()
+
 	khm.mu.RLock()
-	defer khm.mu.RUnlock()
+	status, fingerprint, err := khm.checkHostKey(host, port, remoteAddr, key)
+	if status == hostKeyKnown {
+		for _, entry := range khm.matchingEntries(host, port, remoteAddr) {
+			if entry.Fingerprint == fingerprint {
+				khm.markSeen(entry)
+				break
+			}
+		}
+	}
+	resolver := khm.sshfp
+	auditLog := khm.auditLog
+	khm.mu.RUnlock()
+
+	switch {
+	case status == hostKeyRevoked:
+		auditHostKey(auditLog, audit.EventHostKeyMismatch, host, port, key, "revoked")
+	case status == hostKeyMismatch && err != nil:
+		auditHostKey(auditLog, audit.EventHostKeyMismatch, host, port, key, err.Error())
+	case status == hostKeyMismatch:
+		auditHostKey(auditLog, audit.EventHostKeyMismatch, host, port, key, "differs from the known key")
+	}
 
-	hostKey := khm.getHostKey(host, port)
-	if hostKey == nil {
+	if err != nil {
+		return status, err
+	}
+
+	switch status {
+	case hostKeyUnknown:
+		// The DNS lookup runs without the lock held
+		if resolver != nil && resolver.config.AutoTrust {
+			sshfpStatus, secure := resolver.check(host, key)
+			if sshfpStatus == SSHFPMatch && secure {
+				log.Printf("[SSH] Trusting %s host key for %s:%d via DNSSEC-validated SSHFP", key.Type(), host, port)
+				auditHostKey(auditLog, audit.EventHostKeyAccepted, host, port, key, "SSHFP")
+				return hostKeySSHFP, nil
+			}
+			if sshfpStatus == SSHFPMatch {
+				log.Printf("[SSH] Not trusting %s host key for %s:%d via SSHFP: answer not DNSSEC validated", key.Type(), host, port)
+			}
+			if sshfpStatus == SSHFPMismatch {
+				log.Printf("[SSH] %s host key for %s:%d does not match its SSHFP records", key.Type(), host, port)
+			}
+		}
 		// Host not known - return error to trigger user prompt
-		return fmt.Errorf("host key not known")
+		return status, fmt.Errorf("host key not known")
+	case hostKeyMismatch:
+		return status, fmt.Errorf("host key mismatch - possible MITM attack")
 	}
+	return status, nil
+}
 
-	// Verify the fingerprint matches
-	fingerprintSHA256 := sha256.Sum256(key.Marshal())
-	fingerprintSHA256Str := base64.StdEncoding.EncodeToString(fingerprintSHA256[:])
+// SetSSHFPConfig configures host key verification against SSHFP DNS records
+func (khm *KnownHostsManager) SetSSHFPConfig(config SSHFPConfig) {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
 
-	if hostKey.Fingerprint != fingerprintSHA256Str {
-		return fmt.Errorf("host key mismatch - possible MITM attack")
+	if !config.Enabled {
+		khm.sshfp = nil
+		return
 	}
+	khm.sshfp = newSSHFPResolver(config)
+}
 
-	return nil
+// checkHostKey classifies a presented host key. For the plain-key states it
+// also returns the fingerprint on record. err is set for revoked keys and
+// for certificates from a trusted CA that fail validation (must be called
+// with lock held).
+func (khm *KnownHostsManager) checkHostKey(host string, port int, remote net.Addr, key ssh.PublicKey) (hostKeyStatus, string, error) {
+	if khm.isRevoked(key) {
+		return hostKeyRevoked, "", fmt.Errorf("host key revoked")
+	}
+
+	candidates := hostCandidates(host, port, remote)
+	if cert, ok := key.(*ssh.Certificate); ok && khm.isHostAuthority(cert.SignatureKey, candidates) {
+		checker := &ssh.CertChecker{
+			IsHostAuthority: func(auth ssh.PublicKey, _ string) bool {
+				return khm.isHostAuthority(auth, candidates)
+			},
+			IsRevoked: func(cert *ssh.Certificate) bool {
+				return khm.isRevoked(cert)
+			},
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		if err := checker.CheckHostKey(addr, remote, cert); err != nil {
+			return hostKeyMismatch, "", fmt.Errorf("host certificate rejected: %w", err)
+		}
+		return hostKeyCertified, "", nil
+	}
+
+	// Without a trusted CA a certificate is judged by its plain key, like OpenSSH does
+	matches := khm.matchingEntries(host, port, remote)
+	if len(matches) == 0 {
+		return hostKeyUnknown, "", nil
+	}
+
+	// A host may have keys of several types; only a different key of the
+	// presented type is a mismatch
+	plainKey := plainHostKey(key)
+	fingerprint := fingerprintSHA256(plainKey)
+	expected := ""
+	for _, entry := range matches {
+		if entry.Fingerprint == fingerprint {
+			return hostKeyKnown, entry.Fingerprint, nil
+		}
+		if entry.KeyType == plainKey.Type() && expected == "" {
+			expected = entry.Fingerprint
+		}
+	}
+	if expected == "" {
+		return hostKeyUnknown, "", nil
+	}
+	return hostKeyMismatch, expected, nil
 }
 
-// AddHostKey adds a host key to known hosts
+// knownKeyTypes lists the distinct key types trusted for a host (must be
+// called with lock held)
+func (khm *KnownHostsManager) knownKeyTypes(host string, port int, remote net.Addr) []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, entry := range khm.matchingEntries(host, port, remote) {
+		if !seen[entry.KeyType] {
+			seen[entry.KeyType] = true
+			types = append(types, entry.KeyType)
+		}
+	}
+	return types
+}
+
+// isHostAuthority reports whether auth is a @cert-authority for one of the
+// candidates (must be called with lock held)
+func (khm *KnownHostsManager) isHostAuthority(auth ssh.PublicKey, candidates []hostAddr) bool {
+	fingerprint := fingerprintSHA256(auth)
+	for _, entries := range [][]*KnownHostEntry{khm.entries, khm.systemEntries} {
+		for _, entry := range entries {
+			if entry.Marker == "cert-authority" && entry.Fingerprint == fingerprint &&
+				hostPatternsMatch(entry.Patterns, candidates) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRevoked reports whether key, or for a certificate its plain key or
+// signing CA, is listed as @revoked. Revocation applies regardless of the
+// line's host patterns (must be called with lock held).
+func (khm *KnownHostsManager) isRevoked(key ssh.PublicKey) bool {
+	keys := []ssh.PublicKey{key}
+	if cert, ok := key.(*ssh.Certificate); ok {
+		keys = append(keys, cert.Key, cert.SignatureKey)
+	}
+
+	for _, entries := range [][]*KnownHostEntry{khm.entries, khm.systemEntries} {
+		for _, entry := range entries {
+			if entry.Marker != "revoked" {
+				continue
+			}
+			for _, k := range keys {
+				if entry.Fingerprint == fingerprintSHA256(k) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// AddHostKey adds a host key to known hosts, replacing a key of the same
+// type previously stored for exactly this host and port. Keys of other
+// types are kept.
 // If isGuest is true, the key is only stored in memory for the current session
 // and not persisted to the filesystem (for privacy in guest mode)
 func (khm *KnownHostsManager) AddHostKey(host string, port int, key ssh.PublicKey, isGuest bool) error {
 	khm.mu.Lock()
 	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
 
-	// Calculate fingerprint
-	fingerprintSHA256 := sha256.Sum256(key.Marshal())
-	fingerprintSHA256Str := base64.StdEncoding.EncodeToString(fingerprintSHA256[:])
-
-	entry := &KnownHostEntry{
-		Host:        host,
-		Port:        port,
-		Fingerprint: fingerprintSHA256Str,
-		KeyType:     key.Type(),
-		PublicKey:   key,
-	}
-
-	hostKey := fmt.Sprintf("%s:%d", host, port)
-	khm.knownHosts[hostKey] = entry
+	khm.markAdded(true, khm.addHostKeyLocked(host, port, key, isGuest))
 
 	// Only save to file if not in guest mode (for privacy)
 	if isGuest {
@@ -197,106 +476,347 @@ func (khm *KnownHostsManager) AddHostKey(host string, port int, key ssh.PublicKe
 	return khm.saveKnownHosts()
 }
 
-// RemoveHostKey removes a host key from known hosts
+// addHostKeyLocked stores key for exactly this host and port, replacing a
+// key of the same type, and returns the new entry (must be called with lock held)
+func (khm *KnownHostsManager) addHostKeyLocked(host string, port int, key ssh.PublicKey, isGuest bool) *KnownHostEntry {
+	pattern := newHostAddr(host, port).normalized()
+	plainKey := plainHostKey(key)
+	khm.forgetSeen(khm.removePattern(pattern, plainKey.Type()))
+
+	source := khm.knownHostsPath
+	if isGuest {
+		source = ""
+	}
+	entry := newKnownHostEntry("", []string{pattern}, plainKey, "", source)
+	entry.Guest = isGuest
+	khm.entries = append(khm.entries, entry)
+	auditHostKey(khm.auditLog, audit.EventHostKeyAccepted, host, port, plainKey, "")
+	return entry
+}
+
+// RemoveHostKey removes all of a host's keys from known hosts
 func (khm *KnownHostsManager) RemoveHostKey(host string, port int) error {
 	khm.mu.Lock()
 	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
 
-	hostKey := fmt.Sprintf("%s:%d", host, port)
-	delete(khm.knownHosts, hostKey)
+	khm.forgetSeen(khm.removePattern(newHostAddr(host, port).normalized(), ""))
 
 	return khm.saveKnownHosts()
 }
 
-// getHostKey gets a known host entry (must be called with lock held)
-func (khm *KnownHostsManager) getHostKey(host string, port int) *KnownHostEntry {
-	hostKey := fmt.Sprintf("%s:%d", host, port)
-	return khm.knownHosts[hostKey]
+// ListKnownHosts returns every known_hosts line: the app's own entries
+// (guest ones included) followed by the read-only ~/.ssh/known_hosts ones
+func (khm *KnownHostsManager) ListKnownHosts() []KnownHostRecord {
+	khm.refresh()
+
+	khm.mu.RLock()
+	defer khm.mu.RUnlock()
+
+	records := make([]KnownHostRecord, 0, len(khm.entries)+len(khm.systemEntries))
+	for _, entries := range [][]*KnownHostEntry{khm.entries, khm.systemEntries} {
+		for _, entry := range entries {
+			firstSeen, lastSeen := khm.seenTimes(entry)
+			records = append(records, KnownHostRecord{
+				ID:          knownHostID(entry),
+				Host:        entry.Host,
+				Port:        entry.Port,
+				Patterns:    entry.Patterns,
+				Marker:      entry.Marker,
+				KeyType:     entry.KeyType,
+				Fingerprint: entry.Fingerprint,
+				FirstSeen:   firstSeen,
+				LastSeen:    lastSeen,
+				Source:      entry.Source,
+				Guest:       entry.Guest,
+				ReadOnly:    entry.Source != "" && entry.Source == khm.systemPath,
+			})
+		}
+	}
+	return records
 }
 
-// loadKnownHosts loads known hosts from file
-func (khm *KnownHostsManager) loadKnownHosts() error {
-	data, err := os.ReadFile(khm.knownHostsPath)
+// RemoveKnownHost removes a single line by its record ID. Lines from
+// ~/.ssh/known_hosts are read-only.
+func (khm *KnownHostsManager) RemoveKnownHost(id string) error {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
+
+	removed := khm.removeEntries(func(entry *KnownHostEntry) bool {
+		return knownHostID(entry) == id
+	})
+	if len(removed) == 0 {
+		for _, entry := range khm.systemEntries {
+			if knownHostID(entry) == id {
+				return fmt.Errorf("entry belongs to %s and is read-only", khm.systemPath)
+			}
+		}
+		return fmt.Errorf("known host not found: %s", id)
+	}
+	khm.forgetSeen(removed)
+
+	if removed[0].Guest {
+		return nil
+	}
+	return khm.saveKnownHosts()
+}
+
+// RekeyHostKey replaces every key stored for exactly this host and port
+// with key, e.g. after the server was rebuilt
+func (khm *KnownHostsManager) RekeyHostKey(host string, port int, key ssh.PublicKey, isGuest bool) error {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
+
+	pattern := newHostAddr(host, port).normalized()
+	khm.forgetSeen(khm.removePattern(pattern, ""))
+
+	source := khm.knownHostsPath
+	if isGuest {
+		source = ""
+	}
+	entry := newKnownHostEntry("", []string{pattern}, plainHostKey(key), "", source)
+	entry.Guest = isGuest
+	khm.entries = append(khm.entries, entry)
+	khm.markAdded(true, entry)
+	auditHostKey(khm.auditLog, audit.EventHostKeyAccepted, host, port, plainHostKey(key), "replaced the host's other keys")
+
+	// Dropped keys may have been persisted even when the new one is a guest key
+	return khm.saveKnownHosts()
+}
+
+// ForgetGuestHostKeys drops the in-memory guest entries and returns how
+// many were removed
+func (khm *KnownHostsManager) ForgetGuestHostKeys() int {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+
+	removed := khm.removeEntries(func(entry *KnownHostEntry) bool {
+		return entry.Guest
+	})
+	khm.forgetSeen(removed)
+	return len(removed)
+}
+
+// ImportKnownHosts merges the entries of an OpenSSH known_hosts file into
+// the app's own file and returns how many were new. An empty path imports
+// ~/.ssh/known_hosts.
+func (khm *KnownHostsManager) ImportKnownHosts(path string) (int, error) {
+	if path == "" {
+		path = khm.systemPath
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("failed to read known hosts: %w", err)
 	}
 
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
 
-		// Parse known_hosts format: host port key-type base64-key [comment]
-		parts := strings.Fields(line)
-		if len(parts) < 3 {
+	var added []*KnownHostEntry
+	for _, entry := range parseKnownHostsData(data, khm.knownHostsPath) {
+		if khm.hasLine(entry) {
 			continue
 		}
+		khm.entries = append(khm.entries, entry)
+		added = append(added, entry)
+	}
 
-		hostPort := parts[0]
-		keyType := parts[1]
-		base64Key := parts[2]
-
-		// Parse host:port
-		var host string
-		var port int
-		if strings.Contains(hostPort, ":") {
-			parts2 := strings.Split(hostPort, ":")
-			host = parts2[0]
-			fmt.Sscanf(parts2[1], "%d", &port)
-		} else {
-			host = hostPort
-			port = 22
-		}
+	if len(added) == 0 {
+		return 0, nil
+	}
+	khm.markAdded(false, added...)
+	return len(added), khm.saveKnownHosts()
+}
 
-		// Decode public key
-		keyBytes, err := base64.StdEncoding.DecodeString(base64Key)
-		if err != nil {
-			continue
+// ExportKnownHosts writes the app's persisted entries to path in OpenSSH format
+func (khm *KnownHostsManager) ExportKnownHosts(path string) error {
+	khm.mu.RLock()
+	data := khm.renderKnownHosts(false, nil)
+	khm.mu.RUnlock()
+
+	if err := storage.WriteExport(path, data); err != nil {
+		return fmt.Errorf("failed to export known hosts: %w", err)
+	}
+	return nil
+}
+
+// ExportKnownHostEntries writes only the listed records to path in OpenSSH
+// format. Guest entries are never exported.
+func (khm *KnownHostsManager) ExportKnownHostEntries(path string, ids []string) error {
+	include := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		include[id] = true
+	}
+
+	khm.mu.RLock()
+	data := khm.renderKnownHosts(false, include)
+	khm.mu.RUnlock()
+
+	if err := storage.WriteExport(path, data); err != nil {
+		return fmt.Errorf("failed to export known hosts: %w", err)
+	}
+	return nil
+}
+
+// matchingEntries returns the non-marker entries whose host patterns match
+// (must be called with lock held)
+func (khm *KnownHostsManager) matchingEntries(host string, port int, remote net.Addr) []*KnownHostEntry {
+	candidates := hostCandidates(host, port, remote)
+
+	var matches []*KnownHostEntry
+	for _, entries := range [][]*KnownHostEntry{khm.entries, khm.systemEntries} {
+		for _, entry := range entries {
+			if entry.Marker == "" && hostPatternsMatch(entry.Patterns, candidates) {
+				matches = append(matches, entry)
+			}
 		}
+	}
+	return matches
+}
 
-		pubKey, err := ssh.ParsePublicKey(keyBytes)
-		if err != nil {
+// removePattern drops entries written for exactly this single host
+// pattern, limited to keyType unless it is empty, and returns them (must be
+// called with lock held)
+func (khm *KnownHostsManager) removePattern(pattern, keyType string) []*KnownHostEntry {
+	return khm.removeEntries(func(entry *KnownHostEntry) bool {
+		return entry.Marker == "" && len(entry.Patterns) == 1 && entry.Patterns[0] == pattern &&
+			(keyType == "" || entry.KeyType == keyType)
+	})
+}
+
+// removeEntries drops the app entries matching drop and returns them (must
+// be called with lock held)
+func (khm *KnownHostsManager) removeEntries(drop func(*KnownHostEntry) bool) []*KnownHostEntry {
+	var removed []*KnownHostEntry
+	kept := make([]*KnownHostEntry, 0, len(khm.entries))
+	for _, entry := range khm.entries {
+		if drop(entry) {
+			removed = append(removed, entry)
 			continue
 		}
+		kept = append(kept, entry)
+	}
+	khm.entries = kept
+	return removed
+}
 
-		// Calculate fingerprint
-		fingerprintSHA256 := sha256.Sum256(pubKey.Marshal())
-		fingerprintSHA256Str := base64.StdEncoding.EncodeToString(fingerprintSHA256[:])
-
-		entry := &KnownHostEntry{
-			Host:        host,
-			Port:        port,
-			Fingerprint: fingerprintSHA256Str,
-			KeyType:     keyType,
-			PublicKey:   pubKey,
+// hasLine reports whether an equivalent line is already stored (must be called with lock held)
+func (khm *KnownHostsManager) hasLine(candidate *KnownHostEntry) bool {
+	for _, entry := range khm.entries {
+		if entry.Marker == candidate.Marker &&
+			entry.Fingerprint == candidate.Fingerprint &&
+			strings.Join(entry.Patterns, ",") == strings.Join(candidate.Patterns, ",") {
+			return true
 		}
+	}
+	return false
+}
 
-		hostKey := fmt.Sprintf("%s:%d", host, port)
-		khm.knownHosts[hostKey] = entry
+// loadKnownHosts loads known hosts from file
+func (khm *KnownHostsManager) loadKnownHosts() error {
+	// Taken before reading so a write in between triggers another reload
+	version, err := storage.Version(khm.knownHostsPath)
+	if err != nil {
+		return err
+	}
+	khm.version = version
+	data, err := os.ReadFile(khm.knownHostsPath)
+	if err != nil {
+		return err
+	}
+
+	// Files written before the OpenSSH format carry no header
+	if strings.HasPrefix(string(data), knownHostsHeader) {
+		khm.entries = parseKnownHostsData(data, khm.knownHostsPath)
+		return nil
 	}
 
+	khm.entries = parseLegacyKnownHosts(data, khm.knownHostsPath)
+	if len(khm.entries) > 0 {
+		// Rewrite in OpenSSH format right away
+		return khm.saveKnownHosts()
+	}
 	return nil
 }
 
-// saveKnownHosts saves known hosts to file
-func (khm *KnownHostsManager) saveKnownHosts() error {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(khm.knownHostsPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// loadSystemKnownHosts reads ~/.ssh/known_hosts; a missing or unreadable
+// file simply contributes nothing (must be called with lock held)
+func (khm *KnownHostsManager) loadSystemKnownHosts() {
+	khm.systemVersion, _ = storage.Version(khm.systemPath)
+	data, err := os.ReadFile(khm.systemPath)
+	if err != nil {
+		return
 	}
+	khm.systemEntries = parseKnownHostsData(data, khm.systemPath)
+}
 
-	var lines []string
-	for _, entry := range khm.knownHosts {
-		// Format: host port key-type base64-key
-		base64Key := base64.StdEncoding.EncodeToString(entry.PublicKey.Marshal())
-		line := fmt.Sprintf("%s:%d %s %s", entry.Host, entry.Port, entry.KeyType, base64Key)
-		lines = append(lines, line)
+// refresh picks up changes other app instances or the user made to the
+// files since they were loaded
+func (khm *KnownHostsManager) refresh() {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
+}
+
+// reloadIfChanged rereads known_hosts and ~/.ssh/known_hosts when their
+// version differs from the one loaded. In-memory guest entries are kept
+// (must be called with lock held).
+func (khm *KnownHostsManager) reloadIfChanged() {
+	if version, err := storage.Version(khm.knownHostsPath); err == nil && version != khm.version {
+		var guests []*KnownHostEntry
+		for _, entry := range khm.entries {
+			if entry.Guest {
+				guests = append(guests, entry)
+			}
+		}
+		khm.entries = nil
+		if err := khm.loadKnownHosts(); err != nil && !os.IsNotExist(err) {
+			log.Printf("[SSH] Failed to reload known hosts: %v", err)
+		}
+		khm.entries = append(khm.entries, guests...)
 	}
 
-	data := strings.Join(lines, "\n")
-	return os.WriteFile(khm.knownHostsPath, []byte(data), 0600)
+	if khm.useSystem {
+		if version, err := storage.Version(khm.systemPath); err == nil && version != khm.systemVersion {
+			khm.systemEntries = nil
+			khm.loadSystemKnownHosts()
+		}
+	}
 }
 
+// renderKnownHosts formats the persisted entries, optionally with the
+// format header. A non-nil include limits output to the listed record IDs
+// (must be called with lock held).
+func (khm *KnownHostsManager) renderKnownHosts(withHeader bool, include map[string]bool) []byte {
+	var lines []string
+	if withHeader {
+		lines = append(lines, knownHostsHeader)
+	}
+	for _, entry := range khm.entries {
+		if entry.Guest || (include != nil && !include[knownHostID(entry)]) {
+			continue
+		}
+		lines = append(lines, formatKnownHostsLine(entry))
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// saveKnownHosts saves known hosts to file. Mutations reload the file
+// first, so the write only fails if another instance saved in between; the
+// entries are then reloaded and the caller's change is dropped (must be
+// called with lock held).
+func (khm *KnownHostsManager) saveKnownHosts() error {
+	version, err := storage.WriteFileIfUnchanged(khm.knownHostsPath, khm.renderKnownHosts(true, nil), khm.version)
+	if errors.Is(err, storage.ErrFileChanged) {
+		khm.reloadIfChanged()
+		return fmt.Errorf("known hosts were modified by another instance, please retry")
+	}
+	if err != nil {
+		return err
+	}
+	khm.version = version
+	return nil
+}
@@ -0,0 +1,218 @@
+package terminal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// knownHostsHeader marks an app known_hosts file as OpenSSH formatted.
+// Files without it use the legacy "host:port type key" layout.
+const knownHostsHeader = "# host-vault known_hosts (OpenSSH format)"
+
+// hostAddr is a host and port candidate for known_hosts matching
+type hostAddr struct {
+	host string
+	port string
+}
+
+func newHostAddr(host string, port int) hostAddr {
+	return hostAddr{host: strings.Trim(host, "[]"), port: strconv.Itoa(port)}
+}
+
+// normalized returns the address as OpenSSH writes it: "host" for port 22,
+// "[host]:port" otherwise
+func (a hostAddr) normalized() string {
+	if a.port == "22" {
+		return a.host
+	}
+	return "[" + a.host + "]:" + a.port
+}
+
+// hostCandidates returns the addresses a known_hosts line may match: the
+// configured host and, when it differs, the remote IP actually dialled
+func hostCandidates(host string, port int, remote net.Addr) []hostAddr {
+	candidates := []hostAddr{newHostAddr(host, port)}
+	if tcp, ok := remote.(*net.TCPAddr); ok && tcp.IP != nil {
+		ip := newHostAddr(tcp.IP.String(), port)
+		if ip.host != candidates[0].host {
+			candidates = append(candidates, ip)
+		}
+	}
+	return candidates
+}
+
+// hostPatternsMatch applies OpenSSH host pattern rules to a comma-separated
+// host field already split into patterns: the line matches if some
+// positive pattern matches a candidate and no negated pattern does
+func hostPatternsMatch(patterns []string, candidates []hostAddr) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		for _, candidate := range candidates {
+			if !hostPatternMatch(pattern, candidate) {
+				continue
+			}
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+func hostPatternMatch(pattern string, candidate hostAddr) bool {
+	if strings.HasPrefix(pattern, "|") {
+		return hashedHostMatch(pattern, candidate)
+	}
+
+	host, port := pattern, "22"
+	if strings.HasPrefix(pattern, "[") {
+		if h, p, err := net.SplitHostPort(pattern); err == nil {
+			host, port = h, p
+		} else {
+			host = strings.Trim(pattern, "[]")
+		}
+	}
+
+	return port == candidate.port && wildcardMatch(strings.ToLower(host), strings.ToLower(candidate.host))
+}
+
+// hashedHostMatch checks a "|1|salt|hash" entry (HMAC-SHA1 of the
+// normalized host)
+func hashedHostMatch(pattern string, candidate hostAddr) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 || parts[1] != "1" {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(candidate.normalized()))
+	return bytes.Equal(mac.Sum(nil), hash)
+}
+
+// wildcardMatch implements OpenSSH's * and ? matching, which has no regard
+// for separators
+func wildcardMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(s); i++ {
+				if wildcardMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// isPlainHostPattern reports whether a pattern names exactly one host,
+// i.e. it is not hashed, negated or wildcarded
+func isPlainHostPattern(pattern string) bool {
+	return !strings.ContainsAny(pattern, "|!*?")
+}
+
+// parsePlainHostPattern splits a plain "host" or "[host]:port" pattern
+func parsePlainHostPattern(pattern string) (string, int) {
+	if strings.HasPrefix(pattern, "[") {
+		if h, p, err := net.SplitHostPort(pattern); err == nil {
+			if port, err := strconv.Atoi(p); err == nil {
+				return h, port
+			}
+		}
+		return strings.Trim(pattern, "[]"), 22
+	}
+	return pattern, 22
+}
+
+// parseKnownHostsData parses OpenSSH known_hosts content, skipping lines
+// it can't understand instead of giving up on the whole file
+func parseKnownHostsData(data []byte, source string) []*KnownHostEntry {
+	var entries []*KnownHostEntry
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		marker, hosts, key, comment, _, err := ssh.ParseKnownHosts(line)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, newKnownHostEntry(marker, hosts, key, comment, source))
+	}
+	return entries
+}
+
+// parseLegacyKnownHosts reads the pre-OpenSSH "host:port type key" layout
+func parseLegacyKnownHosts(data []byte, source string) []*KnownHostEntry {
+	var entries []*KnownHostEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 3 || strings.HasPrefix(parts[0], "#") {
+			continue
+		}
+
+		// Split at the last colon so IPv6 addresses survive
+		host, port := parts[0], 22
+		if i := strings.LastIndex(host, ":"); i > 0 {
+			if p, err := strconv.Atoi(host[i+1:]); err == nil {
+				host, port = host[:i], p
+			}
+		}
+
+		keyBytes, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			continue
+		}
+		key, err := ssh.ParsePublicKey(keyBytes)
+		if err != nil {
+			continue
+		}
+
+		pattern := newHostAddr(host, port).normalized()
+		entries = append(entries, newKnownHostEntry("", []string{pattern}, key, "", source))
+	}
+	return entries
+}
+
+// formatKnownHostsLine renders an entry as an OpenSSH known_hosts line
+func formatKnownHostsLine(entry *KnownHostEntry) string {
+	line := strings.Join(entry.Patterns, ",") + " " + entry.PublicKey.Type() + " " +
+		base64.StdEncoding.EncodeToString(entry.PublicKey.Marshal())
+	if entry.Marker != "" {
+		line = "@" + entry.Marker + " " + line
+	}
+	if entry.Comment != "" {
+		line += " " + entry.Comment
+	}
+	return line
+}
@@ -0,0 +1,260 @@
+package terminal
+
+import (
+	"encoding/binary"
+	"errors"
+	"host-vault/internal/audit"
+	"log"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// hostKeysRequest is sent by OpenSSH servers after authentication to
+	// announce every host key they hold
+	hostKeysRequest = "hostkeys-00@openssh.com"
+	// hostKeysProveRequest asks the server to sign announced keys so the
+	// client can confirm it really holds them
+	hostKeysProveRequest = "hostkeys-prove-00@openssh.com"
+)
+
+// defaultHostKeyAlgorithms mirrors x/crypto's built-in preference order,
+// used for whatever isn't preferred because of known keys
+var defaultHostKeyAlgorithms = []string{
+	ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSASHA512v01,
+	ssh.CertAlgoRSAv01, ssh.CertAlgoDSAv01, ssh.CertAlgoECDSA256v01,
+	ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01, ssh.CertAlgoED25519v01,
+
+	ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512,
+	ssh.KeyAlgoRSA, ssh.KeyAlgoDSA,
+
+	ssh.KeyAlgoED25519,
+}
+
+// hostKeyCertAlgorithms are the certificate variants of the host key algorithms
+var hostKeyCertAlgorithms = []string{
+	ssh.CertAlgoED25519v01,
+	ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+	ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01,
+}
+
+// algorithmsForKeyType expands a public key type into the signature
+// algorithms that may be negotiated for it
+func algorithmsForKeyType(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// HostKeyAlgorithms returns the host key algorithms to offer when
+// connecting, known key types first so the server presents a key we can
+// verify. Returns nil (the library default) for hosts we know nothing about.
+func (khm *KnownHostsManager) HostKeyAlgorithms(host string, port int) []string {
+	khm.refresh()
+
+	khm.mu.RLock()
+	defer khm.mu.RUnlock()
+
+	var preferred []string
+	seen := make(map[string]bool)
+	add := func(algos ...string) {
+		for _, algo := range algos {
+			if !seen[algo] {
+				seen[algo] = true
+				preferred = append(preferred, algo)
+			}
+		}
+	}
+
+	candidates := hostCandidates(host, port, nil)
+	for _, entries := range [][]*KnownHostEntry{khm.entries, khm.systemEntries} {
+		for _, entry := range entries {
+			if entry.Marker == "cert-authority" && hostPatternsMatch(entry.Patterns, candidates) {
+				add(hostKeyCertAlgorithms...)
+			}
+		}
+	}
+	for _, entry := range khm.matchingEntries(host, port, nil) {
+		add(algorithmsForKeyType(entry.KeyType)...)
+	}
+
+	if len(preferred) == 0 {
+		return nil
+	}
+	add(defaultHostKeyAlgorithms...)
+	return preferred
+}
+
+// handleGlobalRequests serves the server's global requests for the lifetime
+// of conn. Host key announcements are proved and their new keys added;
+// everything else is refused. trusted tells whether the key presented
+// during the handshake matched a known_hosts entry, which is the only case
+// in which announced keys are accepted.
+func (khm *KnownHostsManager) handleGlobalRequests(conn ssh.Conn, reqs <-chan *ssh.Request, host string, port int, trusted bool) {
+	for req := range reqs {
+		if req.Type == hostKeysRequest && trusted {
+			// Answer first; proving the keys needs another round-trip
+			// that must not block this loop
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+			go khm.updateHostKeys(conn, host, port, req.Payload)
+			continue
+		}
+		if req.WantReply {
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// updateHostKeys proves the announced keys not yet known for the host and
+// stores the ones the server could sign for
+func (khm *KnownHostsManager) updateHostKeys(conn ssh.Conn, host string, port int, payload []byte) {
+	blobs, err := parseStringList(payload)
+	if err != nil {
+		log.Printf("[SSH] Ignoring malformed host key announcement from %s:%d: %v", host, port, err)
+		return
+	}
+
+	var unknown []ssh.PublicKey
+	khm.mu.RLock()
+	for _, blob := range blobs {
+		key, err := ssh.ParsePublicKey(blob)
+		if err != nil {
+			// Servers may announce key types we can't parse
+			continue
+		}
+		if _, ok := key.(*ssh.Certificate); ok {
+			continue
+		}
+		if status, _, _ := khm.checkHostKey(host, port, conn.RemoteAddr(), key); status == hostKeyKnown || status == hostKeyRevoked {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+	khm.mu.RUnlock()
+
+	if len(unknown) == 0 {
+		return
+	}
+
+	proved, err := proveHostKeys(conn, unknown)
+	if err != nil {
+		log.Printf("[SSH] Host key proof from %s:%d failed: %v", host, port, err)
+		return
+	}
+
+	if err := khm.addProvedHostKeys(host, port, conn.RemoteAddr(), proved); err != nil {
+		log.Printf("[SSH] Failed to store rotated host keys for %s:%d: %v", host, port, err)
+		return
+	}
+	log.Printf("[SSH] Learned %d new host key(s) for %s:%d", len(proved), host, port)
+}
+
+// proveHostKeys sends hostkeys-prove-00 for keys and returns the ones whose
+// signature over the session identifier verifies
+func proveHostKeys(conn ssh.Conn, keys []ssh.PublicKey) ([]ssh.PublicKey, error) {
+	var request []byte
+	for _, key := range keys {
+		request = appendString(request, key.Marshal())
+	}
+
+	ok, response, err := conn.SendRequest(hostKeysProveRequest, true, request)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("server refused to prove host keys")
+	}
+
+	signatures, err := parseStringList(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(signatures) != len(keys) {
+		return nil, errors.New("server returned the wrong number of signatures")
+	}
+
+	var proved []ssh.PublicKey
+	for i, key := range keys {
+		sig := new(ssh.Signature)
+		if err := ssh.Unmarshal(signatures[i], sig); err != nil {
+			return nil, err
+		}
+		signed := ssh.Marshal(struct {
+			Request   string
+			SessionID []byte
+			Key       []byte
+		}{hostKeysProveRequest, conn.SessionID(), key.Marshal()})
+		if err := key.Verify(signed, sig); err != nil {
+			return nil, errors.New("invalid signature for " + key.Type() + " host key")
+		}
+		proved = append(proved, key)
+	}
+	return proved, nil
+}
+
+// addProvedHostKeys stores keys next to the ones already known for the
+// host. Unlike AddHostKey nothing is replaced: the server proved it holds
+// every one of them. Keys for hosts only trusted in guest mode stay in memory.
+func (khm *KnownHostsManager) addProvedHostKeys(host string, port int, remote net.Addr, keys []ssh.PublicKey) error {
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
+
+	guest := true
+	for _, entry := range khm.matchingEntries(host, port, remote) {
+		if !entry.Guest {
+			guest = false
+		}
+	}
+
+	pattern := newHostAddr(host, port).normalized()
+	source := khm.knownHostsPath
+	if guest {
+		source = ""
+	}
+	var added []*KnownHostEntry
+	for _, key := range keys {
+		entry := newKnownHostEntry("", []string{pattern}, key, "", source)
+		entry.Guest = guest
+		if khm.hasLine(entry) {
+			continue
+		}
+		khm.entries = append(khm.entries, entry)
+		added = append(added, entry)
+		auditHostKey(khm.auditLog, audit.EventHostKeyAccepted, host, port, key, "announced by the server")
+	}
+	khm.markAdded(true, added...)
+
+	if guest {
+		return nil
+	}
+	return khm.saveKnownHosts()
+}
+
+// parseStringList splits a payload made of consecutive SSH strings
+func parseStringList(data []byte) ([][]byte, error) {
+	var list [][]byte
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errors.New("truncated string length")
+		}
+		n := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(n) > uint64(len(data)) {
+			return nil, errors.New("truncated string")
+		}
+		list = append(list, data[:n])
+		data = data[n:]
+	}
+	return list, nil
+}
+
+func appendString(buf, s []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
@@ -0,0 +1,259 @@
+package terminal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// hostKeyScanConcurrency is how many hosts are scanned at once
+	hostKeyScanConcurrency = 16
+	// hostKeyScanTimeout bounds each handshake of a scan
+	hostKeyScanTimeout = 5 * time.Second
+)
+
+// hostKeyScanAlgorithms are offered one at a time so each handshake yields
+// the server's key of that type, like ssh-keyscan -t
+var hostKeyScanAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512,
+	// Older servers only sign with SHA-1; tried when rsa-sha2 found no RSA key
+	ssh.KeyAlgoRSA,
+	ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256,
+}
+
+// Host key scan statuses, per key and per host
+const (
+	HostKeyScanNew         = "new"
+	HostKeyScanMatch       = "match"
+	HostKeyScanMismatch    = "mismatch"
+	HostKeyScanRevoked     = "revoked"
+	HostKeyScanUnreachable = "unreachable"
+	// HostKeyScanError marks targets that couldn't be scanned at all, e.g.
+	// a saved connection that doesn't exist
+	HostKeyScanError = "error"
+)
+
+// HostKeyScanTarget is a host to scan, given by address or as a saved
+// connection. A ConnectionID is resolved by TerminalManager.ScanHostKeys
+// to the connection's host and port and passed through to the result.
+type HostKeyScanTarget struct {
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	ConnectionID string `json:"connectionId,omitempty"`
+
+	// err is why the target can't be scanned, e.g. its connection is gone
+	err error
+}
+
+// ScannedHostKey is one key collected by a scan
+type ScannedHostKey struct {
+	KeyType             string `json:"keyType"`
+	FingerprintSHA256   string `json:"fingerprintSHA256"`
+	KeyBase64           string `json:"keyBase64"`
+	Status              string `json:"status"`
+	ExpectedFingerprint string `json:"expectedFingerprint,omitempty"`
+}
+
+// HostKeyScanResult is the outcome for one target. Status is the worst of
+// its keys: mismatch (revoked included), then new, then match.
+type HostKeyScanResult struct {
+	Host         string           `json:"host"`
+	Port         int              `json:"port"`
+	ConnectionID string           `json:"connectionId,omitempty"`
+	Status       string           `json:"status"`
+	Keys         []ScannedHostKey `json:"keys"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// HostKeyScanReport collects the results of ScanHostKeys, in target order
+type HostKeyScanReport struct {
+	Results     []HostKeyScanResult `json:"results"`
+	New         int                 `json:"new"`
+	Matches     int                 `json:"matches"`
+	Mismatches  int                 `json:"mismatches"`
+	Unreachable int                 `json:"unreachable"`
+	Errors      int                 `json:"errors"`
+}
+
+// HostKeyAcceptance names a scanned key to trust
+type HostKeyAcceptance struct {
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	KeyBase64 string `json:"keyBase64"`
+}
+
+// ScanHostKeys collects every supported host key of the targets
+// concurrently and compares them with known_hosts. Nothing is stored;
+// pass the keys to trust to AddHostKeys.
+func (khm *KnownHostsManager) ScanHostKeys(targets []HostKeyScanTarget) *HostKeyScanReport {
+	khm.refresh()
+	report := &HostKeyScanReport{Results: make([]HostKeyScanResult, len(targets))}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, hostKeyScanConcurrency)
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target HostKeyScanTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Results[i] = khm.scanHost(target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case HostKeyScanNew:
+			report.New++
+		case HostKeyScanMatch:
+			report.Matches++
+		case HostKeyScanMismatch:
+			report.Mismatches++
+		case HostKeyScanUnreachable:
+			report.Unreachable++
+		case HostKeyScanError:
+			report.Errors++
+		}
+	}
+	return report
+}
+
+// scanHost runs one handshake per algorithm against a target
+func (khm *KnownHostsManager) scanHost(target HostKeyScanTarget) HostKeyScanResult {
+	if target.Port == 0 {
+		target.Port = 22
+	}
+	result := HostKeyScanResult{
+		Host:         target.Host,
+		Port:         target.Port,
+		ConnectionID: target.ConnectionID,
+		Keys:         []ScannedHostKey{},
+	}
+	// An empty host would dial localhost
+	if target.err == nil && strings.TrimSpace(target.Host) == "" {
+		target.err = errors.New("no host to scan")
+	}
+	if target.err != nil {
+		result.Status = HostKeyScanError
+		result.Error = target.err.Error()
+		return result
+	}
+
+	seen := make(map[string]bool)
+	keyTypes := make(map[string]bool)
+	var lastErr error
+	for _, algorithm := range hostKeyScanAlgorithms {
+		if algorithm == ssh.KeyAlgoRSA && keyTypes[ssh.KeyAlgoRSA] {
+			continue
+		}
+		key, remote, err := captureHostKey(target.Host, target.Port, []string{algorithm}, hostKeyScanTimeout)
+		if err != nil {
+			var unreachable *hostUnreachableError
+			if errors.As(err, &unreachable) {
+				// No point trying the other algorithms
+				result.Status = HostKeyScanUnreachable
+				result.Error = err.Error()
+				return result
+			}
+			// Most likely the server has no key of this type
+			lastErr = err
+			continue
+		}
+
+		info, status, _ := khm.describeHostKey(target.Host, target.Port, remote, key)
+		if seen[info.FingerprintSHA256] {
+			continue
+		}
+		seen[info.FingerprintSHA256] = true
+		keyTypes[plainHostKey(key).Type()] = true
+
+		scanned := ScannedHostKey{
+			KeyType:           plainHostKey(key).Type(),
+			FingerprintSHA256: info.FingerprintSHA256,
+			KeyBase64:         info.KeyBase64,
+		}
+		switch status {
+		case hostKeyKnown, hostKeyCertified:
+			scanned.Status = HostKeyScanMatch
+		case hostKeyMismatch:
+			scanned.Status = HostKeyScanMismatch
+			scanned.ExpectedFingerprint = info.ExpectedFingerprint
+		case hostKeyRevoked:
+			scanned.Status = HostKeyScanRevoked
+		default:
+			scanned.Status = HostKeyScanNew
+		}
+		result.Keys = append(result.Keys, scanned)
+	}
+
+	if len(result.Keys) == 0 {
+		result.Status = HostKeyScanUnreachable
+		if lastErr != nil {
+			result.Error = lastErr.Error()
+		}
+		return result
+	}
+
+	result.Status = HostKeyScanMatch
+	for _, key := range result.Keys {
+		switch key.Status {
+		case HostKeyScanMismatch, HostKeyScanRevoked:
+			result.Status = HostKeyScanMismatch
+		case HostKeyScanNew:
+			if result.Status == HostKeyScanMatch {
+				result.Status = HostKeyScanNew
+			}
+		}
+	}
+	return result
+}
+
+// AddHostKeys stores several accepted keys with a single write, e.g. the
+// new keys of a scan. Like AddHostKey, a key replaces one of the same type
+// stored for exactly that host and port.
+func (khm *KnownHostsManager) AddHostKeys(keys []HostKeyAcceptance, isGuest bool) (int, error) {
+	parsed := make([]ssh.PublicKey, len(keys))
+	for i, accepted := range keys {
+		// A blank host field would make the line match nothing, or break it
+		if strings.TrimSpace(accepted.Host) == "" {
+			return 0, errors.New("host key without a host")
+		}
+		keyBytes, err := base64.StdEncoding.DecodeString(accepted.KeyBase64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode key for %s: %w", accepted.Host, err)
+		}
+		key, err := ssh.ParsePublicKey(keyBytes)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse public key for %s: %w", accepted.Host, err)
+		}
+		parsed[i] = key
+	}
+
+	khm.mu.Lock()
+	defer khm.mu.Unlock()
+	khm.reloadIfChanged()
+
+	var added []*KnownHostEntry
+	for i, accepted := range keys {
+		port := accepted.Port
+		if port == 0 {
+			port = 22
+		}
+		added = append(added, khm.addHostKeyLocked(accepted.Host, port, parsed[i], isGuest))
+	}
+	khm.markAdded(true, added...)
+
+	if isGuest || len(added) == 0 {
+		return len(added), nil
+	}
+	return len(added), khm.saveKnownHosts()
+}
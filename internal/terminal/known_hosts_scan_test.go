@@ -0,0 +1,182 @@
+package terminal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// skEd25519Signer signs like a security key holding an sk-ssh-ed25519 key
+type skEd25519Signer struct {
+	private ed25519.PrivateKey
+	public  ssh.PublicKey
+}
+
+func newSKEd25519Signer(t *testing.T) *skEd25519Signer {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	blob := ssh.Marshal(struct {
+		Name        string
+		KeyBytes    []byte
+		Application string
+	}{ssh.KeyAlgoSKED25519, public, "ssh:"})
+	key, err := ssh.ParsePublicKey(blob)
+	if err != nil {
+		t.Fatalf("parse sk key: %v", err)
+	}
+	return &skEd25519Signer{private: private, public: key}
+}
+
+func (s *skEd25519Signer) PublicKey() ssh.PublicKey {
+	return s.public
+}
+
+func (s *skEd25519Signer) Sign(_ io.Reader, data []byte) (*ssh.Signature, error) {
+	appDigest := sha256.Sum256([]byte("ssh:"))
+	dataDigest := sha256.Sum256(data)
+	const flags, counter = 1, 1
+	signed := ssh.Marshal(struct {
+		ApplicationDigest []byte `ssh:"rest"`
+		Flags             byte
+		Counter           uint32
+		MessageDigest     []byte `ssh:"rest"`
+	}{appDigest[:], flags, counter, dataDigest[:]})
+	return &ssh.Signature{
+		Format: ssh.KeyAlgoSKED25519,
+		Blob:   ed25519.Sign(s.private, signed),
+		Rest: ssh.Marshal(struct {
+			Flags   byte
+			Counter uint32
+		}{flags, counter}),
+	}, nil
+}
+
+// startSSHServer accepts connections with the given host keys until the
+// test ends. Clients get as far as authentication, which always fails.
+func startSSHServer(t *testing.T, hostKeys ...ssh.Signer) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+	}
+	for _, key := range hostKeys {
+		config.AddHostKey(key)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func TestScanHostKeysAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	rsaSigner, err := ssh.NewSignerFromKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A server too old for rsa-sha2
+	sha1Only, err := ssh.NewSignerWithAlgorithms(rsaSigner.(ssh.AlgorithmSigner), []string{ssh.KeyAlgoRSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSigner, err := ssh.NewSignerFromKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		hostKeys  []ssh.Signer
+		wantTypes []string
+	}{
+		{name: "ed25519 and rsa-sha2", hostKeys: []ssh.Signer{edSigner, rsaSigner}, wantTypes: []string{ssh.KeyAlgoED25519, ssh.KeyAlgoRSA}},
+		{name: "ssh-rsa only", hostKeys: []ssh.Signer{sha1Only}, wantTypes: []string{ssh.KeyAlgoRSA}},
+		{name: "security key only", hostKeys: []ssh.Signer{newSKEd25519Signer(t)}, wantTypes: []string{ssh.KeyAlgoSKED25519}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := startSSHServer(t, tt.hostKeys...)
+			manager, err := NewKnownHostsManager(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewKnownHostsManager: %v", err)
+			}
+
+			result := manager.ScanHostKeys([]HostKeyScanTarget{{Host: host, Port: port}}).Results[0]
+			if result.Status != HostKeyScanNew {
+				t.Fatalf("status = %s (%s), want %s", result.Status, result.Error, HostKeyScanNew)
+			}
+			var types []string
+			for _, key := range result.Keys {
+				types = append(types, key.KeyType)
+			}
+			if strings.Join(types, ",") != strings.Join(tt.wantTypes, ",") {
+				t.Errorf("scanned key types = %v, want %v", types, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestScanHostKeysWithoutHost(t *testing.T) {
+	manager, err := NewKnownHostsManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKnownHostsManager: %v", err)
+	}
+	report := manager.ScanHostKeys([]HostKeyScanTarget{{Host: " ", Port: 22}})
+	if report.Errors != 1 || report.Results[0].Status != HostKeyScanError {
+		t.Errorf("report = %+v, want the target reported as an error", report)
+	}
+}
+
+func TestAddHostKeysRejectsEmptyHost(t *testing.T) {
+	manager, err := NewKnownHostsManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKnownHostsManager: %v", err)
+	}
+	key := base64.StdEncoding.EncodeToString(newTestHostKey(t).Marshal())
+
+	for _, host := range []string{"", "  "} {
+		if _, err := manager.AddHostKeys([]HostKeyAcceptance{{Host: host, Port: 22, KeyBase64: key}}, false); err == nil {
+			t.Errorf("AddHostKeys accepted host %q", host)
+		}
+	}
+	if entries := manager.ListKnownHosts(); len(entries) != 0 {
+		t.Errorf("known hosts = %v, want none stored", entries)
+	}
+}
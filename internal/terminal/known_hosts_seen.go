@@ -0,0 +1,142 @@
+package terminal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"host-vault/internal/storage"
+	"os"
+	"strings"
+	"time"
+)
+
+// hostKeySeen records when a known_hosts line was added and last matched.
+// OpenSSH's format has no room for this, so it lives in a sidecar file.
+type hostKeySeen struct {
+	FirstSeen *time.Time `json:"firstSeen,omitempty"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+	guest     bool
+}
+
+// KnownHostRecord is a known_hosts line as listed to the UI
+type KnownHostRecord struct {
+	ID          string     `json:"id"`
+	Host        string     `json:"host"`
+	Port        int        `json:"port"`
+	Patterns    []string   `json:"patterns"`
+	Marker      string     `json:"marker,omitempty"`
+	KeyType     string     `json:"keyType"`
+	Fingerprint string     `json:"fingerprint"`
+	FirstSeen   *time.Time `json:"firstSeen,omitempty"`
+	LastSeen    *time.Time `json:"lastSeen,omitempty"`
+	Source      string     `json:"source"`
+	Guest       bool       `json:"guest"`
+	ReadOnly    bool       `json:"readOnly"` // From ~/.ssh/known_hosts, can't be changed here
+}
+
+// seenKey identifies a line independently of its position in the file
+func seenKey(entry *KnownHostEntry) string {
+	return entry.Marker + " " + strings.Join(entry.Patterns, ",") + " " + entry.Fingerprint
+}
+
+// knownHostID derives a stable record ID for an entry
+func knownHostID(entry *KnownHostEntry) string {
+	sum := sha256.Sum256([]byte(entry.Source + "\n" + seenKey(entry)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadSeen reads the sidecar file; a missing or corrupt file just means
+// no history
+func (khm *KnownHostsManager) loadSeen() {
+	khm.seen = make(map[string]*hostKeySeen)
+
+	data, err := os.ReadFile(khm.seenPath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &khm.seen); err != nil {
+		khm.seen = make(map[string]*hostKeySeen)
+	}
+}
+
+// markAdded stamps newly stored entries. seen is set when the key was just
+// presented by the server, as opposed to imported from a file.
+func (khm *KnownHostsManager) markAdded(seen bool, entries ...*KnownHostEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	khm.seenMu.Lock()
+	defer khm.seenMu.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		record := &hostKeySeen{FirstSeen: &now, guest: entry.Guest}
+		if seen {
+			record.LastSeen = &now
+		}
+		khm.seen[seenKey(entry)] = record
+	}
+	khm.saveSeen()
+}
+
+// markSeen updates the last-seen time of an entry that matched a
+// connection. Entries recorded before seen times existed get their first
+// sighting too.
+func (khm *KnownHostsManager) markSeen(entry *KnownHostEntry) {
+	khm.seenMu.Lock()
+	defer khm.seenMu.Unlock()
+
+	now := time.Now()
+	record, ok := khm.seen[seenKey(entry)]
+	if !ok {
+		record = &hostKeySeen{FirstSeen: &now, guest: entry.Guest}
+		khm.seen[seenKey(entry)] = record
+	}
+	record.LastSeen = &now
+	khm.saveSeen()
+}
+
+// forgetSeen drops the history of removed entries
+func (khm *KnownHostsManager) forgetSeen(entries []*KnownHostEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	khm.seenMu.Lock()
+	defer khm.seenMu.Unlock()
+
+	for _, entry := range entries {
+		delete(khm.seen, seenKey(entry))
+	}
+	khm.saveSeen()
+}
+
+// seenTimes returns an entry's first and last seen times
+func (khm *KnownHostsManager) seenTimes(entry *KnownHostEntry) (*time.Time, *time.Time) {
+	khm.seenMu.Lock()
+	defer khm.seenMu.Unlock()
+
+	record, ok := khm.seen[seenKey(entry)]
+	if !ok {
+		return nil, nil
+	}
+	return record.FirstSeen, record.LastSeen
+}
+
+// saveSeen writes the sidecar file, leaving guest entries out (must be
+// called with seenMu held)
+func (khm *KnownHostsManager) saveSeen() {
+	persisted := make(map[string]*hostKeySeen, len(khm.seen))
+	for key, record := range khm.seen {
+		if !record.guest {
+			persisted[key] = record
+		}
+	}
+
+	data, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return
+	}
+	storage.WriteFile(khm.seenPath, data)
+}
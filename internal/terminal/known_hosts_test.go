@@ -0,0 +1,213 @@
+package terminal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsLine formats key as a known_hosts line for patterns
+func knownHostsLine(patterns string, key ssh.PublicKey) string {
+	return patterns + " " + string(ssh.MarshalAuthorizedKey(key))
+}
+
+// newTestKnownHosts returns a manager whose own known_hosts holds the
+// OpenSSH-format lines in content
+func newTestKnownHosts(t *testing.T, content string) *KnownHostsManager {
+	t.Helper()
+	dir := t.TempDir()
+	if content != "" {
+		data := knownHostsHeader + "\n" + content
+		if err := os.WriteFile(filepath.Join(dir, "known_hosts"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manager, err := NewKnownHostsManager(dir)
+	if err != nil {
+		t.Fatalf("NewKnownHostsManager: %v", err)
+	}
+	return manager
+}
+
+func TestSystemKnownHostsOptIn(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	key := newTestHostKey(t)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "known_hosts"), []byte(knownHostsLine("system.test", key)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := newTestKnownHosts(t, "")
+	if _, err := manager.VerifyHostKey("system.test", 22, nil, key); err == nil {
+		t.Fatal("~/.ssh/known_hosts trusted without being enabled")
+	}
+
+	manager.SetUseSystemKnownHosts(true)
+	if status, err := manager.VerifyHostKey("system.test", 22, nil, key); err != nil || status != hostKeyKnown {
+		t.Fatalf("VerifyHostKey with system known_hosts = %v, %v; want known", status, err)
+	}
+
+	manager.SetUseSystemKnownHosts(false)
+	if _, err := manager.VerifyHostKey("system.test", 22, nil, key); err == nil {
+		t.Error("~/.ssh/known_hosts still trusted after disabling it")
+	}
+}
+
+func TestVerifyHostKeyPatterns(t *testing.T) {
+	key := newTestHostKey(t)
+	other := newTestHostKey(t)
+
+	content := knownHostsLine("plain.test,[ported.test]:2222", key) +
+		knownHostsLine(knownhosts.HashHostname("hashed.test"), key) +
+		knownHostsLine("*.example.test,!bad.example.test", key) +
+		"not a known_hosts line\n"
+	manager := newTestKnownHosts(t, content)
+
+	tests := []struct {
+		name    string
+		host    string
+		port    int
+		key     ssh.PublicKey
+		want    hostKeyStatus
+		wantErr bool
+	}{
+		{name: "plain", host: "plain.test", port: 22, key: key, want: hostKeyKnown},
+		{name: "plain case-insensitive", host: "PLAIN.test", port: 22, key: key, want: hostKeyKnown},
+		{name: "plain other key", host: "plain.test", port: 22, key: other, want: hostKeyMismatch, wantErr: true},
+		{name: "non-default port", host: "ported.test", port: 2222, key: key, want: hostKeyKnown},
+		{name: "port not listed", host: "ported.test", port: 22, key: key, want: hostKeyUnknown, wantErr: true},
+		{name: "hashed", host: "hashed.test", port: 22, key: key, want: hostKeyKnown},
+		{name: "hashed other host", host: "unhashed.test", port: 22, key: key, want: hostKeyUnknown, wantErr: true},
+		{name: "wildcard", host: "web.example.test", port: 22, key: key, want: hostKeyKnown},
+		{name: "wildcard needs a label", host: "example.test", port: 22, key: key, want: hostKeyUnknown, wantErr: true},
+		{name: "negated", host: "bad.example.test", port: 22, key: key, want: hostKeyUnknown, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := manager.VerifyHostKey(tt.host, tt.port, nil, tt.key)
+			if status != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("VerifyHostKey = %v, %v; want %v, error %v", status, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyHostKeyRemoteAddress(t *testing.T) {
+	key := newTestHostKey(t)
+	manager := newTestKnownHosts(t, knownHostsLine("192.0.2.10", key))
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+	if status, err := manager.VerifyHostKey("alias.test", 22, remote, key); err != nil || status != hostKeyKnown {
+		t.Errorf("VerifyHostKey by address = %v, %v; want known", status, err)
+	}
+	if _, err := manager.VerifyHostKey("alias.test", 22, nil, key); err == nil {
+		t.Error("VerifyHostKey without the address trusted the key")
+	}
+}
+
+func TestLegacyKnownHostsConverted(t *testing.T) {
+	key := newTestHostKey(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "known_hosts")
+	legacy := "old.test:2222 " + key.Type() + " " + base64.StdEncoding.EncodeToString(key.Marshal()) + "\n"
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewKnownHostsManager(dir)
+	if err != nil {
+		t.Fatalf("NewKnownHostsManager: %v", err)
+	}
+	if status, err := manager.VerifyHostKey("old.test", 2222, nil, key); err != nil || status != hostKeyKnown {
+		t.Errorf("VerifyHostKey = %v, %v; want the legacy entry known", status, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), knownHostsHeader) || !strings.Contains(string(data), knownHostsLine("[old.test]:2222", key)) {
+		t.Errorf("known_hosts after loading = %q, want it rewritten in OpenSSH format", data)
+	}
+}
+
+func newTestSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func newTestHostCert(t *testing.T, key ssh.PublicKey, ca ssh.Signer, principals ...string) *ssh.Certificate {
+	t.Helper()
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.HostCert,
+		KeyId:           "test",
+		ValidPrincipals: principals,
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatalf("SignCert: %v", err)
+	}
+	return cert
+}
+
+func TestVerifyHostKeyMarkers(t *testing.T) {
+	key := newTestHostKey(t)
+	other := newTestHostKey(t)
+	revoked := newTestHostKey(t)
+	ca := newTestSigner(t)
+	revokedCA := newTestSigner(t)
+
+	content := knownHostsLine("plain.test", key) +
+		knownHostsLine("revoked.test", revoked) +
+		"@cert-authority " + knownHostsLine("*.ca.test", ca.PublicKey()) +
+		"@cert-authority " + knownHostsLine("*.ca.test", revokedCA.PublicKey()) +
+		"@revoked " + knownHostsLine("*", revoked) +
+		"@revoked " + knownHostsLine("unrelated.test", revokedCA.PublicKey())
+	manager := newTestKnownHosts(t, content)
+
+	tests := []struct {
+		name    string
+		host    string
+		key     ssh.PublicKey
+		want    hostKeyStatus
+		wantErr bool
+	}{
+		{name: "revoked despite a plain line", host: "revoked.test", key: revoked, want: hostKeyRevoked, wantErr: true},
+		{name: "certificate", host: "web.ca.test", key: newTestHostCert(t, other, ca, "web.ca.test"), want: hostKeyCertified},
+		{name: "certificate for another principal", host: "web.ca.test", key: newTestHostCert(t, other, ca, "db.ca.test"), want: hostKeyMismatch, wantErr: true},
+		{name: "CA key presented as a host key", host: "web.ca.test", key: ca.PublicKey(), want: hostKeyUnknown, wantErr: true},
+		{name: "certificate outside the CA's hosts", host: "plain.test", key: newTestHostCert(t, key, ca, "plain.test"), want: hostKeyKnown},
+		{name: "certificate from a revoked CA", host: "web.ca.test", key: newTestHostCert(t, other, revokedCA, "web.ca.test"), want: hostKeyRevoked, wantErr: true},
+		{name: "certificate of a revoked key", host: "web.ca.test", key: newTestHostCert(t, revoked, ca, "web.ca.test"), want: hostKeyRevoked, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := manager.VerifyHostKey(tt.host, 22, nil, tt.key)
+			if status != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("VerifyHostKey = %v, %v; want %v, error %v", status, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -22,7 +22,7 @@ type LocalPTYSession struct {
 	metadata SessionMetadata
 	mu       sync.RWMutex
 	closed   bool
-	buffer   chan []byte
+	output   *outputPipe
 }
 
 func NewLocalPTYSession(shell, cwd string, env map[string]string) (*LocalPTYSession, error) {
@@ -68,7 +68,7 @@ func NewLocalPTYSession(shell, cwd string, env map[string]string) (*LocalPTYSess
 			Environment:      env,
 			CreatedAt:        time.Now(),
 		},
-		buffer: make(chan []byte, 100),
+		output: newOutputPipe(outputPipeLimit),
 		closed: false,
 	}
 
@@ -85,17 +85,17 @@ func (s *LocalPTYSession) readOutput() {
 			if err != io.EOF {
 				// Log non-EOF errors for debugging
 			}
-			// EOF detected - close buffer channel to signal streamOutput
-			log.Printf("[LOCAL] Session %s readOutput received EOF, closing buffer", s.id)
-			close(s.buffer)
+			// EOF detected - close output pipe to signal streamOutput
+			log.Printf("[LOCAL] Session %s readOutput received EOF, closing output", s.id)
+			s.output.Close()
 			return
 		}
 		if n > 0 {
 			data := make([]byte, n)
 			copy(data, buf[:n])
-			select {
-			case s.buffer <- data:
-			default:
+			// Blocks while the consumer is behind, leaving unread output in the PTY
+			if !s.output.Write(data) {
+				return
 			}
 		}
 	}
@@ -145,6 +145,9 @@ func (s *LocalPTYSession) Close() error {
 
 	s.closed = true
 
+	// Release a reader blocked on a full pipe; queued output is still drained
+	s.output.Close()
+
 	if s.ptyFile != nil {
 		s.ptyFile.Close()
 	}
@@ -162,10 +165,17 @@ func (s *LocalPTYSession) GetMetadata() SessionMetadata {
 	return s.metadata
 }
 
-func (s *LocalPTYSession) ReadOutput() []byte {
-	data, ok := <-s.buffer
-	if !ok {
-		return nil
+func (s *LocalPTYSession) SetWorkingDirectory(dir string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir == "" || dir == s.metadata.WorkingDirectory {
+		return false
 	}
-	return data
+	s.metadata.WorkingDirectory = dir
+	return true
+}
+
+func (s *LocalPTYSession) ReadOutput() []byte {
+	return s.output.Read()
 }
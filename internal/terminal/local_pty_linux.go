@@ -0,0 +1,47 @@
+//go:build linux
+
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ProbeWorkingDirectory reads /proc/<pgid>/cwd for the PTY's foreground
+// process group, so the cwd stays accurate for shells that don't emit
+// OSC 7 and while a program started from the shell is running
+func (s *LocalPTYSession) ProbeWorkingDirectory() (string, error) {
+	s.mu.RLock()
+	ptyFile := s.ptyFile
+	closed := s.closed
+	s.mu.RUnlock()
+
+	if closed || ptyFile == nil {
+		return "", errors.New("session closed")
+	}
+
+	// Use the raw fd without Fd(), which would switch the PTY to blocking mode
+	conn, err := ptyFile.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+	var pgid int
+	var ioctlErr error
+	if err := conn.Control(func(fd uintptr) {
+		pgid, ioctlErr = unix.IoctlGetInt(int(fd), unix.TIOCGPGRP)
+	}); err != nil {
+		return "", err
+	}
+	if ioctlErr != nil || pgid <= 0 {
+		// Fall back to the shell itself
+		if s.cmd == nil || s.cmd.Process == nil {
+			return "", fmt.Errorf("failed to get foreground process group: %w", ioctlErr)
+		}
+		pgid = s.cmd.Process.Pid
+	}
+
+	return os.Readlink(fmt.Sprintf("/proc/%d/cwd", pgid))
+}
@@ -15,14 +15,13 @@ import (
 )
 
 type LocalPTYSession struct {
-	id              string
-	cpty            *conpty.ConPty
-	metadata        SessionMetadata
-	mu              sync.RWMutex
-	closed          bool
-	buffer          chan []byte
-	done            chan struct{}
-	bufferCloseOnce sync.Once
+	id       string
+	cpty     *conpty.ConPty
+	metadata SessionMetadata
+	mu       sync.RWMutex
+	closed   bool
+	output   *outputPipe
+	done     chan struct{}
 }
 
 func NewLocalPTYSession(shell, cwd string, env map[string]string) (*LocalPTYSession, error) {
@@ -58,7 +57,7 @@ func NewLocalPTYSession(shell, cwd string, env map[string]string) (*LocalPTYSess
 			CreatedAt:        time.Now(),
 			State:            SessionStateActive,
 		},
-		buffer: make(chan []byte, 1000), // Increased buffer size
+		output: newOutputPipe(outputPipeLimit),
 		closed: false,
 		done:   make(chan struct{}),
 	}
@@ -69,7 +68,7 @@ func NewLocalPTYSession(shell, cwd string, env map[string]string) (*LocalPTYSess
 	return session, nil
 }
 
-// waitForExit waits for the process to exit and closes the buffer
+// waitForExit waits for the process to exit and closes the output pipe
 func (s *LocalPTYSession) waitForExit() {
 	log.Printf("[LOCAL] Session %s starting waitForExit goroutine", s.id)
 
@@ -82,12 +81,10 @@ func (s *LocalPTYSession) waitForExit() {
 		log.Printf("[LOCAL] Session %s process exited with code: %d", s.id, exitCode)
 	}
 
-	// Close the buffer to signal that output has ended
+	// Close the output pipe to signal that output has ended
 	// This will cause ReadOutput to return nil, triggering terminal:closed event
-	s.bufferCloseOnce.Do(func() {
-		log.Printf("[LOCAL] Session %s closing buffer channel from waitForExit", s.id)
-		close(s.buffer)
-	})
+	log.Printf("[LOCAL] Session %s closing output from waitForExit", s.id)
+	s.output.Close()
 }
 
 func (s *LocalPTYSession) readOutput() {
@@ -109,11 +106,8 @@ func (s *LocalPTYSession) readOutput() {
 					if err.Error() == "EOF" ||
 						err.Error() == "read: The handle is invalid" ||
 						err.Error() == "read: The pipe is being closed" {
-						log.Printf("[LOCAL] Session %s readOutput detected process exit (error: %v), closing buffer", s.id, err)
-						s.bufferCloseOnce.Do(func() {
-							log.Printf("[LOCAL] Session %s closing buffer channel", s.id)
-							close(s.buffer)
-						})
+						log.Printf("[LOCAL] Session %s readOutput detected process exit (error: %v), closing output", s.id, err)
+						s.output.Close()
 						return
 					}
 					// Log error but don't close immediately - might be recoverable
@@ -126,12 +120,9 @@ func (s *LocalPTYSession) readOutput() {
 					}
 					continue
 				}
-				// EOF detected - close buffer channel to signal streamOutput
-				log.Printf("[LOCAL] Session %s readOutput received EOF, closing buffer", s.id)
-				s.bufferCloseOnce.Do(func() {
-					log.Printf("[LOCAL] Session %s closing buffer channel", s.id)
-					close(s.buffer)
-				})
+				// EOF detected - close output pipe to signal streamOutput
+				log.Printf("[LOCAL] Session %s readOutput received EOF, closing output", s.id)
+				s.output.Close()
 				return
 			}
 			if n > 0 {
@@ -139,12 +130,9 @@ func (s *LocalPTYSession) readOutput() {
 				data := make([]byte, n)
 				copy(data, buf[:n])
 
-				// Block until buffer has space - NEVER drop data
-				// The large buffer (1000) should handle temporary slowdowns
-				// If frontend is truly stuck, user will close the tab anyway
-				select {
-				case s.buffer <- data:
-				case <-s.done:
+				// Block until the pipe has space - NEVER drop data
+				// Close() releases the pipe if the session goes away meanwhile
+				if !s.output.Write(data) {
 					return
 				}
 			} else if n == 0 {
@@ -155,11 +143,8 @@ func (s *LocalPTYSession) readOutput() {
 					// When PowerShell exits, ConPTY may not immediately close the pipe,
 					// but we get zero-length reads. After multiple consecutive zeros,
 					// we assume the process has exited.
-					log.Printf("[LOCAL] Session %s readOutput detected %d consecutive zero reads, assuming process exited, closing buffer", s.id, zeroReadCount)
-					s.bufferCloseOnce.Do(func() {
-						log.Printf("[LOCAL] Session %s closing buffer channel", s.id)
-						close(s.buffer)
-					})
+					log.Printf("[LOCAL] Session %s readOutput detected %d consecutive zero reads, assuming process exited, closing output", s.id, zeroReadCount)
+					s.output.Close()
 					return
 				}
 				// Give it a brief moment and check again
@@ -219,11 +204,8 @@ func (s *LocalPTYSession) Close() error {
 	// Signal readOutput goroutine to stop
 	close(s.done)
 
-	// Ensure buffer is closed (only once)
-	s.bufferCloseOnce.Do(func() {
-		log.Printf("[LOCAL] Session %s closing buffer channel in Close()", s.id)
-		close(s.buffer)
-	})
+	// Ensure output is closed so streamOutput drains and exits
+	s.output.Close()
 
 	if s.cpty != nil {
 		s.cpty.Close()
@@ -239,11 +221,21 @@ func (s *LocalPTYSession) GetMetadata() SessionMetadata {
 	return s.metadata
 }
 
+func (s *LocalPTYSession) SetWorkingDirectory(dir string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir == "" || dir == s.metadata.WorkingDirectory {
+		return false
+	}
+	s.metadata.WorkingDirectory = dir
+	return true
+}
+
 func (s *LocalPTYSession) ReadOutput() []byte {
-	data, ok := <-s.buffer
-	if !ok {
-		log.Printf("[LOCAL] Session %s ReadOutput received nil (buffer closed), returning nil to trigger terminal:closed", s.id)
-		return nil
+	data := s.output.Read()
+	if data == nil {
+		log.Printf("[LOCAL] Session %s ReadOutput received nil (output closed), returning nil to trigger terminal:closed", s.id)
 	}
 	return data
 }
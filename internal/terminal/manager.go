@@ -4,37 +4,61 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"host-vault/internal/audit"
+	"host-vault/internal/storage"
 	"log"
-	"os"
-	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"golang.org/x/crypto/ssh"
 )
 
+// outputStream is the manager-side state of one streamOutput loop
+type outputStream struct {
+	flow      *outputFlow
+	coalescer *outputCoalescer
+	framer    outputFramer
+	scanner   oscScanner
+	commands  *commandTracker
+	lastProbe time.Time
+}
+
+// outputNotice is an event derived from a session's output, emitted after
+// the output that produced it
+type outputNotice struct {
+	name    string
+	payload interface{}
+}
+
 type TerminalManager struct {
 	sessions      map[string]Session
+	streams       map[string]*outputStream
 	mu            sync.RWMutex
 	ctx           context.Context
 	knownHostsMgr *KnownHostsManager
+	framing       OutputFraming
+	promptMu      sync.Mutex
+	prompts       map[string]chan bool
+	resolveConn   ConnectionResolver
+	frozen        map[string]bool // sessions refusing input, see FreezeConnectionSessions
+	auditMu       sync.Mutex
+	auditLog      *audit.Log
+	audited       map[string]*auditedConnection // open SSH connections by session ID
 }
 
+// ConnectionResolver looks up a saved connection, secrets included, so
+// they never have to pass through the frontend
+type ConnectionResolver func(connectionID string) (ConnectionConfig, error)
+
 func NewTerminalManager(ctx context.Context) *TerminalManager {
-	// Initialize known hosts manager
-	// Try to get app data path from environment or use default
-	appDataPath := os.Getenv("APPDATA")
-	if appDataPath == "" {
-		// Fallback for non-Windows or if APPDATA is not set
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			log.Printf("[TERM] Failed to get home directory: %v", err)
-			appDataPath = "."
-		} else {
-			appDataPath = filepath.Join(homeDir, "AppData", "Roaming")
-		}
+	// Initialize known hosts manager in the app data directory
+	appPath, err := storage.AppDataDir()
+	if err != nil {
+		log.Printf("[TERM] App data directory unavailable, keeping known hosts in the working directory: %v", err)
+		appPath = "."
 	}
-	appPath := filepath.Join(appDataPath, "host-vault")
 
 	knownHostsMgr, err := NewKnownHostsManager(appPath)
 	if err != nil {
@@ -46,8 +70,13 @@ func NewTerminalManager(ctx context.Context) *TerminalManager {
 
 	return &TerminalManager{
 		sessions:      make(map[string]Session),
+		streams:       make(map[string]*outputStream),
 		ctx:           ctx,
 		knownHostsMgr: knownHostsMgr,
+		framing:       OutputFramingText,
+		prompts:       make(map[string]chan bool),
+		frozen:        make(map[string]bool),
+		audited:       make(map[string]*auditedConnection),
 	}
 }
 
@@ -66,9 +95,44 @@ func (tm *TerminalManager) CreateLocalSession(shell, cwd string, env map[string]
 	return session.ID(), nil
 }
 
+// SetConnectionResolver sets where CreateSSHSessionForConnection and
+// ReconnectSessionForConnection look up saved connections
+func (tm *TerminalManager) SetConnectionResolver(resolve ConnectionResolver) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.resolveConn = resolve
+}
+
+// resolveConnection looks up a saved connection through the resolver
+func (tm *TerminalManager) resolveConnection(connectionID string, guestMode bool) (ConnectionConfig, error) {
+	tm.mu.RLock()
+	resolve := tm.resolveConn
+	tm.mu.RUnlock()
+
+	if resolve == nil {
+		return ConnectionConfig{}, fmt.Errorf("saved connections not available")
+	}
+	config, err := resolve(connectionID)
+	if err != nil {
+		return ConnectionConfig{}, fmt.Errorf("failed to resolve connection %s: %w", connectionID, err)
+	}
+	config.GuestMode = guestMode
+	return config, nil
+}
+
+// CreateSSHSessionForConnection opens a session for a saved connection,
+// with the credentials the resolver holds for it
+func (tm *TerminalManager) CreateSSHSessionForConnection(connectionID string, guestMode bool) (string, error) {
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return "", err
+	}
+	return tm.CreateSSHSession(connectionID, config)
+}
+
 func (tm *TerminalManager) CreateSSHSession(connectionID string, config ConnectionConfig) (string, error) {
 	log.Printf("[TERM] Creating SSH session for connection %s", connectionID)
-	session, err := NewSSHSession(connectionID, config, tm.knownHostsMgr)
+	session, err := NewSSHSession(connectionID, config, tm.knownHostsMgr, tm.promptHostKey)
 	if err != nil {
 		log.Printf("[TERM] Failed to create SSH session for connection %s: %v", connectionID, err)
 		return "", fmt.Errorf("%w", err)
@@ -79,11 +143,69 @@ func (tm *TerminalManager) CreateSSHSession(connectionID string, config Connecti
 	tm.sessions[session.ID()] = session
 	tm.mu.Unlock()
 
+	tm.auditOpened(session, config)
 	go tm.streamOutput(session)
 
 	return session.ID(), nil
 }
 
+// promptHostKey emits terminal:host-key-prompt and waits for the answer
+// given through RespondHostKeyPrompt. No answer within
+// hostKeyPromptTimeout counts as a rejection.
+func (tm *TerminalManager) promptHostKey(host string, port int, info *HostKeyInfo) bool {
+	promptID := uuid.New().String()
+	answer := make(chan bool, 1)
+
+	tm.promptMu.Lock()
+	tm.prompts[promptID] = answer
+	tm.promptMu.Unlock()
+
+	defer func() {
+		tm.promptMu.Lock()
+		delete(tm.prompts, promptID)
+		tm.promptMu.Unlock()
+	}()
+
+	log.Printf("[SSH] Asking user to verify %s host key for %s:%d", info.KeyType, host, port)
+	runtime.EventsEmit(tm.ctx, "terminal:host-key-prompt", HostKeyPromptEvent{
+		PromptID: promptID,
+		Host:     host,
+		Port:     port,
+		HostKey:  *info,
+	})
+
+	select {
+	case accept := <-answer:
+		return accept
+	case <-time.After(hostKeyPromptTimeout):
+		log.Printf("[SSH] Host key prompt for %s:%d timed out", host, port)
+		runtime.EventsEmit(tm.ctx, "terminal:host-key-prompt-expired", HostKeyPromptEvent{
+			PromptID: promptID,
+			Host:     host,
+			Port:     port,
+		})
+		return false
+	}
+}
+
+// RespondHostKeyPrompt answers a terminal:host-key-prompt event
+func (tm *TerminalManager) RespondHostKeyPrompt(promptID string, accept bool) error {
+	tm.promptMu.Lock()
+	answer, exists := tm.prompts[promptID]
+	tm.promptMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("host key prompt not found: %s", promptID)
+	}
+
+	select {
+	case answer <- accept:
+	default:
+		// Already answered
+	}
+	return nil
+}
+
 // GetHostKeyInfo gets the host key fingerprint for a host
 func (tm *TerminalManager) GetHostKeyInfo(host string, port int) (*HostKeyInfo, error) {
 	if tm.knownHostsMgr == nil {
@@ -99,19 +221,146 @@ func (tm *TerminalManager) AcceptHostKey(host string, port int, keyBase64 string
 		return fmt.Errorf("known hosts manager not initialized")
 	}
 
+	key, err := parseHostKeyBase64(keyBase64)
+	if err != nil {
+		return err
+	}
+
+	return tm.knownHostsMgr.AddHostKey(host, port, key, isGuest)
+}
+
+// parseHostKeyBase64 decodes a public key as handed out by GetHostKeyInfo
+func parseHostKeyBase64(keyBase64 string) (ssh.PublicKey, error) {
 	// Decode base64 key
 	keyBytes, err := base64.StdEncoding.DecodeString(keyBase64)
 	if err != nil {
-		return fmt.Errorf("failed to decode key: %w", err)
+		return nil, fmt.Errorf("failed to decode key: %w", err)
 	}
 
 	// Parse the public key
 	key, err := ssh.ParsePublicKey(keyBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse public key: %w", err)
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
+	return key, nil
+}
 
-	return tm.knownHostsMgr.AddHostKey(host, port, key, isGuest)
+// ListKnownHosts returns all known_hosts lines with their seen times
+func (tm *TerminalManager) ListKnownHosts() ([]KnownHostRecord, error) {
+	if tm.knownHostsMgr == nil {
+		return nil, fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.ListKnownHosts(), nil
+}
+
+// RemoveKnownHost removes a single known_hosts line by record ID
+func (tm *TerminalManager) RemoveKnownHost(id string) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.RemoveKnownHost(id)
+}
+
+// RemoveHostKey removes all keys stored for a host
+func (tm *TerminalManager) RemoveHostKey(host string, port int) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.RemoveHostKey(host, port)
+}
+
+// RekeyHostKey replaces all keys stored for a host with keyBase64
+func (tm *TerminalManager) RekeyHostKey(host string, port int, keyBase64 string, isGuest bool) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+
+	key, err := parseHostKeyBase64(keyBase64)
+	if err != nil {
+		return err
+	}
+	return tm.knownHostsMgr.RekeyHostKey(host, port, key, isGuest)
+}
+
+// ForgetGuestHostKeys drops host keys accepted in guest mode
+func (tm *TerminalManager) ForgetGuestHostKeys() (int, error) {
+	if tm.knownHostsMgr == nil {
+		return 0, fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.ForgetGuestHostKeys(), nil
+}
+
+// ScanHostKeys collects and classifies the host keys of many hosts at once.
+// Targets naming a saved connection are scanned at its host and port.
+func (tm *TerminalManager) ScanHostKeys(targets []HostKeyScanTarget) (*HostKeyScanReport, error) {
+	if tm.knownHostsMgr == nil {
+		return nil, fmt.Errorf("known hosts manager not initialized")
+	}
+
+	resolved := make([]HostKeyScanTarget, len(targets))
+	for i, target := range targets {
+		if target.ConnectionID != "" {
+			config, err := tm.resolveConnection(target.ConnectionID, false)
+			if err != nil {
+				target.err = err
+			} else {
+				target.Host, target.Port = config.Host, config.Port
+			}
+		}
+		resolved[i] = target
+	}
+	return tm.knownHostsMgr.ScanHostKeys(resolved), nil
+}
+
+// AcceptHostKeys stores several host keys in one go, e.g. from a scan report
+func (tm *TerminalManager) AcceptHostKeys(keys []HostKeyAcceptance, isGuest bool) (int, error) {
+	if tm.knownHostsMgr == nil {
+		return 0, fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.AddHostKeys(keys, isGuest)
+}
+
+// ImportKnownHosts merges an OpenSSH known_hosts file (default ~/.ssh/known_hosts)
+// into the app's trusted hosts and returns the number of new entries
+func (tm *TerminalManager) ImportKnownHosts(path string) (int, error) {
+	if tm.knownHostsMgr == nil {
+		return 0, fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.ImportKnownHosts(path)
+}
+
+// ExportKnownHosts writes the app's trusted hosts to path in OpenSSH format
+func (tm *TerminalManager) ExportKnownHosts(path string) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.ExportKnownHosts(path)
+}
+
+// ExportKnownHostEntries writes the selected known_hosts lines to path
+func (tm *TerminalManager) ExportKnownHostEntries(path string, ids []string) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	return tm.knownHostsMgr.ExportKnownHostEntries(path, ids)
+}
+
+// SetSSHFPConfig configures SSHFP DNS verification of host keys
+func (tm *TerminalManager) SetSSHFPConfig(config SSHFPConfig) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	tm.knownHostsMgr.SetSSHFPConfig(config)
+	return nil
+}
+
+// SetUseSystemKnownHosts toggles ~/.ssh/known_hosts as a read-only trust source
+func (tm *TerminalManager) SetUseSystemKnownHosts(enabled bool) error {
+	if tm.knownHostsMgr == nil {
+		return fmt.Errorf("known hosts manager not initialized")
+	}
+	tm.knownHostsMgr.SetUseSystemKnownHosts(enabled)
+	return nil
 }
 
 func (tm *TerminalManager) DuplicateSession(sessionID string) (string, error) {
@@ -123,6 +372,8 @@ func (tm *TerminalManager) DuplicateSession(sessionID string) (string, error) {
 		return "", fmt.Errorf("session not found: %s", sessionID)
 	}
 
+	// WorkingDirectory follows the shell (OSC 7 / /proc), so the copy
+	// opens where the user currently is
 	metadata := originalSession.GetMetadata()
 
 	if originalSession.Type() == SessionTypeLocal {
@@ -135,11 +386,15 @@ func (tm *TerminalManager) DuplicateSession(sessionID string) (string, error) {
 func (tm *TerminalManager) WriteToSession(sessionID string, data []byte) error {
 	tm.mu.RLock()
 	session, exists := tm.sessions[sessionID]
+	frozenErr := tm.checkFrozen(sessionID)
 	tm.mu.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("session not found: %s", sessionID)
 	}
+	if frozenErr != nil {
+		return frozenErr
+	}
 
 	return session.Write(data)
 }
@@ -164,8 +419,15 @@ func (tm *TerminalManager) CloseSession(sessionID string) error {
 		log.Printf("[TERM] Removing session %s from sessions map", sessionID)
 		delete(tm.sessions, sessionID)
 	}
+	delete(tm.frozen, sessionID)
+	stream := tm.streams[sessionID]
 	tm.mu.Unlock()
 
+	// Don't let streamOutput sit waiting for acks from a tab that is going away
+	if stream != nil {
+		stream.flow.stop()
+	}
+
 	if !exists {
 		log.Printf("[TERM] Session %s not found for closing (may already be closed)", sessionID)
 		return fmt.Errorf("session not found: %s", sessionID)
@@ -213,15 +475,168 @@ func (tm *TerminalManager) GetSessionScrollback(sessionID string) ([][]byte, err
 	return nil, nil
 }
 
+// AckOutput records that the frontend has consumed every terminal:output
+// event for the session up to and including seq
+func (tm *TerminalManager) AckOutput(sessionID string, seq uint64) error {
+	tm.mu.RLock()
+	stream, exists := tm.streams[sessionID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	stream.flow.ack(seq)
+	return nil
+}
+
+// GetOutputStats returns output batching metrics for a session
+func (tm *TerminalManager) GetOutputStats(sessionID string) (OutputStats, error) {
+	tm.mu.RLock()
+	stream, exists := tm.streams[sessionID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return OutputStats{}, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	return stream.coalescer.Stats(), nil
+}
+
+// SetOutputFraming switches how terminal:output payloads are encoded for
+// all sessions. Takes effect from the next event.
+func (tm *TerminalManager) SetOutputFraming(framing OutputFraming) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.framing = framing
+}
+
+func (tm *TerminalManager) outputFraming() OutputFraming {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.framing
+}
+
+// emitOutput sends one terminal:output event, waiting for the frontend to
+// catch up first; while we wait the session's pipe fills and its reader
+// stops reading
+func (tm *TerminalManager) emitOutput(sessionID string, stream *outputStream, payload, encoding string, size int) {
+	seq := stream.flow.reserve(sessionID, size)
+	runtime.EventsEmit(tm.ctx, "terminal:output", TerminalOutputEvent{
+		SessionID: sessionID,
+		Data:      payload,
+		Encoding:  encoding,
+		Seq:       seq,
+	})
+}
+
+// scanOutput runs a batch of output through the session's OSC scanner and
+// returns the events the embedded control sequences give rise to
+func (tm *TerminalManager) scanOutput(session Session, stream *outputStream, data []byte) []outputNotice {
+	sessionID := session.ID()
+	var notices []outputNotice
+	cwdFromOSC := false
+	_, local := session.(*LocalPTYSession)
+
+	stream.scanner.Scan(data, oscHandler{
+		text: stream.commands.Text,
+		osc: func(payload string, offset int64) {
+			if host, dir, ok := parseOSC7(payload); ok {
+				if local && !isLocalHost(host) {
+					return
+				}
+				cwdFromOSC = true
+				if session.SetWorkingDirectory(dir) {
+					notices = append(notices, cwdNotice(sessionID, dir))
+				}
+				return
+			}
+			if name, record := stream.commands.Marker(payload, offset); record != nil {
+				notices = append(notices, outputNotice{
+					name:    name,
+					payload: TerminalCommandEvent{SessionID: sessionID, Command: *record},
+				})
+			}
+		},
+	})
+
+	// Shells that don't emit OSC 7 can still be followed through /proc
+	if prober, ok := session.(cwdProber); ok && !cwdFromOSC && time.Since(stream.lastProbe) >= cwdProbeInterval {
+		stream.lastProbe = time.Now()
+		if dir, err := prober.ProbeWorkingDirectory(); err == nil && session.SetWorkingDirectory(dir) {
+			notices = append(notices, cwdNotice(sessionID, dir))
+		}
+	}
+
+	return notices
+}
+
+func cwdNotice(sessionID, dir string) outputNotice {
+	return outputNotice{
+		name:    "terminal:cwd-changed",
+		payload: TerminalCwdEvent{SessionID: sessionID, WorkingDirectory: dir},
+	}
+}
+
+// GetCommandHistory returns the shell-integration command history of a session
+func (tm *TerminalManager) GetCommandHistory(sessionID string) ([]CommandRecord, error) {
+	tm.mu.RLock()
+	stream, exists := tm.streams[sessionID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	return stream.commands.History(), nil
+}
+
+// GetCommandOutput returns the plain-text output of one recorded command
+func (tm *TerminalManager) GetCommandOutput(sessionID string, commandID int) (string, error) {
+	tm.mu.RLock()
+	stream, exists := tm.streams[sessionID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	output, ok := stream.commands.Output(commandID)
+	if !ok {
+		return "", fmt.Errorf("output not available for command %d", commandID)
+	}
+	return output, nil
+}
+
 func (tm *TerminalManager) streamOutput(session Session) {
 	sessionID := session.ID()
 	log.Printf("[TERM] Starting output stream for session %s", sessionID)
 
+	stream := &outputStream{
+		flow:      newOutputFlow(outputFlowWindow),
+		coalescer: newOutputCoalescer(session.ReadOutput),
+		commands:  newCommandTracker(),
+	}
+	tm.mu.Lock()
+	if previous := tm.streams[sessionID]; previous != nil {
+		previous.flow.stop()
+		// Keep command history across a reconnect
+		stream.commands = previous.commands
+	}
+	tm.streams[sessionID] = stream
+	tm.mu.Unlock()
+	conn := tm.auditedConnection(sessionID)
+
 	for {
-		data := session.ReadOutput()
+		data := stream.coalescer.Next()
 		if data == nil {
 			log.Printf("[TERM] Session %s output stream ended - will emit terminal:closed", sessionID)
 
+			// Deliver a trailing partial character rather than losing it
+			if payload, encoding := stream.framer.Flush(tm.outputFraming()); payload != "" {
+				tm.emitOutput(sessionID, stream, payload, encoding, len(payload))
+			}
+
 			// Clean up the session from our map
 			tm.mu.Lock()
 			_, exists := tm.sessions[sessionID]
@@ -229,7 +644,13 @@ func (tm *TerminalManager) streamOutput(session Session) {
 				delete(tm.sessions, sessionID)
 				log.Printf("[TERM] Removed session %s from sessions map", sessionID)
 			}
+			if tm.streams[sessionID] == stream {
+				delete(tm.streams, sessionID)
+			}
+			delete(tm.frozen, sessionID)
 			tm.mu.Unlock()
+			stream.flow.stop()
+			tm.auditClosed(conn)
 
 			// Emit closed event so frontend can close the tab
 			log.Printf("[TERM] Emitting terminal:closed for session %s", sessionID)
@@ -239,10 +660,18 @@ func (tm *TerminalManager) streamOutput(session Session) {
 			break
 		}
 
-		runtime.EventsEmit(tm.ctx, "terminal:output", TerminalOutputEvent{
-			SessionID: sessionID,
-			Data:      string(data),
-		})
+		notices := tm.scanOutput(session, stream, data)
+
+		payload, encoding := stream.framer.Frame(data, tm.outputFraming())
+		if payload != "" {
+			tm.emitOutput(sessionID, stream, payload, encoding, len(data))
+		}
+		// An empty payload means everything was held back as an incomplete
+		// UTF-8 sequence
+
+		for _, notice := range notices {
+			runtime.EventsEmit(tm.ctx, notice.name, notice.payload)
+		}
 	}
 	log.Printf("[TERM] Output stream ended for session %s", sessionID)
 }
@@ -273,12 +702,16 @@ func (tm *TerminalManager) ReconnectSession(sessionID string, config ConnectionC
 		return fmt.Errorf("failed to cast session to SSH session")
 	}
 
+	// Reconnect drops the current connection, if it is still up
+	tm.auditClosed(tm.auditedConnection(sessionID))
+
 	err := sshSession.Reconnect(config)
 	if err != nil {
 		log.Printf("[TERM] Failed to reconnect session %s: %v", sessionID, err)
 		return fmt.Errorf("failed to reconnect session: %w", err)
 	}
 
+	tm.auditOpened(sshSession, config)
 	go tm.streamOutput(session)
 
 	runtime.EventsEmit(tm.ctx, "terminal:reconnected", TerminalClosedEvent{
@@ -289,6 +722,28 @@ func (tm *TerminalManager) ReconnectSession(sessionID string, config ConnectionC
 	return nil
 }
 
+// ReconnectSessionForConnection reconnects a session opened for a saved
+// connection, resolving its current settings and credentials again
+func (tm *TerminalManager) ReconnectSessionForConnection(sessionID string, guestMode bool) error {
+	tm.mu.RLock()
+	session, exists := tm.sessions[sessionID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	connectionID := session.GetMetadata().ConnectionID
+	if connectionID == "" {
+		return fmt.Errorf("session %s was not opened for a saved connection", sessionID)
+	}
+
+	config, err := tm.resolveConnection(connectionID, guestMode)
+	if err != nil {
+		return err
+	}
+	return tm.ReconnectSession(sessionID, config)
+}
+
 func (tm *TerminalManager) CloseAll() {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -296,6 +751,12 @@ func (tm *TerminalManager) CloseAll() {
 	for _, session := range tm.sessions {
 		session.Close()
 	}
+	for _, stream := range tm.streams {
+		stream.flow.stop()
+	}
 
 	tm.sessions = make(map[string]Session)
+	tm.streams = make(map[string]*outputStream)
+	tm.frozen = make(map[string]bool)
+	tm.auditCloseAll()
 }
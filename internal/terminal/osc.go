@@ -0,0 +1,183 @@
+package terminal
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// oscMaxPayload bounds the payload kept for a single OSC sequence; longer
+// sequences (e.g. inline images) are skipped rather than buffered
+const oscMaxPayload = 4096
+
+type oscState int
+
+const (
+	oscGround oscState = iota
+	oscEscape
+	oscPayload
+	oscPayloadEscape
+)
+
+// oscScanner splits a terminal output stream into plain text and OSC
+// sequences (ESC ] ... BEL or ESC ] ... ESC \). Sequences may be split
+// across any number of chunks. Offsets count bytes since the scanner was
+// created.
+type oscScanner struct {
+	state    oscState
+	payload  []byte
+	overflow bool
+	offset   int64
+}
+
+// oscHandler receives the scanner's output. text is called for bytes
+// outside OSC sequences (other escape sequences included) and osc for each
+// complete sequence, with the stream offset just past its terminator.
+type oscHandler struct {
+	text func(data []byte)
+	osc  func(payload string, offset int64)
+}
+
+// Scan feeds one chunk of output through the scanner
+func (s *oscScanner) Scan(data []byte, h oscHandler) {
+	segStart := 0
+	flushText := func(end int) {
+		if end > segStart && h.text != nil {
+			h.text(data[segStart:end])
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch s.state {
+		case oscGround:
+			if b == 0x1b {
+				flushText(i)
+				s.state = oscEscape
+			}
+		case oscEscape:
+			if b == ']' {
+				s.state = oscPayload
+				s.payload = s.payload[:0]
+				s.overflow = false
+				continue
+			}
+			// Not an OSC: the ESC belongs to the text stream, and b is
+			// handled as ordinary input
+			if h.text != nil {
+				h.text([]byte{0x1b})
+			}
+			segStart = i
+			s.state = oscGround
+			i--
+		case oscPayload:
+			switch b {
+			case 0x07:
+				s.finish(s.offset+int64(i)+1, h)
+				segStart = i + 1
+			case 0x1b:
+				s.state = oscPayloadEscape
+			default:
+				if len(s.payload) < oscMaxPayload {
+					s.payload = append(s.payload, b)
+				} else {
+					s.overflow = true
+				}
+			}
+		case oscPayloadEscape:
+			// ESC \ is the string terminator; any other ESC ends the
+			// sequence and starts a new escape, so b is handled again
+			s.finish(s.offset+int64(i)+1, h)
+			segStart = i + 1
+			if b != '\\' {
+				s.state = oscEscape
+				i--
+			}
+		}
+	}
+
+	if s.state == oscGround {
+		flushText(len(data))
+	}
+	s.offset += int64(len(data))
+}
+
+func (s *oscScanner) finish(offset int64, h oscHandler) {
+	s.state = oscGround
+	if !s.overflow && h.osc != nil {
+		h.osc(string(s.payload), offset)
+	}
+	s.payload = s.payload[:0]
+}
+
+// Offset returns the number of bytes scanned so far
+func (s *oscScanner) Offset() int64 {
+	return s.offset
+}
+
+// stripTerminalControls reduces captured terminal output to plain text:
+// escape sequences are removed, backspaces applied and line endings
+// normalised
+func stripTerminalControls(data []byte) string {
+	var out []rune
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == 0x1b:
+			i = skipEscapeSequence(data, i)
+			continue
+		case b == '\b':
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case b == '\n' || b == '\t':
+			out = append(out, rune(b))
+		case b < 0x20 || b == 0x7f:
+			// Drop \r and other C0 controls
+		default:
+			r, size := utf8.DecodeRune(data[i:])
+			out = append(out, r)
+			i += size
+			continue
+		}
+		i++
+	}
+	return string(out)
+}
+
+// skipEscapeSequence returns the index just past the escape sequence
+// starting at data[i]. Unterminated sequences run to the end of data.
+func skipEscapeSequence(data []byte, i int) int {
+	i++
+	if i >= len(data) {
+		return i
+	}
+	switch data[i] {
+	case '[':
+		// CSI: parameters, then a final byte in 0x40-0x7e
+		for i++; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return i
+	case ']', 'P', '_', '^':
+		// String sequences end with BEL or ST
+		for i++; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return i
+	default:
+		return i + 1
+	}
+}
+
+// splitOSC separates an OSC payload into its numeric code and the rest
+func splitOSC(payload string) (code, rest string) {
+	code, rest, _ = strings.Cut(payload, ";")
+	return code, rest
+}
@@ -0,0 +1,140 @@
+package terminal
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// coalesceMaxBytes caps the size of a single terminal:output event
+	coalesceMaxBytes = 64 * 1024
+	// coalesceLinger is how long a batch waits for more output to arrive
+	coalesceLinger = 4 * time.Millisecond
+	// coalesceMaxEventsPerSecond caps the event rate per session
+	coalesceMaxEventsPerSecond = 200
+	// coalesceInteractiveBytes is the largest chunk treated as an
+	// interactive echo and flushed without lingering
+	coalesceInteractiveBytes = 256
+)
+
+// OutputStats describes how a session's output has been batched into
+// terminal:output events
+type OutputStats struct {
+	Events             uint64  `json:"events"`
+	Bytes              uint64  `json:"bytes"`
+	AvgBytesPerEvent   float64 `json:"avgBytesPerEvent"`
+	MaxBytesPerEvent   int     `json:"maxBytesPerEvent"`
+	InteractiveFlushes uint64  `json:"interactiveFlushes"`
+}
+
+// outputCoalescer batches the chunks a session produces into fewer, larger
+// events. A lone small chunk (typically a keystroke echo) is flushed
+// immediately; sustained output is gathered for up to coalesceLinger or
+// coalesceMaxBytes, and events are spaced at least minInterval apart.
+type outputCoalescer struct {
+	in          chan []byte
+	minInterval time.Duration
+	lastFlush   time.Time
+	eof         bool
+
+	mu    sync.Mutex
+	stats OutputStats
+}
+
+// newOutputCoalescer starts pumping read into the coalescer. The pump hands
+// chunks over unbuffered, so a stalled consumer still stalls read.
+func newOutputCoalescer(read func() []byte) *outputCoalescer {
+	c := &outputCoalescer{
+		in:          make(chan []byte),
+		minInterval: time.Second / coalesceMaxEventsPerSecond,
+	}
+
+	go func() {
+		defer close(c.in)
+		for {
+			data := read()
+			if data == nil {
+				return
+			}
+			c.in <- data
+		}
+	}()
+
+	return c
+}
+
+// Next returns the next batch of output, or nil once the session's output
+// has ended and everything before it was returned
+func (c *outputCoalescer) Next() []byte {
+	if c.eof {
+		return nil
+	}
+
+	first, ok := <-c.in
+	if !ok {
+		c.eof = true
+		return nil
+	}
+
+	batch := first
+	interactive := len(first) <= coalesceInteractiveBytes && time.Since(c.lastFlush) >= c.minInterval
+	if interactive {
+		select {
+		case data, ok := <-c.in:
+			if !ok {
+				c.eof = true
+				return c.flush(batch, false)
+			}
+			batch = append(batch, data...)
+		default:
+			return c.flush(batch, true)
+		}
+	}
+
+	wait := coalesceLinger
+	if untilAllowed := c.minInterval - time.Since(c.lastFlush); untilAllowed > wait {
+		wait = untilAllowed
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for len(batch) < coalesceMaxBytes {
+		select {
+		case data, ok := <-c.in:
+			if !ok {
+				c.eof = true
+				return c.flush(batch, false)
+			}
+			batch = append(batch, data...)
+		case <-timer.C:
+			return c.flush(batch, false)
+		}
+	}
+
+	return c.flush(batch, false)
+}
+
+func (c *outputCoalescer) flush(batch []byte, interactive bool) []byte {
+	c.lastFlush = time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Events++
+	c.stats.Bytes += uint64(len(batch))
+	c.stats.AvgBytesPerEvent = float64(c.stats.Bytes) / float64(c.stats.Events)
+	if len(batch) > c.stats.MaxBytesPerEvent {
+		c.stats.MaxBytesPerEvent = len(batch)
+	}
+	if interactive {
+		c.stats.InteractiveFlushes++
+	}
+
+	return batch
+}
+
+// Stats returns a snapshot of the batching metrics
+func (c *outputCoalescer) Stats() OutputStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
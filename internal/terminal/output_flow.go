@@ -0,0 +1,107 @@
+package terminal
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// outputFlowWindow is how many emitted bytes may await a frontend ack
+	outputFlowWindow = 512 * 1024
+	// outputAckTimeout bounds how long streamOutput waits for an ack before
+	// assuming the frontend lost them (e.g. after a webview reload)
+	outputAckTimeout = 10 * time.Second
+)
+
+type flowEntry struct {
+	seq   uint64
+	bytes int
+}
+
+// outputFlow tracks terminal:output events the frontend has not yet
+// acknowledged. streamOutput waits in reserve while a full window is in
+// flight, which stops it draining the session's outputPipe and so pauses
+// the PTY/SSH reader behind it.
+type outputFlow struct {
+	mu       sync.Mutex
+	window   int
+	inFlight int
+	pending  []flowEntry
+	nextSeq  uint64
+	acked    chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newOutputFlow(window int) *outputFlow {
+	return &outputFlow{
+		window: window,
+		acked:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// reserve waits until there is room in the window and returns the
+// sequence number to attach to the next event of n bytes
+func (f *outputFlow) reserve(sessionID string, n int) uint64 {
+	for {
+		f.mu.Lock()
+		if f.inFlight < f.window {
+			f.nextSeq++
+			seq := f.nextSeq
+			f.pending = append(f.pending, flowEntry{seq: seq, bytes: n})
+			f.inFlight += n
+			f.mu.Unlock()
+			return seq
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-f.acked:
+		case <-f.done:
+			return f.forceReserve()
+		case <-time.After(outputAckTimeout):
+			log.Printf("[TERM] Session %s: no output ack for %s, resetting flow window", sessionID, outputAckTimeout)
+			f.reset()
+		}
+	}
+}
+
+// forceReserve assigns a sequence number without waiting for the window
+func (f *outputFlow) forceReserve() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextSeq++
+	return f.nextSeq
+}
+
+// ack releases every event up to and including seq
+func (f *outputFlow) ack(seq uint64) {
+	f.mu.Lock()
+	i := 0
+	for ; i < len(f.pending) && f.pending[i].seq <= seq; i++ {
+		f.inFlight -= f.pending[i].bytes
+	}
+	f.pending = f.pending[i:]
+	f.mu.Unlock()
+
+	select {
+	case f.acked <- struct{}{}:
+	default:
+	}
+}
+
+func (f *outputFlow) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = nil
+	f.inFlight = 0
+}
+
+// stop releases a waiting reserve call; later calls no longer block
+func (f *outputFlow) stop() {
+	f.stopOnce.Do(func() {
+		close(f.done)
+	})
+}
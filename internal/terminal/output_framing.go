@@ -0,0 +1,85 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"unicode/utf8"
+)
+
+// OutputFraming selects how terminal:output payloads are encoded
+type OutputFraming string
+
+const (
+	// OutputFramingText sends Data as a UTF-8 string. Sequences split across
+	// reads are reassembled; bytes that are not valid UTF-8 become U+FFFD.
+	OutputFramingText OutputFraming = "text"
+	// OutputFramingBase64 sends the exact byte stream base64 encoded, for
+	// emulators that do their own decoding
+	OutputFramingBase64 OutputFraming = "base64"
+)
+
+// ParseOutputFraming validates a framing name coming from the frontend
+func ParseOutputFraming(name string) (OutputFraming, error) {
+	switch OutputFraming(name) {
+	case OutputFramingText, OutputFramingBase64:
+		return OutputFraming(name), nil
+	default:
+		return "", fmt.Errorf("unknown output framing: %s", name)
+	}
+}
+
+// outputFramer turns arbitrary output chunks into event payloads. In text
+// mode it holds back a trailing incomplete UTF-8 sequence and prepends it
+// to the next chunk, so multi-byte characters are never cut in half.
+type outputFramer struct {
+	carry []byte
+}
+
+// Frame encodes data for the given framing. The returned encoding is empty
+// for text and "base64" for binary payloads.
+func (f *outputFramer) Frame(data []byte, framing OutputFraming) (payload string, encoding string) {
+	if len(f.carry) > 0 {
+		data = append(f.carry, data...)
+		f.carry = nil
+	}
+
+	if framing == OutputFramingBase64 {
+		return base64.StdEncoding.EncodeToString(data), string(OutputFramingBase64)
+	}
+
+	if cut := incompleteUTF8Suffix(data); cut > 0 {
+		f.carry = append([]byte(nil), data[len(data)-cut:]...)
+		data = data[:len(data)-cut]
+	}
+
+	return string(bytes.ToValidUTF8(data, []byte("\uFFFD"))), ""
+}
+
+// Flush returns whatever is still carried over, for use when output ends
+func (f *outputFramer) Flush(framing OutputFraming) (payload string, encoding string) {
+	if len(f.carry) == 0 {
+		return "", ""
+	}
+	data := f.carry
+	f.carry = nil
+
+	if framing == OutputFramingBase64 {
+		return base64.StdEncoding.EncodeToString(data), string(OutputFramingBase64)
+	}
+	return string(bytes.ToValidUTF8(data, []byte("\uFFFD"))), ""
+}
+
+// incompleteUTF8Suffix returns the length of a truncated multi-byte
+// sequence at the end of p, or 0 if p ends on a rune boundary
+func incompleteUTF8Suffix(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i > len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return 0
+			}
+			return len(p) - i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"sync"
+)
+
+// outputPipeLimit is the number of bytes a session may queue before its
+// reader goroutine blocks
+const outputPipeLimit = 256 * 1024
+
+// outputPipe is a bounded, lossless queue of output chunks between a
+// session's reader goroutine and the manager's streamOutput loop.
+// Write blocks while the queue is full, so a slow consumer pauses reads
+// from the PTY or SSH channel instead of dropping data. The kernel PTY
+// buffer or the SSH channel window then pushes back on the producer.
+type outputPipe struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	queued int
+	limit  int
+	closed bool
+}
+
+func newOutputPipe(limit int) *outputPipe {
+	p := &outputPipe{limit: limit}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Write queues a chunk, blocking while the pipe is full. It returns false
+// if the pipe was closed, in which case the chunk is discarded.
+func (p *outputPipe) Write(data []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Always admit a chunk into an empty pipe so oversized chunks can't wedge it
+	for !p.closed && p.queued > 0 && p.queued+len(data) > p.limit {
+		p.cond.Wait()
+	}
+	if p.closed {
+		return false
+	}
+
+	p.chunks = append(p.chunks, data)
+	p.queued += len(data)
+	p.cond.Broadcast()
+	return true
+}
+
+// Read returns the next queued chunk, blocking until one is available.
+// Chunks queued before Close are still delivered; nil is returned once the
+// pipe is closed and drained.
+func (p *outputPipe) Read() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.chunks) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.chunks) == 0 {
+		return nil
+	}
+
+	data := p.chunks[0]
+	p.chunks[0] = nil
+	p.chunks = p.chunks[1:]
+	p.queued -= len(data)
+	p.cond.Broadcast()
+	return data
+}
+
+// Close marks the end of output and releases any blocked writers. It is
+// safe to call more than once.
+func (p *outputPipe) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.cond.Broadcast()
+}
@@ -36,18 +36,36 @@ type Session interface {
 	Resize(cols, rows int) error
 	Close() error
 	GetMetadata() SessionMetadata
+	// SetWorkingDirectory records the shell's live cwd and reports whether it changed
+	SetWorkingDirectory(dir string) bool
 	ReadOutput() []byte
 }
 
+// cwdProber is implemented by sessions that can look up the cwd of their
+// foreground process directly, without relying on OSC 7
+type cwdProber interface {
+	ProbeWorkingDirectory() (string, error)
+}
+
 type TerminalOutputEvent struct {
 	SessionID string `json:"SessionID"`
 	Data      string `json:"Data"`
+	// Encoding is "base64" when Data carries raw bytes; empty for UTF-8 text
+	Encoding string `json:"Encoding,omitempty"`
+	// Seq must be acknowledged via AckTerminalOutput once Data is consumed
+	Seq uint64 `json:"Seq"`
 }
 
 type TerminalClosedEvent struct {
 	SessionID string `json:"SessionID"`
 }
 
+// TerminalCwdEvent is emitted as terminal:cwd-changed
+type TerminalCwdEvent struct {
+	SessionID        string `json:"SessionID"`
+	WorkingDirectory string `json:"WorkingDirectory"`
+}
+
 type TerminalErrorEvent struct {
 	SessionID string `json:"SessionID"`
 	Error     string `json:"Error"`
@@ -0,0 +1,252 @@
+package terminal
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// commandHistoryLimit is how many commands are remembered per session
+	commandHistoryLimit = 1000
+	// commandOutputLimit caps the output captured for a single command
+	commandOutputLimit = 256 * 1024
+	// commandOutputRetained is how many recent commands keep their output
+	commandOutputRetained = 16
+	// commandInputLimit caps the echoed input captured as command text
+	commandInputLimit = 4096
+)
+
+// CommandRecord describes one command delimited by OSC 133 shell
+// integration markers. Offsets are byte positions in the session's output
+// stream.
+type CommandRecord struct {
+	ID              int        `json:"id"`
+	Command         string     `json:"command"`
+	PromptOffset    int64      `json:"promptOffset"`
+	OutputStart     int64      `json:"outputStart"`
+	OutputEnd       int64      `json:"outputEnd"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	ExitCode        *int       `json:"exitCode,omitempty"`
+	OutputTruncated bool       `json:"outputTruncated"`
+	Running         bool       `json:"running"`
+}
+
+// TerminalCommandEvent is emitted as terminal:command-started and
+// terminal:command-finished
+type TerminalCommandEvent struct {
+	SessionID string        `json:"SessionID"`
+	Command   CommandRecord `json:"Command"`
+}
+
+type commandPhase int
+
+const (
+	phaseIdle commandPhase = iota
+	phasePrompt
+	phaseInput
+	phaseOutput
+)
+
+type commandEntry struct {
+	record CommandRecord
+	output []byte
+}
+
+// commandTracker follows FinalTerm/OSC 133 markers in a session's output:
+// A (prompt start), B (command start), C (command executed, output begins)
+// and D[;exit] (command finished). The VS Code 633 variant is accepted too,
+// including 633;E which carries the command line explicitly.
+type commandTracker struct {
+	mu       sync.Mutex
+	phase    commandPhase
+	nextID   int
+	prompt   int64
+	input    []byte
+	explicit string
+	current  *commandEntry
+	history  []*commandEntry
+}
+
+func newCommandTracker() *commandTracker {
+	return &commandTracker{nextID: 1}
+}
+
+// Text records plain output between markers
+func (ct *commandTracker) Text(data []byte) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	switch ct.phase {
+	case phaseInput:
+		ct.input = appendCapped(ct.input, data, commandInputLimit)
+	case phaseOutput:
+		if ct.current == nil {
+			return
+		}
+		before := len(ct.current.output)
+		ct.current.output = appendCapped(ct.current.output, data, commandOutputLimit)
+		if len(ct.current.output)-before < len(data) {
+			ct.current.record.OutputTruncated = true
+		}
+	}
+}
+
+// Marker handles an OSC 133/633 payload seen at offset. It returns the
+// event name to emit and the affected record, if any.
+func (ct *commandTracker) Marker(payload string, offset int64) (string, *CommandRecord) {
+	code, rest := splitOSC(payload)
+	if code != "133" && code != "633" {
+		return "", nil
+	}
+	kind, args, _ := strings.Cut(rest, ";")
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	switch kind {
+	case "A":
+		ct.phase = phasePrompt
+		ct.prompt = offset
+		ct.input = ct.input[:0]
+		ct.explicit = ""
+	case "B":
+		ct.phase = phaseInput
+		ct.input = ct.input[:0]
+	case "E":
+		// 633;E;<command line>[;<nonce>] - literal semicolons arrive as \x3b
+		line, _, _ := strings.Cut(args, ";")
+		ct.explicit = unescapeCommandLine(line)
+	case "C":
+		// A command still marked running never saw its D marker
+		ct.finishCurrent(offset, nil)
+
+		command := ct.explicit
+		if command == "" {
+			command = strings.TrimSpace(stripTerminalControls(ct.input))
+		}
+		entry := &commandEntry{record: CommandRecord{
+			ID:           ct.nextID,
+			Command:      command,
+			PromptOffset: ct.prompt,
+			OutputStart:  offset,
+			OutputEnd:    offset,
+			StartedAt:    time.Now(),
+			Running:      true,
+		}}
+		ct.nextID++
+		ct.current = entry
+		ct.phase = phaseOutput
+		ct.input = ct.input[:0]
+		ct.explicit = ""
+
+		ct.history = append(ct.history, entry)
+		if len(ct.history) > commandHistoryLimit {
+			ct.history = ct.history[len(ct.history)-commandHistoryLimit:]
+		}
+		if n := len(ct.history) - commandOutputRetained - 1; n >= 0 {
+			ct.history[n].output = nil
+		}
+
+		record := entry.record
+		return "terminal:command-started", &record
+	case "D":
+		if ct.current == nil {
+			ct.phase = phaseIdle
+			return "", nil
+		}
+		var exitCode *int
+		if code, err := strconv.Atoi(strings.TrimSpace(args)); err == nil {
+			exitCode = &code
+		}
+		record := ct.finishCurrent(offset, exitCode)
+		ct.phase = phaseIdle
+		return "terminal:command-finished", record
+	}
+
+	return "", nil
+}
+
+// finishCurrent closes the running command, if any (must be called with lock held)
+func (ct *commandTracker) finishCurrent(offset int64, exitCode *int) *CommandRecord {
+	if ct.current == nil {
+		return nil
+	}
+	now := time.Now()
+	ct.current.record.OutputEnd = offset
+	ct.current.record.FinishedAt = &now
+	ct.current.record.ExitCode = exitCode
+	ct.current.record.Running = false
+
+	record := ct.current.record
+	ct.current = nil
+	return &record
+}
+
+// History returns the recorded commands, oldest first
+func (ct *commandTracker) History() []CommandRecord {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	records := make([]CommandRecord, len(ct.history))
+	for i, entry := range ct.history {
+		records[i] = entry.record
+	}
+	return records
+}
+
+// Output returns the plain-text output of a command. ok is false if the
+// command is unknown or its output is no longer retained.
+func (ct *commandTracker) Output(commandID int) (output string, ok bool) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for _, entry := range ct.history {
+		if entry.record.ID != commandID {
+			continue
+		}
+		if entry.output == nil && entry.record.OutputEnd > entry.record.OutputStart {
+			return "", false
+		}
+		return stripTerminalControls(entry.output), true
+	}
+	return "", false
+}
+
+func appendCapped(dst, data []byte, limit int) []byte {
+	if room := limit - len(dst); room < len(data) {
+		if room <= 0 {
+			return dst
+		}
+		data = data[:room]
+	}
+	return append(dst, data...)
+}
+
+// unescapeCommandLine decodes the \xNN and \\ escapes used by 633;E
+func unescapeCommandLine(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if s[i+1] == '\\' {
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+			if s[i+1] == 'x' && i+3 < len(s) {
+				if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+					b.WriteByte(byte(v))
+					i += 3
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -2,9 +2,11 @@ package terminal
 
 import (
 	"fmt"
+	"host-vault/internal/sshkeys"
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -81,76 +83,84 @@ type SSHSession struct {
 	metadata        SessionMetadata
 	mu              sync.RWMutex
 	closed          bool
-	buffer          chan []byte
+	output          *outputPipe
 	scrollback      *ScrollbackBuffer
 	connectionID    string
 	keepAliveTicker *time.Ticker
 	done            chan struct{}
-	needsReplay     bool
-	bufferCloseOnce sync.Once
+	knownHostsMgr   *KnownHostsManager
+	promptHostKey   hostKeyPromptFunc
+	authMethod      string // method the server accepted, see passwordAuth
 }
 
 type ConnectionConfig struct {
-	Host       string
-	Port       int
-	Username   string
-	Password   string
-	PrivateKey string
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	PrivateKey    string
+	Passphrase    string        // Decrypts PrivateKey if it is encrypted
+	HostKeyPolicy HostKeyPolicy // Empty means strict
+	GuestMode     bool          // Host keys accepted for this connection stay in memory
 }
 
-func NewSSHSession(connectionID string, config ConnectionConfig, knownHostsMgr *KnownHostsManager) (*SSHSession, error) {
+// parsePrivateKey parses the configured private key in any format sshkeys
+// reads, PuTTY's included, using the passphrase if it is encrypted
+func parsePrivateKey(config ConnectionConfig) (ssh.Signer, error) {
+	return sshkeys.Signer([]byte(config.PrivateKey), config.Passphrase)
+}
+
+// passwordAuth offers password and sets used when the client tries it.
+// Methods are tried in order, so after a successful handshake used names
+// the one the server accepted; it stays empty if no method was needed.
+func passwordAuth(password string, used *string) ssh.AuthMethod {
+	return ssh.PasswordCallback(func() (string, error) {
+		*used = "password"
+		return password, nil
+	})
+}
+
+// publicKeyAuth offers signer, setting used like passwordAuth
+func publicKeyAuth(signer ssh.Signer, used *string) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		*used = "publickey"
+		return []ssh.Signer{signer}, nil
+	})
+}
+
+func NewSSHSession(connectionID string, config ConnectionConfig, knownHostsMgr *KnownHostsManager, prompt hostKeyPromptFunc) (*SSHSession, error) {
 	sessionID := uuid.New().String()
 	log.Printf("[SSH] Creating new SSH session %s for connection %s", sessionID, connectionID)
 
 	var authMethods []ssh.AuthMethod
+	var authMethod string
 
 	// Always try password auth if provided
 	if config.Password != "" {
-		authMethods = append(authMethods, ssh.Password(config.Password))
+		authMethods = append(authMethods, passwordAuth(config.Password, &authMethod))
 	}
 
 	// Try private key if provided
 	if config.PrivateKey != "" {
 		log.Printf("[SSH] Attempting to parse private key for session %s (key length: %d)", sessionID, len(config.PrivateKey))
-		signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+		signer, err := parsePrivateKey(config)
 		if err != nil {
 			log.Printf("[SSH] Failed to parse private key for session %s: %v", sessionID, err)
-			// Key might be encrypted, but we don't have passphrase support yet
-			// TODO: Add passphrase support
 		} else {
 			log.Printf("[SSH] Successfully parsed private key for session %s", sessionID)
-			authMethods = append(authMethods, ssh.PublicKeys(signer))
+			authMethods = append(authMethods, publicKeyAuth(signer, &authMethod))
 		}
 	}
 
 	// If no auth methods provided, try with empty password (for key-based auth or no auth required)
 	if len(authMethods) == 0 {
 		log.Printf("[SSH] No auth methods available for session %s, trying empty password", sessionID)
-		authMethods = append(authMethods, ssh.Password(""))
+		authMethods = append(authMethods, passwordAuth("", &authMethod))
 	} else {
 		log.Printf("[SSH] Using %d auth method(s) for session %s", len(authMethods), sessionID)
 	}
 
-	// Set up host key verification
-	var hostKeyCallback ssh.HostKeyCallback
-	if knownHostsMgr != nil {
-		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			return knownHostsMgr.VerifyHostKey(config.Host, config.Port, remote, key)
-		}
-	} else {
-		// Fallback to insecure if known hosts manager is not available
-		hostKeyCallback = ssh.InsecureIgnoreHostKey()
-	}
-
-	clientConfig := &ssh.ClientConfig{
-		User:            config.Username,
-		Auth:            authMethods,
-		HostKeyCallback: hostKeyCallback,
-		Timeout:         30 * time.Second,
-	}
-
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	client, err := ssh.Dial("tcp", addr, clientConfig)
+	client, err := dialSSH(config, authMethods, knownHostsMgr, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial SSH: %w", err)
 	}
@@ -214,25 +224,88 @@ func NewSSHSession(connectionID string, config ConnectionConfig, knownHostsMgr *
 			CreatedAt:        time.Now(),
 			State:            SessionStateActive,
 		},
-		buffer:       make(chan []byte, 100),    // Smaller buffer, rely on scrollback
-		scrollback:   NewScrollbackBuffer(5000), // Keep last 5000 chunks
-		closed:       false,
-		connectionID: connectionID,
-		done:         make(chan struct{}),
-		needsReplay:  false,
+		output:        newOutputPipe(outputPipeLimit),
+		scrollback:    NewScrollbackBuffer(5000), // Keep last 5000 chunks
+		closed:        false,
+		connectionID:  connectionID,
+		done:          make(chan struct{}),
+		knownHostsMgr: knownHostsMgr,
+		promptHostKey: prompt,
+		authMethod:    authMethod,
 	}
 
 	sshSession.keepAliveTicker = time.NewTicker(30 * time.Second)
 	go sshSession.keepAlive()
 
-	go sshSession.readOutput(stdout)
-	go sshSession.readOutput(stderr)
+	sshSession.startReaders(stdout, stderr, sshSession.output)
 
 	log.Printf("[SSH] SSH session %s connected successfully to %s:%d", sessionID, config.Host, config.Port)
 	return sshSession, nil
 }
 
-func (s *SSHSession) readOutput(reader io.Reader) {
+// dialSSH connects and authenticates, verifying the host key according to
+// config.HostKeyPolicy
+func dialSSH(config ConnectionConfig, authMethods []ssh.AuthMethod, knownHostsMgr *KnownHostsManager, prompt hostKeyPromptFunc) (*ssh.Client, error) {
+	trustedKey := false
+	hostKeyCallback, err := hostKeyVerifier(config, knownHostsMgr, prompt, &trustedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}
+	if knownHostsMgr != nil {
+		clientConfig.HostKeyAlgorithms = knownHostsMgr.HostKeyAlgorithms(config.Host, config.Port)
+	}
+
+	// Dial by hand rather than with ssh.Dial so the server's global
+	// requests (host key announcements) reach us instead of being discarded
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	netConn, err := net.DialTimeout("tcp", addr, clientConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, clientConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	if knownHostsMgr != nil {
+		go knownHostsMgr.handleGlobalRequests(sshConn, reqs, config.Host, config.Port, trustedKey)
+	} else {
+		go ssh.DiscardRequests(reqs)
+	}
+	return ssh.NewClient(sshConn, chans, nil), nil
+}
+
+// startReaders copies stdout and stderr into output and closes it once
+// both are done, so EOF on one stream never drops what the other has
+// still buffered
+func (s *SSHSession) startReaders(stdout, stderr io.Reader, output *outputPipe) {
+	var readers sync.WaitGroup
+	readers.Add(2)
+	for _, reader := range []io.Reader{stdout, stderr} {
+		go func(reader io.Reader) {
+			defer readers.Done()
+			s.readOutput(reader, output)
+		}(reader)
+	}
+	go func() {
+		readers.Wait()
+		// Signals EOF to streamOutput
+		output.Close()
+	}()
+}
+
+// readOutput copies one channel stream into output until it ends. It takes
+// the pipe explicitly so readers left over from before a reconnect never
+// write into the replacement pipe.
+func (s *SSHSession) readOutput(reader io.Reader, output *outputPipe) {
 	buf := make([]byte, 4096)
 	consecutiveErrors := 0
 	maxConsecutiveErrors := 5
@@ -248,10 +321,6 @@ func (s *SSHSession) readOutput(reader io.Reader) {
 					consecutiveErrors++
 					if consecutiveErrors >= maxConsecutiveErrors {
 						log.Printf("[SSH] Session %s disconnected due to %d consecutive read errors", s.id, consecutiveErrors)
-						// Close buffer to signal EOF to streamOutput
-						s.bufferCloseOnce.Do(func() {
-							close(s.buffer)
-						})
 						return
 					}
 					select {
@@ -261,11 +330,7 @@ func (s *SSHSession) readOutput(reader io.Reader) {
 					}
 					continue
 				}
-				// EOF detected - close buffer to signal streamOutput
-				log.Printf("[SSH] Session %s readOutput received EOF, closing buffer", s.id)
-				s.bufferCloseOnce.Do(func() {
-					close(s.buffer)
-				})
+				log.Printf("[SSH] Session %s readOutput received EOF", s.id)
 				return
 			}
 
@@ -278,16 +343,11 @@ func (s *SSHSession) readOutput(reader io.Reader) {
 				// Always add to scrollback buffer for history
 				s.scrollback.Add(data)
 
-				// Try to send to channel, but don't block
-				select {
-				case s.buffer <- data:
-					// Successfully sent to active consumer
-				default:
-					// // Channel full, data is in scrollback anyway
-					// // Mark that we need to replay scrollback on next ReadOutput
-					// s.mu.Lock()
-					// s.needsReplay = true
-					// s.mu.Unlock()
+				// Block while the consumer is behind. Not reading the channel
+				// stops the SSH window from being replenished, so the server
+				// pauses instead of us dropping output.
+				if !output.Write(data) {
+					return
 				}
 			}
 		}
@@ -368,10 +428,8 @@ func (s *SSHSession) Close() error {
 	close(s.done)
 	s.closeConnection()
 
-	// Ensure buffer is closed (only once)
-	s.bufferCloseOnce.Do(func() {
-		close(s.buffer)
-	})
+	// Ensure output is closed so streamOutput drains and exits
+	s.output.Close()
 
 	return nil
 }
@@ -382,37 +440,39 @@ func (s *SSHSession) GetMetadata() SessionMetadata {
 	return s.metadata
 }
 
+// AuthMethod returns how the current connection authenticated:
+// "password", "publickey" or "none"
+func (s *SSHSession) AuthMethod() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.authMethod == "" {
+		return "none"
+	}
+	return s.authMethod
+}
+
+func (s *SSHSession) SetWorkingDirectory(dir string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir == "" || dir == s.metadata.WorkingDirectory {
+		return false
+	}
+	s.metadata.WorkingDirectory = dir
+	return true
+}
+
 // GetScrollbackHistory returns all buffered output for terminal restoration
 func (s *SSHSession) GetScrollbackHistory() [][]byte {
 	return s.scrollback.GetAll()
 }
 
 func (s *SSHSession) ReadOutput() []byte {
-	s.mu.Lock()
-	needsReplay := s.needsReplay
-	if needsReplay {
-		s.needsReplay = false
-	}
-	s.mu.Unlock()
-
-	// If we need to replay, drain the channel first
-	if needsReplay {
-		// Drain any pending items
-		for {
-			select {
-			case <-s.buffer:
-			default:
-				goto replay
-			}
-		}
-	}
+	s.mu.RLock()
+	output := s.output
+	s.mu.RUnlock()
 
-replay:
-	data, ok := <-s.buffer
-	if !ok {
-		return nil
-	}
-	return data
+	return output.Read()
 }
 
 func (s *SSHSession) Reconnect(config ConnectionConfig) error {
@@ -426,39 +486,38 @@ func (s *SSHSession) Reconnect(config ConnectionConfig) error {
 
 	s.done = make(chan struct{})
 
+	// The previous pipe was closed when the connection dropped; start a
+	// fresh one for the new streamOutput loop
+	s.output.Close()
+	s.output = newOutputPipe(outputPipeLimit)
+
 	var authMethods []ssh.AuthMethod
+	var authMethod string
 
 	if config.Password != "" {
-		authMethods = append(authMethods, ssh.Password(config.Password))
+		authMethods = append(authMethods, passwordAuth(config.Password, &authMethod))
 	}
 
 	if config.PrivateKey != "" {
 		log.Printf("[SSH] Attempting to parse private key for reconnect (key length: %d)", len(config.PrivateKey))
-		signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+		signer, err := parsePrivateKey(config)
 		if err != nil {
 			log.Printf("[SSH] Failed to parse private key for reconnect: %v", err)
 		} else {
 			log.Printf("[SSH] Successfully parsed private key for reconnect")
-			authMethods = append(authMethods, ssh.PublicKeys(signer))
+			authMethods = append(authMethods, publicKeyAuth(signer, &authMethod))
 		}
 	}
 
 	if len(authMethods) == 0 {
 		log.Printf("[SSH] No auth methods available for reconnect, trying empty password")
-		authMethods = append(authMethods, ssh.Password(""))
+		authMethods = append(authMethods, passwordAuth("", &authMethod))
 	} else {
 		log.Printf("[SSH] Using %d auth method(s) for reconnect", len(authMethods))
 	}
 
-	clientConfig := &ssh.ClientConfig{
-		User:            config.Username,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-	client, err := ssh.Dial("tcp", addr, clientConfig)
+	// Reconnects are held to the same host key policy as the first connection
+	client, err := dialSSH(config, authMethods, s.knownHostsMgr, s.promptHostKey)
 	if err != nil {
 		return fmt.Errorf("failed to reconnect SSH: %w", err)
 	}
@@ -513,13 +572,13 @@ func (s *SSHSession) Reconnect(config ConnectionConfig) error {
 	s.stdin = stdin
 	s.stdout = stdout
 	s.stderr = stderr
+	s.authMethod = authMethod
 	s.metadata.State = SessionStateActive
 
 	s.keepAliveTicker = time.NewTicker(30 * time.Second)
 	go s.keepAlive()
 
-	go s.readOutput(s.stdout)
-	go s.readOutput(s.stderr)
+	s.startReaders(s.stdout, s.stderr, s.output)
 
 	log.Printf("[SSH] Session %s reconnected successfully", s.id)
 	return nil
@@ -0,0 +1,341 @@
+package terminal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// typeSSHFP is the SSHFP resource record type (RFC 4255)
+	typeSSHFP dnsmessage.Type = 44
+	// sshfpTimeout bounds a single DNS exchange
+	sshfpTimeout = 3 * time.Second
+	// sshfpCacheTTL keeps lookups around for the handful of handshakes a
+	// single connect or scan runs
+	sshfpCacheTTL = 30 * time.Second
+)
+
+// SSHFP verification results reported in HostKeyInfo
+const (
+	SSHFPNone     = "none"     // No SSHFP records for this key's algorithm
+	SSHFPMatch    = "match"    // A record matches the key
+	SSHFPMismatch = "mismatch" // Records exist for the algorithm but none match
+	SSHFPError    = "error"    // The lookup failed
+)
+
+// SSHFPConfig controls host key verification against SSHFP DNS records
+type SSHFPConfig struct {
+	Enabled bool `json:"enabled"`
+	// Resolver is the DNS server as host[:port]; empty uses the first
+	// nameserver in /etc/resolv.conf
+	Resolver string `json:"resolver"`
+	// AutoTrust accepts unknown host keys that match SSHFP records without
+	// asking, provided the resolver validated the answer (AD bit). An
+	// unvalidated match only raises confidence in the prompt, since anyone
+	// on the path can forge it.
+	AutoTrust bool `json:"autoTrust"`
+}
+
+// sshfpRecord is one SSHFP record's RDATA
+type sshfpRecord struct {
+	algorithm   uint8
+	fpType      uint8
+	fingerprint []byte
+}
+
+// sshfpAnswer is the result of a lookup. secure is set when the resolver
+// marked the answer as DNSSEC validated.
+type sshfpAnswer struct {
+	records []sshfpRecord
+	secure  bool
+	err     error
+	expires time.Time
+}
+
+// sshfpResolver looks up and caches SSHFP records
+type sshfpResolver struct {
+	config SSHFPConfig
+	mu     sync.Mutex
+	cache  map[string]*sshfpAnswer
+}
+
+func newSSHFPResolver(config SSHFPConfig) *sshfpResolver {
+	return &sshfpResolver{config: config, cache: make(map[string]*sshfpAnswer)}
+}
+
+// check looks up host's SSHFP records and compares them with key. secure
+// reports whether the answer was DNSSEC validated. IP addresses have no
+// SSHFP records and report SSHFPNone.
+func (r *sshfpResolver) check(host string, key ssh.PublicKey) (status string, secure bool) {
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if net.ParseIP(host) != nil {
+		return SSHFPNone, false
+	}
+
+	answer := r.lookup(host)
+	if answer.err != nil {
+		return SSHFPError, false
+	}
+	return matchSSHFP(answer.records, plainHostKey(key)), answer.secure
+}
+
+func (r *sshfpResolver) lookup(host string) *sshfpAnswer {
+	name := strings.ToLower(host)
+
+	r.mu.Lock()
+	if answer, ok := r.cache[name]; ok && time.Now().Before(answer.expires) {
+		r.mu.Unlock()
+		return answer
+	}
+	r.mu.Unlock()
+
+	answer := &sshfpAnswer{expires: time.Now().Add(sshfpCacheTTL)}
+	answer.records, answer.secure, answer.err = querySSHFP(r.config.Resolver, name)
+
+	r.mu.Lock()
+	r.cache[name] = answer
+	r.mu.Unlock()
+	return answer
+}
+
+// sshfpAlgorithm maps an SSH key type to its SSHFP algorithm number
+// (RFC 4255, 6594, 7479)
+func sshfpAlgorithm(keyType string) uint8 {
+	switch {
+	case keyType == ssh.KeyAlgoRSA:
+		return 1
+	case keyType == ssh.KeyAlgoDSA:
+		return 2
+	case strings.HasPrefix(keyType, "ecdsa-sha2-"):
+		return 3
+	case keyType == ssh.KeyAlgoED25519:
+		return 4
+	}
+	return 0
+}
+
+// matchSSHFP compares records with key. Only records of the key's
+// algorithm count, so a host publishing just an RSA fingerprint doesn't
+// make its Ed25519 key a mismatch.
+func matchSSHFP(records []sshfpRecord, key ssh.PublicKey) string {
+	algorithm := sshfpAlgorithm(key.Type())
+	if algorithm == 0 {
+		return SSHFPNone
+	}
+
+	blob := key.Marshal()
+	sha1Sum := sha1.Sum(blob)
+	sha256Sum := sha256.Sum256(blob)
+
+	status := SSHFPNone
+	for _, record := range records {
+		if record.algorithm != algorithm {
+			continue
+		}
+		var expected []byte
+		switch record.fpType {
+		case 1:
+			expected = sha1Sum[:]
+		case 2:
+			expected = sha256Sum[:]
+		default:
+			continue
+		}
+		if string(record.fingerprint) == string(expected) {
+			return SSHFPMatch
+		}
+		status = SSHFPMismatch
+	}
+	return status
+}
+
+// querySSHFP asks server for name's SSHFP records, over UDP with a TCP
+// retry when the answer is truncated
+func querySSHFP(server, name string) ([]sshfpRecord, bool, error) {
+	server, err := dnsServerAddress(server)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query, id, err := buildSSHFPQuery(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	response, err := exchangeDNS("udp", server, query)
+	if err != nil {
+		return nil, false, err
+	}
+	records, secure, truncated, err := parseSSHFPResponse(response, id)
+	if err == nil && truncated {
+		if response, err = exchangeDNS("tcp", server, query); err != nil {
+			return nil, false, err
+		}
+		records, secure, _, err = parseSSHFPResponse(response, id)
+	}
+	return records, secure, err
+}
+
+// dnsServerAddress adds the default port to a configured resolver or
+// falls back to the system's first nameserver
+func dnsServerAddress(server string) (string, error) {
+	if server == "" {
+		server = systemNameserver()
+		if server == "" {
+			return "", errors.New("no DNS resolver configured for SSHFP lookups")
+		}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server, nil
+}
+
+// systemNameserver returns the first nameserver of /etc/resolv.conf, if any
+func systemNameserver() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// buildSSHFPQuery builds a recursive SSHFP query that asks for DNSSEC
+// validation status (AD bit, RFC 6840)
+func buildSSHFPQuery(name string) ([]byte, uint16, error) {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid host name for SSHFP lookup: %w", err)
+	}
+
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               id,
+		RecursionDesired: true,
+		AuthenticData:    true,
+	})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: qname, Type: typeSSHFP, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+	query, err := builder.Finish()
+	return query, id, err
+}
+
+// exchangeDNS sends one query and reads the response; TCP messages carry
+// a two-byte length prefix
+func exchangeDNS(network, server string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, sshfpTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach DNS resolver: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(sshfpTimeout))
+
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+		if _, err := conn.Write(append(framed, query...)); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		response := make([]byte, binary.BigEndian.Uint16(length[:]))
+		_, err := io.ReadFull(conn, response)
+		return response, err
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	response := make([]byte, 65535)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+	return response[:n], nil
+}
+
+// parseSSHFPResponse extracts the SSHFP records of a response. NXDOMAIN
+// counts as an empty answer.
+func parseSSHFPResponse(response []byte, id uint16) (records []sshfpRecord, secure, truncated bool, err error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if header.ID != id || !header.Response {
+		return nil, false, false, errors.New("unexpected DNS response")
+	}
+	if header.Truncated {
+		return nil, false, true, nil
+	}
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, header.AuthenticData, false, nil
+	default:
+		return nil, false, false, fmt.Errorf("DNS lookup failed: %s", header.RCode)
+	}
+
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, false, false, err
+	}
+	answers, err := parser.AllAnswers()
+	if err != nil {
+		return nil, false, false, err
+	}
+	for _, answer := range answers {
+		unknown, ok := answer.Body.(*dnsmessage.UnknownResource)
+		if !ok || answer.Header.Type != typeSSHFP || len(unknown.Data) < 3 {
+			continue
+		}
+		records = append(records, sshfpRecord{
+			algorithm:   unknown.Data[0],
+			fpType:      unknown.Data[1],
+			fingerprint: append([]byte(nil), unknown.Data[2:]...),
+		})
+	}
+	return records, header.AuthenticData, false, nil
+}
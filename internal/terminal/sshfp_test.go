@@ -0,0 +1,204 @@
+package terminal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsZone is what the stand-in resolver answers for one name
+type dnsZone struct {
+	records []sshfpRecord
+	secure  bool
+}
+
+// startDNSServer runs a UDP resolver on localhost answering SSHFP queries
+// from zones; other names get NXDOMAIN. It returns the server's address.
+func startDNSServer(t *testing.T, zones map[string]dnsZone) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			response, err := dnsAnswer(buf[:n], zones)
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func dnsAnswer(query []byte, zones map[string]dnsZone) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	zone, ok := zones[strings.TrimSuffix(question.Name.String(), ".")]
+	header.Response = true
+	header.AuthenticData = zone.secure
+	if !ok {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, header)
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, record := range zone.records {
+		data := append([]byte{record.algorithm, record.fpType}, record.fingerprint...)
+		err := builder.UnknownResource(dnsmessage.ResourceHeader{
+			Name:  question.Name,
+			Type:  typeSSHFP,
+			Class: dnsmessage.ClassINET,
+			TTL:   60,
+		}, dnsmessage.UnknownResource{Type: typeSSHFP, Data: data})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	return key
+}
+
+func sha256Record(key ssh.PublicKey) sshfpRecord {
+	sum := sha256.Sum256(key.Marshal())
+	return sshfpRecord{algorithm: 4, fpType: 2, fingerprint: sum[:]}
+}
+
+func TestSSHFPAutoTrust(t *testing.T) {
+	key := newTestHostKey(t)
+	other := newTestHostKey(t)
+
+	server := startDNSServer(t, map[string]dnsZone{
+		"secure.test":   {records: []sshfpRecord{sha256Record(key)}, secure: true},
+		"insecure.test": {records: []sshfpRecord{sha256Record(key)}},
+		"mismatch.test": {records: []sshfpRecord{sha256Record(other)}, secure: true},
+		"rsa-only.test": {records: []sshfpRecord{{algorithm: 1, fpType: 2, fingerprint: make([]byte, 32)}}, secure: true},
+	})
+
+	tests := []struct {
+		host       string
+		wantStatus string
+		wantSecure bool
+		trusted    bool
+	}{
+		{host: "secure.test", wantStatus: SSHFPMatch, wantSecure: true, trusted: true},
+		{host: "insecure.test", wantStatus: SSHFPMatch, wantSecure: false, trusted: false},
+		{host: "mismatch.test", wantStatus: SSHFPMismatch, wantSecure: true, trusted: false},
+		{host: "rsa-only.test", wantStatus: SSHFPNone, wantSecure: true, trusted: false},
+		{host: "missing.test", wantStatus: SSHFPNone, wantSecure: false, trusted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			resolver := newSSHFPResolver(SSHFPConfig{Enabled: true, Resolver: server})
+			status, secure := resolver.check(tt.host, key)
+			if status != tt.wantStatus || secure != tt.wantSecure {
+				t.Errorf("check = %s, secure %v; want %s, secure %v", status, secure, tt.wantStatus, tt.wantSecure)
+			}
+
+			manager, err := NewKnownHostsManager(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewKnownHostsManager: %v", err)
+			}
+			manager.SetSSHFPConfig(SSHFPConfig{Enabled: true, Resolver: server, AutoTrust: true})
+			matched, err := manager.VerifyHostKey(tt.host, 22, nil, key)
+			if tt.trusted && (err != nil || matched != hostKeySSHFP) {
+				t.Errorf("VerifyHostKey = %v, %v; want the key trusted via SSHFP", matched, err)
+			}
+			if !tt.trusted && err == nil {
+				t.Error("VerifyHostKey trusted the key, want it left unknown")
+			}
+		})
+	}
+}
+
+func TestSSHFPIPAddressSkipsLookup(t *testing.T) {
+	resolver := newSSHFPResolver(SSHFPConfig{Enabled: true, Resolver: "127.0.0.1:1"})
+	if status, _ := resolver.check("192.0.2.1", newTestHostKey(t)); status != SSHFPNone {
+		t.Errorf("check = %s, want %s", status, SSHFPNone)
+	}
+}
+
+func TestSSHFPMatchDoesNotEnableKeyLearning(t *testing.T) {
+	sshfpKey := newTestHostKey(t)
+	knownKey := newTestHostKey(t)
+	server := startDNSServer(t, map[string]dnsZone{
+		"dns.test": {records: []sshfpRecord{sha256Record(sshfpKey)}, secure: true},
+	})
+
+	manager, err := NewKnownHostsManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKnownHostsManager: %v", err)
+	}
+	manager.SetSSHFPConfig(SSHFPConfig{Enabled: true, Resolver: server, AutoTrust: true})
+	if err := manager.AddHostKey("known.test", 22, knownKey, false); err != nil {
+		t.Fatalf("AddHostKey: %v", err)
+	}
+
+	tests := []struct {
+		host    string
+		key     ssh.PublicKey
+		trusted bool
+	}{
+		{host: "dns.test", key: sshfpKey, trusted: false},
+		{host: "known.test", key: knownKey, trusted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			var trusted bool
+			config := ConnectionConfig{Host: tt.host, Port: 22, HostKeyPolicy: HostKeyPolicyStrict}
+			callback, err := hostKeyVerifier(config, manager, nil, &trusted)
+			if err != nil {
+				t.Fatalf("hostKeyVerifier: %v", err)
+			}
+			if err := callback(tt.host+":22", nil, tt.key); err != nil {
+				t.Fatalf("host key rejected: %v", err)
+			}
+			if trusted != tt.trusted {
+				t.Errorf("trusted = %v, want %v", trusted, tt.trusted)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// autoLockCheckInterval is how often RunAutoLock looks for an idle vault
+const autoLockCheckInterval = 15 * time.Second
+
+// LockReason says why the vault was locked
+type LockReason string
+
+const (
+	LockReasonManual     LockReason = "manual"
+	LockReasonIdle       LockReason = "idle"
+	LockReasonSuspend    LockReason = "suspend"
+	LockReasonScreenLock LockReason = "screen-lock"
+)
+
+// SessionLockAction is what happens to sessions opened with vault
+// credentials when the vault locks
+type SessionLockAction string
+
+const (
+	SessionLockNone       SessionLockAction = "none"
+	SessionLockFreeze     SessionLockAction = "freeze"
+	SessionLockDisconnect SessionLockAction = "disconnect"
+)
+
+// AutoLockSettings are stored unencrypted in the vault file so they apply
+// while it is locked
+type AutoLockSettings struct {
+	IdleMinutes      int               `json:"idleMinutes"` // 0 disables the idle timer
+	LockOnSuspend    bool              `json:"lockOnSuspend"`
+	LockOnScreenLock bool              `json:"lockOnScreenLock"`
+	SessionAction    SessionLockAction `json:"sessionAction"`
+}
+
+// LockHandler is called after the vault locks, outside the vault's lock
+type LockHandler func(reason LockReason)
+
+// LockedEvent is emitted as vault:locked
+type LockedEvent struct {
+	Reason        LockReason        `json:"reason"`
+	SessionAction SessionLockAction `json:"sessionAction"`
+	// SessionIDs are the sessions frozen or disconnected by the lock
+	SessionIDs []string `json:"sessionIds"`
+}
+
+func defaultAutoLockSettings() AutoLockSettings {
+	return AutoLockSettings{
+		IdleMinutes:      15,
+		LockOnSuspend:    true,
+		LockOnScreenLock: true,
+		SessionAction:    SessionLockNone,
+	}
+}
+
+// validate checks the settings, treating an empty session action as none
+func (s *AutoLockSettings) validate() error {
+	if s.IdleMinutes < 0 || s.IdleMinutes > 24*60 {
+		return fmt.Errorf("idle timeout must be between 0 and %d minutes", 24*60)
+	}
+	if s.SessionAction == "" {
+		s.SessionAction = SessionLockNone
+	}
+	switch s.SessionAction {
+	case SessionLockNone, SessionLockFreeze, SessionLockDisconnect:
+		return nil
+	}
+	return fmt.Errorf("unknown session action: %s", s.SessionAction)
+}
+
+// SetLockHandler sets what is called whenever the vault locks
+func (v *Vault) SetLockHandler(handle LockHandler) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onLock = handle
+}
+
+// AutoLockSettings returns the auto-lock settings, defaults included
+func (v *Vault) AutoLockSettings() AutoLockSettings {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+	return v.autoLockSettings()
+}
+
+// SetAutoLockSettings changes the auto-lock settings. The vault must be
+// unlocked, so they can't be loosened by whoever finds it locked.
+func (v *Vault) SetAutoLockSettings(settings AutoLockSettings) error {
+	if err := settings.validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return err
+	}
+	previous := v.file.AutoLock
+	v.file.AutoLock = &settings
+	if err := v.save(); err != nil {
+		v.file.AutoLock = previous
+		return err
+	}
+	return nil
+}
+
+// Touch counts as activity, postponing the idle lock
+func (v *Vault) Touch() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.touch()
+}
+
+// LockFor locks the vault for a system event if the settings ask for it
+func (v *Vault) LockFor(reason LockReason) {
+	v.mu.Lock()
+	v.reloadIfChanged()
+	settings := v.autoLockSettings()
+	v.mu.Unlock()
+
+	switch {
+	case reason == LockReasonSuspend && settings.LockOnSuspend,
+		reason == LockReasonScreenLock && settings.LockOnScreenLock:
+		v.lock(reason)
+	}
+}
+
+// RunAutoLock locks the vault once it has been idle for the configured
+// time, until ctx is done
+func (v *Vault) RunAutoLock(ctx context.Context) {
+	ticker := time.NewTicker(autoLockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if v.idle() {
+				v.lock(LockReasonIdle)
+			}
+		}
+	}
+}
+
+// idle reports whether the vault is unlocked past its idle timeout
+func (v *Vault) idle() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.key == nil {
+		return false
+	}
+	settings := v.autoLockSettings()
+	if settings.IdleMinutes == 0 {
+		return false
+	}
+	return time.Since(v.lastActivity) >= time.Duration(settings.IdleMinutes)*time.Minute
+}
+
+// touch records activity now (must be called with lock held)
+func (v *Vault) touch() {
+	// Round(0) drops the monotonic reading, so time spent suspended, which
+	// the monotonic clock may skip, still counts as idle
+	v.lastActivity = time.Now().Round(0)
+}
+
+// autoLockSettings returns the stored settings or the defaults (must be
+// called with lock held)
+func (v *Vault) autoLockSettings() AutoLockSettings {
+	if v.file == nil || v.file.AutoLock == nil {
+		return defaultAutoLockSettings()
+	}
+	return *v.file.AutoLock
+}
+
+// lock wipes the data key and tells the lock handler, unless the vault is
+// already locked. Any reason but a manual lock means the user may be away,
+// so the password is needed to unlock again even if the vault was locked
+// already.
+func (v *Vault) lock(reason LockReason) {
+	v.mu.Lock()
+	if reason != LockReasonManual {
+		v.passwordRequired = true
+	}
+	if v.key == nil {
+		v.mu.Unlock()
+		return
+	}
+	wipe(v.key)
+	v.key = nil
+	handle := v.onLock
+	v.mu.Unlock()
+
+	log.Printf("[VAULT] Vault locked (%s)", reason)
+	if handle != nil {
+		handle(reason)
+	}
+}
@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const testPassword = "correct horse battery staple"
+
+// newTestVault returns an unlocked vault and its cached master key
+func newTestVault(t *testing.T) (*Vault, []byte) {
+	t.Helper()
+	v, err := Open(filepath.Join(t.TempDir(), "vault.json"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := v.Initialize(testPassword); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	masterKey, err := v.MasterKey(testPassword)
+	if err != nil {
+		t.Fatalf("MasterKey: %v", err)
+	}
+	return v, masterKey
+}
+
+func TestCachedKeyUnlockAfterLock(t *testing.T) {
+	tests := []struct {
+		name      string
+		lock      func(v *Vault)
+		wantError error
+	}{
+		{name: "manual", lock: (*Vault).Lock},
+		{name: "idle", lock: func(v *Vault) { v.lock(LockReasonIdle) }, wantError: ErrPasswordRequired},
+		{name: "suspend", lock: func(v *Vault) { v.LockFor(LockReasonSuspend) }, wantError: ErrPasswordRequired},
+		{name: "screen lock", lock: func(v *Vault) { v.LockFor(LockReasonScreenLock) }, wantError: ErrPasswordRequired},
+		{
+			name: "screen lock while locked",
+			lock: func(v *Vault) {
+				v.Lock()
+				v.LockFor(LockReasonScreenLock)
+			},
+			wantError: ErrPasswordRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, masterKey := newTestVault(t)
+			tt.lock(v)
+			if v.IsUnlocked() {
+				t.Fatal("vault still unlocked")
+			}
+
+			err := v.UnlockWithMasterKey(masterKey)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("UnlockWithMasterKey = %v, want %v", err, tt.wantError)
+			}
+			if status := v.Status(); status.PasswordRequired != (tt.wantError != nil) {
+				t.Errorf("PasswordRequired = %v", status.PasswordRequired)
+			}
+			if tt.wantError == nil {
+				return
+			}
+
+			// The password lifts the requirement for the next lock cycle
+			if err := v.Unlock(testPassword); err != nil {
+				t.Fatalf("Unlock: %v", err)
+			}
+			v.Lock()
+			if err := v.UnlockWithMasterKey(masterKey); err != nil {
+				t.Errorf("UnlockWithMasterKey after the password = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// keySize is the AES-256 key size used for both the master and data keys
+	keySize = 32
+	// saltSize is the Argon2id salt length
+	saltSize = 16
+)
+
+// KDFParams are the Argon2id parameters a vault was created with. They are
+// stored in the vault file so they can be raised later without breaking
+// existing vaults.
+type KDFParams struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memoryKiB"`
+	Threads   uint8  `json:"threads"`
+}
+
+// defaultKDFParams follows the second recommended option of RFC 9106
+// (64 MiB, 3 passes) with a fresh salt
+func defaultKDFParams() (KDFParams, error) {
+	salt, err := randomBytes(saltSize)
+	if err != nil {
+		return KDFParams{}, err
+	}
+	return KDFParams{
+		Algorithm: "argon2id",
+		Salt:      salt,
+		Time:      3,
+		MemoryKiB: 64 * 1024,
+		Threads:   4,
+	}, nil
+}
+
+// deriveKey turns the master password into the key that wraps the data key
+func deriveKey(password string, params KDFParams) ([]byte, error) {
+	if params.Algorithm != "argon2id" {
+		return nil, fmt.Errorf("unsupported key derivation: %s", params.Algorithm)
+	}
+	if len(params.Salt) < saltSize || params.Time == 0 || params.MemoryKiB == 0 || params.Threads == 0 {
+		return nil, errors.New("invalid key derivation parameters")
+	}
+	return argon2.IDKey([]byte(password), params.Salt, params.Time, params.MemoryKiB, params.Threads, keySize), nil
+}
+
+// seal encrypts plaintext with AES-256-GCM. The nonce is prepended to the
+// ciphertext; aad binds the result to its record so sealed values can't be
+// swapped between records.
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open reverses seal
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// wipe overwrites key material that is no longer needed
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
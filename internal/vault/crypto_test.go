@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := randomBytes(keySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := randomBytes(keySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte(`{"password":"hunter2"}`)
+	aad := connectionAAD("a")
+
+	sealed, err := seal(key, plaintext, aad)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if again, _ := seal(key, plaintext, aad); bytes.Equal(again, sealed) {
+		t.Error("sealing twice gave the same output, want a fresh nonce")
+	}
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[len(flipped)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		key     []byte
+		sealed  []byte
+		aad     []byte
+		wantErr bool
+	}{
+		{name: "same record", key: key, sealed: sealed, aad: aad},
+		{name: "other record", key: key, sealed: sealed, aad: connectionAAD("b"), wantErr: true},
+		{name: "other record kind", key: key, sealed: sealed, aad: credentialAAD("a"), wantErr: true},
+		{name: "no aad", key: key, sealed: sealed, wantErr: true},
+		{name: "other key", key: otherKey, sealed: sealed, aad: aad, wantErr: true},
+		{name: "tampered", key: key, sealed: flipped, aad: aad, wantErr: true},
+		{name: "truncated", key: key, sealed: sealed[:8], aad: aad, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := open(tt.key, tt.sealed, tt.aad)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("open error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(opened, plaintext) {
+				t.Errorf("open = %q, want %q", opened, plaintext)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SSHKey is a key pair kept in the vault. The public half and its details
+// are stored in plain form, so keys can be listed and their public halves
+// exported while the vault is locked; the private key is sealed like a
+// credential.
+type SSHKey struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"` // SSH algorithm name, e.g. ssh-ed25519
+	Bits        int       `json:"bits"`
+	Comment     string    `json:"comment,omitempty"`
+	PublicKey   string    `json:"publicKey"` // authorized_keys line
+	Fingerprint string    `json:"fingerprint"`
+	RandomArt   string    `json:"randomArt"`
+	Encrypted   bool      `json:"encrypted"` // the private key needs a passphrase
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// keyAAD binds a sealed private key to the fingerprint of its public half,
+// so swapping the plain public key makes the private one unreadable
+func keyAAD(key SSHKey) []byte {
+	return []byte("key:" + key.ID + ":" + key.Fingerprint)
+}
+
+// ListKeys returns every stored key, sorted by name. This works while the
+// vault is locked.
+func (v *Vault) ListKeys() ([]SSHKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return nil, ErrNotInitialized
+	}
+	keys := make([]SSHKey, 0, len(v.file.Keys))
+	for _, key := range v.file.Keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i].Name) < strings.ToLower(keys[j].Name)
+	})
+	return keys, nil
+}
+
+// GetKey returns one stored key without its private half. This works
+// while the vault is locked.
+func (v *Vault) GetKey(id string) (SSHKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return SSHKey{}, ErrNotInitialized
+	}
+	key, ok := v.file.Keys[id]
+	if !ok {
+		return SSHKey{}, fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+	return key, nil
+}
+
+// AddKey stores a new key pair and returns it as stored
+func (v *Vault) AddKey(key SSHKey, privateKey string) (SSHKey, error) {
+	if key.PublicKey == "" || key.Fingerprint == "" || privateKey == "" {
+		return SSHKey{}, fmt.Errorf("key pair is incomplete")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return SSHKey{}, err
+	}
+
+	key.ID = uuid.New().String()
+	key.CreatedAt = time.Now().UTC()
+	if key.Name == "" {
+		key.Name = key.Fingerprint
+	}
+
+	// The public half goes in first so the same save writes both
+	keys := v.file.Keys
+	keys[key.ID] = key
+	if err := v.put(v.file.KeySecrets, key.ID, keyAAD(key), privateKey); err != nil {
+		delete(keys, key.ID)
+		return SSHKey{}, err
+	}
+	return key, nil
+}
+
+// RenameKey changes a key's display name
+func (v *Vault) RenameKey(id, name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return err
+	}
+	key, ok := v.file.Keys[id]
+	if !ok {
+		return fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+
+	keys := v.file.Keys
+	previous := key
+	key.Name = name
+	keys[id] = key
+	if err := v.save(); err != nil {
+		keys[id] = previous
+		return err
+	}
+	return nil
+}
+
+// DeleteKey removes a key pair
+func (v *Vault) DeleteKey(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return err
+	}
+	key, ok := v.file.Keys[id]
+	if !ok {
+		return fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+
+	// save may reload the file, so restore into the maps changed here
+	keys, secrets := v.file.Keys, v.file.KeySecrets
+	sealed := secrets[id]
+	delete(keys, id)
+	delete(secrets, id)
+	if err := v.save(); err != nil {
+		keys[id] = key
+		v.restore(secrets, id, sealed)
+		return err
+	}
+	return nil
+}
+
+// PrivateKey returns a key's private half in OpenSSH PEM form, still
+// encrypted if the key has a passphrase
+func (v *Vault) PrivateKey(id string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return "", err
+	}
+	key, ok := v.file.Keys[id]
+	if !ok {
+		return "", fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+	var privateKey string
+	if err := v.get(v.file.KeySecrets, id, keyAAD(key), &privateKey); err != nil {
+		return "", fmt.Errorf("key %s: %w", id, err)
+	}
+	return privateKey, nil
+}
@@ -0,0 +1,288 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Connection is a saved SSH connection. Its credential is stored
+// separately; HasPassword and HasPrivateKey tell the UI what is there
+// without revealing it.
+type Connection struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Host          string    `json:"host"`
+	Port          int       `json:"port"`
+	Username      string    `json:"username"`
+	HostKeyPolicy string    `json:"hostKeyPolicy,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	IsFavorite    bool      `json:"isFavorite"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	HasPassword   bool      `json:"hasPassword"`
+	HasPrivateKey bool      `json:"hasPrivateKey"`
+}
+
+// Credential holds the secrets of a connection. It is accepted from the UI
+// but never handed back to it.
+type Credential struct {
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"` // For an encrypted PrivateKey
+}
+
+func (c Credential) empty() bool {
+	return c.Password == "" && c.PrivateKey == ""
+}
+
+func connectionAAD(id string) []byte {
+	return []byte("connection:" + id)
+}
+
+func credentialAAD(id string) []byte {
+	return []byte("credential:" + id)
+}
+
+// ListConnections returns every saved connection, sorted by name
+func (v *Vault) ListConnections() ([]Connection, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	connections := make([]Connection, 0, len(v.file.Connections))
+	for id := range v.file.Connections {
+		conn, err := v.connection(id)
+		if err != nil {
+			return nil, err
+		}
+		connections = append(connections, conn)
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return strings.ToLower(connections[i].Name) < strings.ToLower(connections[j].Name)
+	})
+	return connections, nil
+}
+
+// GetConnection returns one saved connection
+func (v *Vault) GetConnection(id string) (Connection, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return Connection{}, err
+	}
+	return v.connection(id)
+}
+
+// SaveConnection creates the connection (empty ID) or replaces the stored
+// one and returns it as stored
+func (v *Vault) SaveConnection(conn Connection) (Connection, error) {
+	if conn.Host == "" {
+		return Connection{}, fmt.Errorf("host is required")
+	}
+	if conn.Port == 0 {
+		conn.Port = 22
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return Connection{}, err
+	}
+
+	now := time.Now().UTC()
+	if conn.ID == "" {
+		conn.ID = uuid.New().String()
+		conn.CreatedAt = now
+	} else if existing, err := v.connection(conn.ID); err == nil {
+		conn.CreatedAt = existing.CreatedAt
+	} else {
+		conn.CreatedAt = now
+	}
+	conn.UpdatedAt = now
+	// Derived from the credential when read
+	conn.HasPassword, conn.HasPrivateKey = false, false
+
+	if err := v.put(v.file.Connections, conn.ID, connectionAAD(conn.ID), conn); err != nil {
+		return Connection{}, err
+	}
+	return v.connection(conn.ID)
+}
+
+// DeleteConnection removes a connection together with its credential
+func (v *Vault) DeleteConnection(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return err
+	}
+	if _, ok := v.file.Connections[id]; !ok {
+		return fmt.Errorf("connection %s: %w", id, ErrNotFound)
+	}
+
+	// save may reload the file, so restore into the maps changed here
+	connections, credentials := v.file.Connections, v.file.Credentials
+	sealedConn, sealedCred := connections[id], credentials[id]
+	delete(connections, id)
+	delete(credentials, id)
+	if err := v.save(); err != nil {
+		v.restore(connections, id, sealedConn)
+		v.restore(credentials, id, sealedCred)
+		return err
+	}
+	return nil
+}
+
+// SetCredential stores the secrets of a connection, replacing any previous
+// ones. An empty credential removes them.
+func (v *Vault) SetCredential(connectionID string, cred Credential) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return err
+	}
+	if _, ok := v.file.Connections[connectionID]; !ok {
+		return fmt.Errorf("connection %s: %w", connectionID, ErrNotFound)
+	}
+
+	if cred.empty() {
+		credentials := v.file.Credentials
+		previous, ok := credentials[connectionID]
+		if !ok {
+			return nil
+		}
+		delete(credentials, connectionID)
+		if err := v.save(); err != nil {
+			v.restore(credentials, connectionID, previous)
+			return err
+		}
+		return nil
+	}
+	return v.put(v.file.Credentials, connectionID, credentialAAD(connectionID), cred)
+}
+
+// ResolveConnection returns a connection with its secrets, for the
+// terminal layer to connect with
+func (v *Vault) ResolveConnection(id string) (Connection, Credential, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if err := v.checkUnlocked(); err != nil {
+		return Connection{}, Credential{}, err
+	}
+	conn, err := v.connection(id)
+	if err != nil {
+		return Connection{}, Credential{}, err
+	}
+	cred, err := v.credential(id)
+	if err != nil {
+		return Connection{}, Credential{}, err
+	}
+	return conn, cred, nil
+}
+
+// checkUnlocked fails unless records can be read; otherwise the access
+// counts as activity for the idle lock (must be called with lock held)
+func (v *Vault) checkUnlocked() error {
+	if v.file == nil {
+		return ErrNotInitialized
+	}
+	if v.key == nil {
+		return ErrLocked
+	}
+	v.touch()
+	return nil
+}
+
+// connection decrypts a connection and fills in what its credential holds
+// (must be called with lock held)
+func (v *Vault) connection(id string) (Connection, error) {
+	var conn Connection
+	if err := v.get(v.file.Connections, id, connectionAAD(id), &conn); err != nil {
+		return Connection{}, fmt.Errorf("connection %s: %w", id, err)
+	}
+	cred, err := v.credential(id)
+	if err != nil {
+		return Connection{}, err
+	}
+	conn.HasPassword = cred.Password != ""
+	conn.HasPrivateKey = cred.PrivateKey != ""
+	return conn, nil
+}
+
+// credential decrypts a connection's secrets; a connection without any
+// gives an empty credential (must be called with lock held)
+func (v *Vault) credential(id string) (Credential, error) {
+	var cred Credential
+	if _, ok := v.file.Credentials[id]; !ok {
+		return cred, nil
+	}
+	if err := v.get(v.file.Credentials, id, credentialAAD(id), &cred); err != nil {
+		return Credential{}, fmt.Errorf("credential %s: %w", id, err)
+	}
+	return cred, nil
+}
+
+// get decrypts a record into out (must be called with lock held)
+func (v *Vault) get(records map[string][]byte, id string, aad []byte, out interface{}) error {
+	sealed, ok := records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	plaintext, err := open(v.key, sealed, aad)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt record: %w", err)
+	}
+	defer wipe(plaintext)
+	return json.Unmarshal(plaintext, out)
+}
+
+// put encrypts a record and saves the vault, leaving the previous record
+// in place if saving fails (must be called with lock held)
+func (v *Vault) put(records map[string][]byte, id string, aad []byte, value interface{}) error {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	defer wipe(plaintext)
+
+	sealed, err := seal(v.key, plaintext, aad)
+	if err != nil {
+		return err
+	}
+
+	previous := records[id]
+	records[id] = sealed
+	if err := v.save(); err != nil {
+		v.restore(records, id, previous)
+		return err
+	}
+	return nil
+}
+
+// restore puts back a record after a failed save; nil means there was none
+// (must be called with lock held)
+func (v *Vault) restore(records map[string][]byte, id string, sealed []byte) {
+	if sealed == nil {
+		delete(records, id)
+		return
+	}
+	records[id] = sealed
+}
@@ -0,0 +1,354 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"host-vault/internal/storage"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileVersion is the current vault file format
+const fileVersion = 1
+
+// wrappedKeyAAD binds the wrapped data key to its purpose
+var wrappedKeyAAD = []byte("host-vault:data-key")
+
+var (
+	ErrNotInitialized     = errors.New("vault not set up")
+	ErrAlreadyInitialized = errors.New("vault already set up")
+	ErrLocked             = errors.New("vault is locked")
+	ErrInvalidPassword    = errors.New("invalid master password")
+	ErrNotFound           = errors.New("not found in vault")
+	ErrPasswordRequired   = errors.New("master password required after an automatic lock")
+)
+
+// vaultFile is the on-disk layout. Records are sealed individually with
+// the data key, which in turn is sealed with the key derived from the
+// master password, so changing the password only rewraps one value.
+type vaultFile struct {
+	Version     int               `json:"version"`
+	KDF         KDFParams         `json:"kdf"`
+	WrappedKey  []byte            `json:"wrappedKey"`
+	Connections map[string][]byte `json:"connections"`
+	Credentials map[string][]byte `json:"credentials"`
+	Keys        map[string]SSHKey `json:"keys,omitempty"`       // public halves, in plain form
+	KeySecrets  map[string][]byte `json:"keySecrets,omitempty"` // sealed private keys
+	AutoLock    *AutoLockSettings `json:"autoLock,omitempty"`
+}
+
+// Status describes the vault without revealing anything stored in it
+type Status struct {
+	Initialized bool `json:"initialized"`
+	Unlocked    bool `json:"unlocked"`
+	// PasswordRequired is set after an automatic lock: only the master
+	// password unlocks the vault, not a cached master key
+	PasswordRequired bool `json:"passwordRequired"`
+}
+
+// Vault holds connections, their credentials and SSH keys encrypted at
+// rest. Secrets are only decrypted in Go, on demand, while the vault is
+// unlocked.
+type Vault struct {
+	path         string
+	version      string // storage.Version of path as last loaded or saved
+	mu           sync.RWMutex
+	file         *vaultFile // nil until the vault is set up
+	key          []byte     // data key, nil while locked
+	lastActivity time.Time
+	onLock       LockHandler
+	// passwordRequired is set by automatic locks and cleared by Unlock, so
+	// a cached master key can't undo a lock the user didn't ask for
+	passwordRequired bool
+}
+
+// Open loads the vault stored at path. A missing file gives a vault that
+// still needs Initialize.
+func Open(path string) (*Vault, error) {
+	v := &Vault{path: path}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Status reports whether the vault is set up and unlocked
+func (v *Vault) Status() Status {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return Status{Initialized: v.file != nil, Unlocked: v.key != nil, PasswordRequired: v.passwordRequired}
+}
+
+// IsUnlocked reports whether records can currently be read
+func (v *Vault) IsUnlocked() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key != nil
+}
+
+// Initialize sets the vault up with a master password and leaves it unlocked
+func (v *Vault) Initialize(password string) error {
+	if password == "" {
+		return errors.New("master password must not be empty")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file != nil {
+		return ErrAlreadyInitialized
+	}
+
+	params, err := defaultKDFParams()
+	if err != nil {
+		return err
+	}
+	dataKey, err := randomBytes(keySize)
+	if err != nil {
+		return err
+	}
+	wrapped, err := wrapKey(password, params, dataKey)
+	if err != nil {
+		return err
+	}
+
+	v.file = &vaultFile{
+		Version:     fileVersion,
+		KDF:         params,
+		WrappedKey:  wrapped,
+		Connections: make(map[string][]byte),
+		Credentials: make(map[string][]byte),
+		Keys:        make(map[string]SSHKey),
+		KeySecrets:  make(map[string][]byte),
+	}
+	if err := v.save(); err != nil {
+		v.file = nil
+		return err
+	}
+	v.key = dataKey
+	v.touch()
+	log.Printf("[VAULT] Vault created at %s", v.path)
+	return nil
+}
+
+// Unlock derives the master key from password and unwraps the data key
+func (v *Vault) Unlock(password string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return ErrNotInitialized
+	}
+	if v.key != nil {
+		return nil
+	}
+
+	dataKey, err := unwrapKey(password, v.file.KDF, v.file.WrappedKey)
+	if err != nil {
+		return err
+	}
+	v.key = dataKey
+	v.passwordRequired = false
+	v.touch()
+	log.Printf("[VAULT] Vault unlocked")
+	return nil
+}
+
+// MasterKey derives the key password unlocks the vault with, for caching
+// in an OS keychain. It fails like Unlock for a wrong password.
+func (v *Vault) MasterKey(password string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return nil, ErrNotInitialized
+	}
+	masterKey, err := deriveKey(password, v.file.KDF)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(masterKey, v.file.WrappedKey, wrappedKeyAAD)
+	if err != nil {
+		wipe(masterKey)
+		return nil, ErrInvalidPassword
+	}
+	wipe(dataKey)
+	return masterKey, nil
+}
+
+// UnlockWithMasterKey unlocks with a key from MasterKey, skipping the key
+// derivation. A key cached before the password changed gives
+// ErrInvalidPassword; after an automatic lock it gives ErrPasswordRequired
+// until Unlock succeeds.
+func (v *Vault) UnlockWithMasterKey(masterKey []byte) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return ErrNotInitialized
+	}
+	if v.key != nil {
+		return nil
+	}
+	if v.passwordRequired {
+		return ErrPasswordRequired
+	}
+
+	dataKey, err := open(masterKey, v.file.WrappedKey, wrappedKeyAAD)
+	if err != nil {
+		return ErrInvalidPassword
+	}
+	v.key = dataKey
+	v.touch()
+	log.Printf("[VAULT] Vault unlocked with cached master key")
+	return nil
+}
+
+// Lock wipes the data key from memory. Locking a locked vault is a no-op.
+func (v *Vault) Lock() {
+	v.lock(LockReasonManual)
+}
+
+// ChangePassword rewraps the data key under a new master password with
+// fresh key derivation parameters. Records are not re-encrypted.
+func (v *Vault) ChangePassword(current, password string) error {
+	if password == "" {
+		return errors.New("master password must not be empty")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reloadIfChanged()
+
+	if v.file == nil {
+		return ErrNotInitialized
+	}
+
+	dataKey, err := unwrapKey(current, v.file.KDF, v.file.WrappedKey)
+	if err != nil {
+		return err
+	}
+	defer wipe(dataKey)
+
+	params, err := defaultKDFParams()
+	if err != nil {
+		return err
+	}
+	wrapped, err := wrapKey(password, params, dataKey)
+	if err != nil {
+		return err
+	}
+
+	previousKDF, previousKey := v.file.KDF, v.file.WrappedKey
+	v.file.KDF, v.file.WrappedKey = params, wrapped
+	if err := v.save(); err != nil {
+		v.file.KDF, v.file.WrappedKey = previousKDF, previousKey
+		return err
+	}
+	return nil
+}
+
+func wrapKey(password string, params KDFParams, dataKey []byte) ([]byte, error) {
+	masterKey, err := deriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(masterKey)
+	return seal(masterKey, dataKey, wrappedKeyAAD)
+}
+
+func unwrapKey(password string, params KDFParams, wrapped []byte) ([]byte, error) {
+	masterKey, err := deriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(masterKey)
+
+	dataKey, err := open(masterKey, wrapped, wrappedKeyAAD)
+	if err != nil {
+		// GCM can't tell a wrong password from a tampered file
+		return nil, ErrInvalidPassword
+	}
+	return dataKey, nil
+}
+
+// load reads the vault file; a missing file leaves the vault uninitialized
+// (must be called with lock held or before the vault is shared)
+func (v *Vault) load() error {
+	// Taken before reading so a write in between triggers another reload
+	version, err := storage.Version(v.path)
+	if err != nil {
+		return err
+	}
+	v.version = version
+	if version == "" {
+		v.file = nil
+		return nil
+	}
+
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if file.Version > fileVersion {
+		return fmt.Errorf("vault was written by a newer version (format %d)", file.Version)
+	}
+	if file.Connections == nil {
+		file.Connections = make(map[string][]byte)
+	}
+	if file.Credentials == nil {
+		file.Credentials = make(map[string][]byte)
+	}
+	if file.Keys == nil {
+		file.Keys = make(map[string]SSHKey)
+	}
+	if file.KeySecrets == nil {
+		file.KeySecrets = make(map[string][]byte)
+	}
+	v.file = &file
+	return nil
+}
+
+// reloadIfChanged picks up writes another app instance made since the file
+// was loaded. The data key never changes, so an unlocked vault stays
+// unlocked (must be called with lock held).
+func (v *Vault) reloadIfChanged() {
+	version, err := storage.Version(v.path)
+	if err != nil || version == v.version {
+		return
+	}
+	if err := v.load(); err != nil {
+		log.Printf("[VAULT] Failed to reload vault: %v", err)
+	}
+}
+
+// save writes the vault file unless another instance changed it since it
+// was loaded; the file is then reloaded and the caller's change dropped
+// (must be called with lock held)
+func (v *Vault) save() error {
+	data, err := json.MarshalIndent(v.file, "", "  ")
+	if err != nil {
+		return err
+	}
+	version, err := storage.WriteFileIfUnchanged(v.path, data, v.version)
+	if errors.Is(err, storage.ErrFileChanged) {
+		v.reloadIfChanged()
+		return fmt.Errorf("vault was modified by another instance, please retry")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	v.version = version
+	return nil
+}
@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// editVaultFile rewrites the vault file on disk through edit
+func editVaultFile(t *testing.T, path string, edit func(file *vaultFile)) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("parse vault: %v", err)
+	}
+	edit(&file)
+	if data, err = json.Marshal(&file); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reopenVault loads the vault at path afresh and unlocks it
+func reopenVault(t *testing.T, path string) *Vault {
+	t.Helper()
+	v, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := v.Unlock(testPassword); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	return v
+}
+
+func TestRecordsRoundTrip(t *testing.T) {
+	v, _ := newTestVault(t)
+	conn, err := v.SaveConnection(Connection{Name: "web", Host: "web.test", Username: "deploy"})
+	if err != nil {
+		t.Fatalf("SaveConnection: %v", err)
+	}
+	if err := v.SetCredential(conn.ID, Credential{Password: "hunter2"}); err != nil {
+		t.Fatalf("SetCredential: %v", err)
+	}
+
+	reopened := reopenVault(t, v.path)
+	got, cred, err := reopened.ResolveConnection(conn.ID)
+	if err != nil {
+		t.Fatalf("ResolveConnection: %v", err)
+	}
+	if got.Host != "web.test" || got.Port != 22 || !got.HasPassword || cred.Password != "hunter2" {
+		t.Errorf("ResolveConnection = %+v, %+v", got, cred)
+	}
+
+	reopened.Lock()
+	if _, err := reopened.GetConnection(conn.ID); !errors.Is(err, ErrLocked) {
+		t.Errorf("GetConnection while locked = %v, want %v", err, ErrLocked)
+	}
+	if err := reopened.Unlock("wrong password"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Unlock with a wrong password = %v, want %v", err, ErrInvalidPassword)
+	}
+}
+
+func TestSwappedRecordsRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		swap func(file *vaultFile, a, b string)
+	}{
+		{
+			name: "connections swapped",
+			swap: func(file *vaultFile, a, b string) {
+				file.Connections[a], file.Connections[b] = file.Connections[b], file.Connections[a]
+			},
+		},
+		{
+			name: "credential moved to another connection",
+			swap: func(file *vaultFile, a, b string) {
+				file.Credentials[b] = file.Credentials[a]
+			},
+		},
+		{
+			name: "credential stored as a connection",
+			swap: func(file *vaultFile, a, b string) {
+				file.Connections[a] = file.Credentials[a]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := newTestVault(t)
+			var ids []string
+			for _, host := range []string{"a.test", "b.test"} {
+				conn, err := v.SaveConnection(Connection{Host: host})
+				if err != nil {
+					t.Fatalf("SaveConnection: %v", err)
+				}
+				ids = append(ids, conn.ID)
+			}
+			if err := v.SetCredential(ids[0], Credential{Password: "a-secret"}); err != nil {
+				t.Fatalf("SetCredential: %v", err)
+			}
+
+			editVaultFile(t, v.path, func(file *vaultFile) { tt.swap(file, ids[0], ids[1]) })
+
+			reopened := reopenVault(t, v.path)
+			failed := false
+			for _, id := range ids {
+				if _, _, err := reopened.ResolveConnection(id); err != nil {
+					failed = true
+				}
+			}
+			if !failed {
+				t.Error("every connection resolved, want the moved record rejected")
+			}
+		})
+	}
+}
+
+func TestChangePasswordKeepsRecords(t *testing.T) {
+	v, masterKey := newTestVault(t)
+	conn, err := v.SaveConnection(Connection{Host: "web.test"})
+	if err != nil {
+		t.Fatalf("SaveConnection: %v", err)
+	}
+	const newPassword = "another horse battery staple"
+	if err := v.ChangePassword(testPassword, newPassword); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	v.Lock()
+	if err := v.UnlockWithMasterKey(masterKey); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("UnlockWithMasterKey with the old key = %v, want %v", err, ErrInvalidPassword)
+	}
+	if err := v.Unlock(testPassword); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Unlock with the old password = %v, want %v", err, ErrInvalidPassword)
+	}
+	if err := v.Unlock(newPassword); err != nil {
+		t.Fatalf("Unlock with the new password: %v", err)
+	}
+	if _, err := v.GetConnection(conn.ID); err != nil {
+		t.Errorf("GetConnection after the change: %v", err)
+	}
+}